@@ -0,0 +1,546 @@
+package pg2mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ArrayMode is how a PostgreSQL array column is translated for MySQL, which
+// has no arrays.
+type ArrayMode string
+
+const (
+	// ArrayModeJSON stores the array as a JSON array.
+	ArrayModeJSON ArrayMode = "json"
+	// ArrayModeDelimited stores the elements joined with a delimiter.
+	ArrayModeDelimited ArrayMode = "delimited"
+	// ArrayModeChildTable stores each element as a row of a child table,
+	// with the primary key of its row and its position in the array.
+	ArrayModeChildTable ArrayMode = "child_table"
+)
+
+// ArrayConversion is the translation of a single array column. Array
+// columns without one are migrated in their {a,b,c} literal form.
+type ArrayConversion struct {
+	Mode ArrayMode `yaml:"mode"`
+
+	// Delimiter joins the elements in delimited mode. NULL elements are
+	// left out. Defaults to a comma.
+	Delimiter string `yaml:"delimiter"`
+
+	// ChildTable is the MySQL table elements are stored in in child_table
+	// mode. It has the primary key columns of the parent table, and the
+	// OrdinalColumn and ValueColumn, which default to ordinal and value.
+	// Ordinals start at 1.
+	ChildTable    string `yaml:"child_table"`
+	OrdinalColumn string `yaml:"ordinal_column"`
+	ValueColumn   string `yaml:"value_column"`
+}
+
+func (c ArrayConversion) delimiter() string {
+	if c.Delimiter == "" {
+		return ","
+	}
+	return c.Delimiter
+}
+
+func (c ArrayConversion) ordinalColumn() string {
+	if c.OrdinalColumn == "" {
+		return "ordinal"
+	}
+	return c.OrdinalColumn
+}
+
+func (c ArrayConversion) valueColumn() string {
+	if c.ValueColumn == "" {
+		return "value"
+	}
+	return c.ValueColumn
+}
+
+// ArrayConversions are the array conversions of each table, by table and
+// column name.
+type ArrayConversions map[string]map[string]ArrayConversion
+
+func (a ArrayConversions) validate() error {
+	for table, columns := range a {
+		for column, conversion := range columns {
+			switch conversion.Mode {
+			case ArrayModeJSON, ArrayModeDelimited:
+			case ArrayModeChildTable:
+				if conversion.ChildTable == "" {
+					return fmt.Errorf("array column %s.%s has no child table", table, column)
+				}
+			default:
+				return fmt.Errorf("unknown array mode '%s' for %s.%s", conversion.Mode, table, column)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the conversion of column of table, if it is an array
+// column that has one.
+func (a ArrayConversions) lookup(table string, column *Column) (ArrayConversion, bool) {
+	conversion, ok := a[table][column.Name]
+	return conversion, ok && isArrayColumn(column)
+}
+
+// isArrayColumn is whether column is an array. PostgreSQL array columns are
+// typed by their element type, e.g. integer[].
+func isArrayColumn(column *Column) bool {
+	return strings.HasSuffix(column.Type, "[]")
+}
+
+func arrayElementType(column *Column) string {
+	return strings.TrimSuffix(column.Type, "[]")
+}
+
+// parseArray returns the elements of an array value, as the PostgreSQL
+// driver scans it or as row_to_json renders it, in their text form. Only
+// one-dimensional arrays can be parsed.
+func parseArray(value interface{}) ([]sql.NullString, bool) {
+	switch v := value.(type) {
+	case []byte:
+		var elements []sql.NullString
+		if err := pq.Array(&elements).Scan(v); err != nil {
+			return nil, false
+		}
+		return elements, true
+	case string:
+		return parseArray([]byte(v))
+	case []interface{}:
+		elements := make([]sql.NullString, len(v))
+		for i, element := range v {
+			switch e := element.(type) {
+			case nil:
+			case string:
+				elements[i] = sql.NullString{String: e, Valid: true}
+			case json.Number:
+				elements[i] = sql.NullString{String: e.String(), Valid: true}
+			case bool:
+				elements[i] = sql.NullString{String: "f", Valid: true}
+				if e {
+					elements[i].String = "t"
+				}
+			default:
+				bs, err := json.Marshal(e)
+				if err != nil {
+					return nil, false
+				}
+				elements[i] = sql.NullString{String: string(bs), Valid: true}
+			}
+		}
+		return elements, true
+	default:
+		return nil, false
+	}
+}
+
+// arrayElementValue is the value of an element of an array of elementType,
+// as it is written to MySQL.
+func arrayElementValue(elementType string, element sql.NullString) interface{} {
+	if !element.Valid {
+		return nil
+	}
+
+	if elementType == "boolean" {
+		return element.String == "t"
+	}
+
+	return element.String
+}
+
+// arrayJSONValue is an element of an array of elementType in a JSON array.
+// Numbers, booleans and JSON documents keep their type; anything else, and
+// numbers JSON can't represent such as NaN, is a string.
+func arrayJSONValue(elementType string, element sql.NullString) interface{} {
+	if !element.Valid {
+		return nil
+	}
+
+	switch elementType {
+	case "smallint", "integer", "bigint", "numeric", "real", "double precision":
+		if json.Valid([]byte(element.String)) {
+			return json.Number(element.String)
+		}
+	case "boolean":
+		return element.String == "t"
+	case "json", "jsonb":
+		if json.Valid([]byte(element.String)) {
+			return json.RawMessage(element.String)
+		}
+	}
+
+	return element.String
+}
+
+// arrayConversion translates array values of column in json or delimited
+// mode. Values that can't be parsed are left as they are.
+func arrayConversion(column *Column, conversion ArrayConversion) func(interface{}) interface{} {
+	elementType := arrayElementType(column)
+
+	return func(value interface{}) interface{} {
+		elements, ok := parseArray(value)
+		if !ok {
+			return value
+		}
+
+		if conversion.Mode == ArrayModeDelimited {
+			var parts []string
+			for _, element := range elements {
+				if element.Valid {
+					parts = append(parts, element.String)
+				}
+			}
+			return strings.Join(parts, conversion.delimiter())
+		}
+
+		values := make([]interface{}, len(elements))
+		for i, element := range elements {
+			values[i] = arrayJSONValue(elementType, element)
+		}
+
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		if err := encoder.Encode(values); err != nil {
+			return value
+		}
+
+		return strings.TrimSuffix(buf.String(), "\n")
+	}
+}
+
+// arrayLiteralConversion turns arrays rendered by row_to_json back into the
+// literal form PostgreSQL outputs them in, for array columns without a
+// conversion.
+func arrayLiteralConversion(value interface{}) interface{} {
+	if _, ok := value.([]interface{}); !ok {
+		return value
+	}
+
+	elements, ok := parseArray(value)
+	if !ok {
+		return value
+	}
+
+	parts := make([]string, len(elements))
+	for i, element := range elements {
+		switch {
+		case !element.Valid:
+			parts[i] = "NULL"
+		case element.String == "" || strings.EqualFold(element.String, "NULL") || strings.ContainsAny(element.String, "{},\"\\ \t\n\r\v\f"):
+			parts[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(element.String) + `"`
+		default:
+			parts[i] = element.String
+		}
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// arrayChildTable is an array column of a table whose elements are stored
+// in a child table in MySQL.
+type arrayChildTable struct {
+	parent     *Table
+	column     *Column
+	conversion ArrayConversion
+}
+
+// splitArrayColumns removes the array columns stored in child tables from the
+// tables of schema, as their parent tables in MySQL don't have them, and
+// returns them.
+func splitArrayColumns(schema *Schema, arrays ArrayConversions) []*arrayChildTable {
+	var children []*arrayChildTable
+	for name, table := range schema.Tables {
+		parent := &Table{Name: table.Name}
+		var columns []*arrayChildTable
+		for _, column := range table.Columns {
+			if conversion, ok := arrays.lookup(table.Name, column); ok && conversion.Mode == ArrayModeChildTable {
+				columns = append(columns, &arrayChildTable{parent: parent, column: column, conversion: conversion})
+				continue
+			}
+			parent.Columns = append(parent.Columns, column)
+		}
+
+		if len(columns) > 0 {
+			schema.Tables[name] = parent
+			children = append(children, columns...)
+		}
+	}
+
+	sort.Slice(children, func(i, j int) bool {
+		if children[i].parent.Name != children[j].parent.Name {
+			return children[i].parent.Name < children[j].parent.Name
+		}
+		return children[i].column.Name < children[j].column.Name
+	})
+
+	return children
+}
+
+func (c *arrayChildTable) name() string {
+	return c.conversion.ChildTable
+}
+
+// columns are the columns of the child table, in the order of its rows.
+func (c *arrayChildTable) columns(primaryKey []string) []string {
+	return append(append([]string{}, primaryKey...), c.conversion.ordinalColumn(), c.conversion.valueColumn())
+}
+
+func (c *arrayChildTable) insertStatement(primaryKey []string) string {
+	columns := c.columns(primaryKey)
+	names := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = fmt.Sprintf("`%s`", column)
+		placeholders[i] = "?"
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO `%s` (%s) VALUES (%s)",
+		c.name(),
+		strings.Join(names, ","),
+		strings.Join(placeholders, ","),
+	)
+}
+
+func (c *arrayChildTable) deleteStatement(dst DB, primaryKey []string) string {
+	return fmt.Sprintf("DELETE FROM `%s` WHERE %s", c.name(), keyCondition(dst, primaryKey, "=", "?"))
+}
+
+// rows returns the rows of the child table for the array value of the row
+// with the given primary key.
+func (c *arrayChildTable) rows(key []interface{}, value interface{}) ([][]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	elements, ok := parseArray(value)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse array in %s.%s of row %s", c.parent.Name, c.column.Name, FormatRowID(key))
+	}
+
+	elementType := arrayElementType(c.column)
+	rows := make([][]interface{}, len(elements))
+	for i, element := range elements {
+		rows[i] = append(append([]interface{}{}, key...), i+1, arrayElementValue(elementType, element))
+	}
+
+	return rows, nil
+}
+
+// eachRow calls f with the rows of the child table for each row of the
+// parent table in PostgreSQL.
+func (c *arrayChildTable) eachRow(ctx context.Context, src DB, primaryKey []string, f func([]interface{}) error) error {
+	if len(primaryKey) == 0 {
+		return fmt.Errorf("cannot store array column %s.%s in a child table without a primary key", c.parent.Name, c.column.Name)
+	}
+
+	srcColumns := make([]string, len(primaryKey)+1)
+	for i, column := range primaryKey {
+		srcColumns[i] = src.ColumnNameForSelect(column)
+	}
+	srcColumns[len(primaryKey)] = src.ColumnNameForSelect(c.column.Name)
+
+	rows, err := src.DB().QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM \"%s\" WHERE %s IS NOT NULL",
+		strings.Join(srcColumns, ","),
+		c.parent.Name,
+		src.ColumnNameForSelect(c.column.Name),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to select arrays: %s", err)
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(srcColumns))
+	scanArgs := make([]interface{}, len(srcColumns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		childRows, err := c.rows(values[:len(primaryKey)], values[len(primaryKey)])
+		if err != nil {
+			return err
+		}
+
+		for _, row := range childRows {
+			if err := f(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating through rows: %s", err)
+	}
+
+	return rows.Close()
+}
+
+// eachMissingRow calls f with each row of the child table that MySQL
+// doesn't have.
+func (c *arrayChildTable) eachMissingRow(ctx context.Context, src, dst DB, primaryKey []string, f func([]interface{}) error) error {
+	columns := c.columns(primaryKey)
+	conditions := make([]string, len(columns))
+	for i, column := range columns {
+		conditions[i] = fmt.Sprintf("%s <=> ?", dst.ColumnNameForSelect(column))
+	}
+
+	stmt, err := dst.DB().PrepareContext(ctx, fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM `%s` WHERE %s)",
+		c.name(),
+		strings.Join(conditions, " AND "),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %s", err)
+	}
+	defer stmt.Close()
+
+	return c.eachRow(ctx, src, primaryKey, func(row []interface{}) error {
+		var exists bool
+		if err := stmt.QueryRowContext(ctx, row...).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to check if row exists: %s", err)
+		}
+
+		if exists {
+			return nil
+		}
+		return f(row)
+	})
+}
+
+type InvalidArrayMetadata struct {
+	ColumnName string
+	Problem    string
+	RowCount   int64
+	RowIDs     []string
+}
+
+// GetInvalidArrays checks the translated array columns of src against dst:
+// that the columns and child tables they are stored in exist, and that the
+// arrays fit in them.
+func GetInvalidArrays(ctx context.Context, db DB, src, dst *Table, dstSchema *Schema, arrays ArrayConversions) ([]InvalidArrayMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var invalid []InvalidArrayMetadata
+	for _, column := range src.Columns {
+		conversion, ok := arrays.lookup(src.Name, column)
+		if !ok {
+			continue
+		}
+
+		var target *Column
+		var length string
+		var args []interface{}
+		switch conversion.Mode {
+		case ArrayModeJSON:
+			_, target, err = dst.GetColumn(column.Name)
+			length = fmt.Sprintf("LENGTH(array_to_json(\"%s\")::text)", column.Name)
+		case ArrayModeDelimited:
+			_, target, err = dst.GetColumn(column.Name)
+			length = fmt.Sprintf("LENGTH(array_to_string(\"%s\", $1))", column.Name)
+			args = []interface{}{conversion.delimiter()}
+		case ArrayModeChildTable:
+			child := &arrayChildTable{conversion: conversion}
+			var childTable *Table
+			childTable, err = dstSchema.GetTable(child.name())
+			if err == nil {
+				for _, name := range child.columns(primaryKey) {
+					if _, _, err = childTable.GetColumn(name); err != nil {
+						break
+					}
+				}
+			}
+			if err == nil {
+				_, target, _ = childTable.GetColumn(conversion.valueColumn())
+			}
+			length = fmt.Sprintf("(SELECT MAX(LENGTH(e::text)) FROM unnest(\"%s\") AS e)", column.Name)
+		}
+
+		if err != nil {
+			invalid = append(invalid, InvalidArrayMetadata{
+				ColumnName: column.Name,
+				Problem:    err.Error(),
+			})
+			continue
+		}
+
+		if target.MaxChars == 0 {
+			continue
+		}
+
+		metadata, err := arraysLongerThan(ctx, db, src, column, primaryKey, length, target.MaxChars, args...)
+		if err != nil {
+			return nil, err
+		}
+		if metadata.RowCount > 0 {
+			invalid = append(invalid, metadata)
+		}
+	}
+
+	return invalid, nil
+}
+
+func arraysLongerThan(ctx context.Context, db DB, src *Table, column *Column, primaryKey []string, length string, maxChars int64, args ...interface{}) (InvalidArrayMetadata, error) {
+	metadata := InvalidArrayMetadata{
+		ColumnName: column.Name,
+		Problem:    fmt.Sprintf("longer than %d characters", maxChars),
+	}
+
+	condition := fmt.Sprintf("%s > %d", length, maxChars)
+	if len(primaryKey) == 0 {
+		stmt := fmt.Sprintf("SELECT count(1) FROM \"%s\" WHERE %s", src.Name, condition)
+		if err := db.DB().QueryRowContext(ctx, stmt, args...).Scan(&metadata.RowCount); err != nil {
+			return metadata, fmt.Errorf("failed counting long arrays: %s", err)
+		}
+		return metadata, nil
+	}
+
+	keyColumnsForSelect := make([]string, len(primaryKey))
+	for i := range primaryKey {
+		keyColumnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM \"%s\" WHERE %s", strings.Join(keyColumnsForSelect, ","), src.Name, condition)
+	rows, err := db.DB().QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return metadata, fmt.Errorf("failed getting long arrays: %s", err)
+	}
+	defer rows.Close()
+
+	keyValues := make([]interface{}, len(primaryKey))
+	keyScanArgs := make([]interface{}, len(primaryKey))
+	for i := range keyValues {
+		keyScanArgs[i] = &keyValues[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(keyScanArgs...); err != nil {
+			return metadata, fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		metadata.RowIDs = append(metadata.RowIDs, FormatRowID(keyValues))
+		metadata.RowCount++
+	}
+
+	return metadata, rows.Err()
+}
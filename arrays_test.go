@@ -0,0 +1,76 @@
+package pg2mysql_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("Array conversions", func() {
+	var src, dst *pg2mysql.Table
+
+	BeforeEach(func() {
+		src = &pg2mysql.Table{
+			Name: "posts",
+			Columns: []*pg2mysql.Column{
+				{Name: "scores", Type: "integer[]"},
+				{Name: "tags", Type: "text[]"},
+			},
+		}
+		dst = &pg2mysql.Table{
+			Name: "posts",
+			Columns: []*pg2mysql.Column{
+				{Name: "scores", Type: "json"},
+				{Name: "tags", Type: "varchar", MaxChars: 255},
+			},
+		}
+	})
+
+	convert := func(arrays pg2mysql.ArrayConversions, values ...interface{}) []interface{} {
+		return pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{Arrays: arrays}).Convert(values)
+	}
+
+	It("translates arrays to JSON arrays, keeping the type of their elements", func() {
+		converted := convert(pg2mysql.ArrayConversions{
+			"posts": {
+				"scores": {Mode: pg2mysql.ArrayModeJSON},
+				"tags":   {Mode: pg2mysql.ArrayModeJSON},
+			},
+		}, []byte("{1,NULL,3}"), []byte(`{a,"b c","d,\"e\""}`))
+
+		Expect(converted).To(Equal([]interface{}{`[1,null,3]`, `["a","b c","d,\"e\""]`}))
+	})
+
+	It("joins arrays with a delimiter, leaving out NULLs", func() {
+		converted := convert(pg2mysql.ArrayConversions{
+			"posts": {
+				"tags": {Mode: pg2mysql.ArrayModeDelimited, Delimiter: "|"},
+			},
+		}, []byte("{1,2}"), []byte(`{a,NULL,"b c"}`))
+
+		Expect(converted).To(Equal([]interface{}{[]byte("{1,2}"), "a|b c"}))
+	})
+
+	It("translates arrays rendered as JSON by PostgreSQL the same way", func() {
+		converted := convert(pg2mysql.ArrayConversions{
+			"posts": {
+				"scores": {Mode: pg2mysql.ArrayModeJSON},
+			},
+		}, []interface{}{json.Number("1"), nil}, []interface{}{"a", "b c", ""})
+
+		Expect(converted).To(Equal([]interface{}{`[1,null]`, `{a,"b c",""}`}))
+	})
+
+	It("leaves values that aren't one-dimensional arrays alone", func() {
+		converted := convert(pg2mysql.ArrayConversions{
+			"posts": {
+				"scores": {Mode: pg2mysql.ArrayModeJSON},
+			},
+		}, []byte("{{1,2},{3,4}}"), nil)
+
+		Expect(converted).To(Equal([]interface{}{[]byte("{{1,2},{3,4}}"), nil}))
+	})
+})
@@ -0,0 +1,103 @@
+package pg2mysql_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("Booleans and bit strings", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE toggles (
+				id int PRIMARY KEY,
+				enabled boolean,
+				flag boolean,
+				mask bit varying(8),
+				bits bit(9)
+			);
+			INSERT INTO toggles VALUES
+				(1, true, false, B'101', B'100000001'),
+				(2, false, true, B'11111111', B'000000000'),
+				(3, NULL, NULL, NULL, NULL);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec(`
+			CREATE TABLE toggles (
+				id int PRIMARY KEY,
+				enabled tinyint(1),
+				flag bit(1),
+				mask bit(8),
+				bits varbinary(2)
+			);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE toggles")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE toggles")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("migrates them as numbers and verifies them", func() {
+		migrator := pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{})
+		Expect(migrator.Migrate(context.Background(), config)).To(Succeed())
+
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT_WS(',', id, enabled, flag+0, mask+0, HEX(bits)) FROM toggles ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var migrated []string
+		for rows.Next() {
+			var row string
+			Expect(rows.Scan(&row)).To(Succeed())
+			migrated = append(migrated, row)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		Expect(migrated).To(Equal([]string{"1,1,0,5,0101", "2,0,1,255,0000", "3"}))
+
+		watcher := &pg2mysqlfakes.FakeVerifierWatcher{}
+		verifier := pg2mysql.NewVerifier(pg, mysql, watcher)
+		Expect(verifier.Verify(context.Background(), config)).To(Succeed())
+
+		Expect(watcher.TableVerificationDidFinishCallCount()).To(Equal(1))
+		tableName, missingRows, _ := watcher.TableVerificationDidFinishArgsForCall(0)
+		Expect(tableName).To(Equal("toggles"))
+		Expect(missingRows).To(BeZero())
+	})
+
+	It("validates bit strings against the width of bit columns", func() {
+		_, err := pgRunner.DB().Exec("ALTER TABLE toggles ALTER COLUMN mask TYPE bit varying(16); INSERT INTO toggles (id, mask) VALUES (4, B'100000000')")
+		Expect(err).NotTo(HaveOccurred())
+
+		results, err := pg2mysql.NewValidator(pg, mysql).Validate(context.Background(), config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].UnconvertibleValues).To(HaveLen(1))
+		Expect(results[0].UnconvertibleValues[0].ColumnName).To(Equal("mask"))
+		Expect(results[0].UnconvertibleValues[0].RowIDs).To(Equal([]string{"4"}))
+	})
+})
@@ -0,0 +1,308 @@
+package pg2mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/lib/pq"
+)
+
+// ChangelogTable is the table in PostgreSQL that the triggers installed by a
+// Capturer record changes in. It is never migrated.
+const ChangelogTable = "pg2mysql_changelog"
+
+type Capturer interface {
+	Install(ctx context.Context, migrationConfig MigrationConfig) error
+	Apply(ctx context.Context, captureConfig CaptureConfig) error
+	Uninstall(ctx context.Context, migrationConfig MigrationConfig) error
+}
+
+type CaptureConfig struct {
+	MigrationConfig
+
+	// BatchSize is how many changes are applied to MySQL in one
+	// transaction.
+	BatchSize int
+}
+
+// NewCapturer returns a Capturer that records the changes to each migrated
+// table with triggers, for when logical replication isn't available.
+func NewCapturer(src, dst DB, watcher CapturerWatcher) Capturer {
+	return &capturer{
+		src:     src,
+		dst:     dst,
+		watcher: watcher,
+	}
+}
+
+type capturer struct {
+	src, dst DB
+	watcher  CapturerWatcher
+}
+
+var captureFunction = fmt.Sprintf(`
+CREATE OR REPLACE FUNCTION pg2mysql_capture() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'INSERT' THEN
+		INSERT INTO %[1]s (table_name, operation, new_row) VALUES (TG_TABLE_NAME, TG_OP, row_to_json(NEW));
+	ELSIF TG_OP = 'UPDATE' THEN
+		INSERT INTO %[1]s (table_name, operation, old_row, new_row) VALUES (TG_TABLE_NAME, TG_OP, row_to_json(OLD), row_to_json(NEW));
+	ELSIF TG_OP = 'DELETE' THEN
+		INSERT INTO %[1]s (table_name, operation, old_row) VALUES (TG_TABLE_NAME, TG_OP, row_to_json(OLD));
+	ELSE
+		INSERT INTO %[1]s (table_name, operation) VALUES (TG_TABLE_NAME, TG_OP);
+	END IF;
+	RETURN NULL;
+END
+$$ LANGUAGE plpgsql`, ChangelogTable)
+
+// captureTables are the tables changes are captured for: those the migrator
+// copies.
+func (c *capturer) captureTables(ctx context.Context, migrationConfig MigrationConfig) ([]*Table, error) {
+	schema, err := BuildSchema(ctx, c.src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build source schema: %s", err)
+	}
+
+	var tables []*Table
+	for _, table := range schema.Tables {
+		if !ignoreTable(table.Name, migrationConfig.IgnoreTables) {
+			tables = append(tables, table)
+		}
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+
+	return tables, nil
+}
+
+// Install creates the changelog table and the triggers that fill it in one
+// transaction, so that every change after it commits is captured.
+func (c *capturer) Install(ctx context.Context, migrationConfig MigrationConfig) error {
+	tables, err := c.captureTables(ctx, migrationConfig)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.src.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id bigserial PRIMARY KEY,
+			table_name text NOT NULL,
+			operation text NOT NULL,
+			old_row json,
+			new_row json,
+			captured_at timestamp with time zone NOT NULL DEFAULT now()
+		)`, ChangelogTable),
+		captureFunction,
+	}
+
+	var names []string
+	for _, table := range tables {
+		stmts = append(stmts,
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS pg2mysql_capture ON "%s"`, table.Name),
+			fmt.Sprintf(`CREATE TRIGGER pg2mysql_capture AFTER INSERT OR UPDATE OR DELETE ON "%s" FOR EACH ROW EXECUTE PROCEDURE pg2mysql_capture()`, table.Name),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS pg2mysql_capture_truncate ON "%s"`, table.Name),
+			fmt.Sprintf(`CREATE TRIGGER pg2mysql_capture_truncate AFTER TRUNCATE ON "%s" FOR EACH STATEMENT EXECUTE PROCEDURE pg2mysql_capture()`, table.Name),
+		)
+		names = append(names, table.Name)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to install change capture: %s", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %s", err)
+	}
+
+	c.watcher.CaptureDidInstall(names)
+
+	return nil
+}
+
+// Uninstall drops the triggers, the changelog table and any changes in it
+// that haven't been applied.
+func (c *capturer) Uninstall(ctx context.Context, migrationConfig MigrationConfig) error {
+	tables, err := c.captureTables(ctx, migrationConfig)
+	if err != nil {
+		return err
+	}
+
+	tx, err := c.src.DB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+	defer tx.Rollback()
+
+	var stmts []string
+	var names []string
+	for _, table := range tables {
+		stmts = append(stmts,
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS pg2mysql_capture ON "%s"`, table.Name),
+			fmt.Sprintf(`DROP TRIGGER IF EXISTS pg2mysql_capture_truncate ON "%s"`, table.Name),
+		)
+		names = append(names, table.Name)
+	}
+	stmts = append(stmts,
+		"DROP FUNCTION IF EXISTS pg2mysql_capture()",
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", ChangelogTable),
+	)
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to uninstall change capture: %s", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %s", err)
+	}
+
+	c.watcher.CaptureDidUninstall(names)
+
+	return nil
+}
+
+// Apply drains the changelog into MySQL in the order the changes were
+// captured, a batch per transaction. Each batch is removed from the changelog
+// once it is committed to MySQL, so a batch is applied again rather than lost
+// if Apply stops in between.
+func (c *capturer) Apply(ctx context.Context, captureConfig CaptureConfig) error {
+	applier, err := newChangeApplier(ctx, c.src, c.dst, captureConfig.MigrationConfig)
+	if err != nil {
+		return err
+	}
+
+	batchSize := captureConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		applied, err := c.applyBatch(ctx, applier, batchSize)
+		if err != nil {
+			return err
+		}
+		if applied == 0 {
+			return nil
+		}
+
+		c.watcher.CaptureDidApplyChanges(applied)
+	}
+}
+
+func (c *capturer) applyBatch(ctx context.Context, applier *changeApplier, batchSize int) (int64, error) {
+	rows, err := c.src.DB().QueryContext(ctx, fmt.Sprintf(
+		"SELECT id, table_name, operation, old_row, new_row FROM %s ORDER BY id LIMIT $1",
+		ChangelogTable,
+	), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read changes: %s", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	var changes []Change
+	for rows.Next() {
+		var id int64
+		var tableName, operation string
+		var oldRow, newRow []byte
+		if err := rows.Scan(&id, &tableName, &operation, &oldRow, &newRow); err != nil {
+			return 0, fmt.Errorf("failed to scan change: %s", err)
+		}
+
+		change := Change{Table: tableName}
+		switch operation {
+		case "INSERT":
+			change.Kind = ChangeInsert
+		case "UPDATE":
+			change.Kind = ChangeUpdate
+		case "DELETE":
+			change.Kind = ChangeDelete
+		case "TRUNCATE":
+			change.Kind = ChangeTruncate
+		default:
+			return 0, fmt.Errorf("unknown operation '%s' in change %d", operation, id)
+		}
+
+		if change.Key, err = decodeRowJSON(oldRow); err != nil {
+			return 0, fmt.Errorf("failed to decode change %d: %s", id, err)
+		}
+		if change.Columns, err = decodeRowJSON(newRow); err != nil {
+			return 0, fmt.Errorf("failed to decode change %d: %s", id, err)
+		}
+
+		ids = append(ids, id)
+		changes = append(changes, change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed iterating through changes: %s", err)
+	}
+
+	if len(changes) == 0 {
+		return 0, nil
+	}
+
+	// Like repairs, a batch that has started is committed even when the
+	// run is interrupted
+	tx, err := c.dst.DB().Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %s", err)
+	}
+
+	for _, change := range changes {
+		if err := applier.apply(ctx, tx, change); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %s", err)
+	}
+
+	// Changes are removed by id rather than up to the last one, as a
+	// transaction that was given a lower id may not have committed yet
+	_, err = c.src.DB().Exec(
+		fmt.Sprintf("DELETE FROM %s WHERE id = ANY($1)", ChangelogTable),
+		pq.Array(ids),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove applied changes: %s", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// decodeRowJSON decodes a row rendered by row_to_json, keeping numbers as
+// they were written.
+func decodeRowJSON(data []byte) (map[string]interface{}, error) {
+	if data == nil {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var row map[string]interface{}
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	return row, nil
+}
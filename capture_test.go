@@ -0,0 +1,172 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("DecodeChangeValue", func() {
+	decode := func(columnType string, value interface{}) interface{} {
+		decoded, err := pg2mysql.DecodeChangeValue(&pg2mysql.Column{Name: "some_column", Type: columnType}, value)
+		Expect(err).NotTo(HaveOccurred())
+		return decoded
+	}
+
+	It("decodes values the way the PostgreSQL driver scans them", func() {
+		Expect(decode("numeric", json.Number("1.50"))).To(Equal("1.50"))
+		Expect(decode("text", "some-text")).To(Equal("some-text"))
+		Expect(decode("boolean", true)).To(Equal(true))
+		Expect(decode("text", nil)).To(BeNil())
+		Expect(decode("bytea", `\xff00`)).To(Equal([]byte{0xff, 0x00}))
+		Expect(decode("jsonb", "some-string")).To(Equal([]byte("some-string")))
+		Expect(decode("jsonb", map[string]interface{}{"a": json.Number("1")})).To(Equal([]byte(`{"a":1}`)))
+		Expect(decode("integer[]", []interface{}{json.Number("1")})).To(Equal([]interface{}{json.Number("1")}))
+	})
+
+	It("parses timestamps rendered by to_json", func() {
+		decoded := decode("timestamp with time zone", "2017-03-24T12:30:15.5+02:00")
+		Expect(decoded).To(BeTemporally("==", time.Date(2017, 3, 24, 10, 30, 15, 500000000, time.UTC)))
+	})
+
+	It("rejects bytea that isn't in hex format", func() {
+		_, err := pg2mysql.DecodeChangeValue(&pg2mysql.Column{Type: "bytea"}, `\001`)
+		Expect(err).To(MatchError("bytea not in hex format"))
+	})
+})
+
+var _ = Describe("SameValues", func() {
+	It("compares keys by their values rather than how they print", func() {
+		at := time.Date(2017, 3, 24, 12, 30, 15, 0, time.UTC)
+		uuid, err := pg2mysql.ParseUUID("6ccd780c-baba-1026-9564-5b8c656024db")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pg2mysql.SameValues(
+			[]interface{}{[]byte{0xff}, at, pg2mysql.PackedUUID{UUID: uuid}, "1", nil},
+			[]interface{}{[]byte{0xff}, at.In(time.FixedZone("UTC+2", 2*60*60)), pg2mysql.PackedUUID{UUID: uuid}, "1", nil},
+		)).To(BeTrue())
+
+		Expect(pg2mysql.SameValues([]interface{}{[]byte{0xff}}, []interface{}{[]byte{0xfe}})).To(BeFalse())
+		Expect(pg2mysql.SameValues([]interface{}{[]byte("1")}, []interface{}{"1"})).To(BeFalse())
+		Expect(pg2mysql.SameValues([]interface{}{nil}, []interface{}{"<nil>"})).To(BeFalse())
+		Expect(pg2mysql.SameValues([]interface{}{at}, []interface{}{at.Add(time.Microsecond)})).To(BeFalse())
+	})
+})
+
+var _ = Describe("Capturer", func() {
+	var (
+		mysql    pg2mysql.DB
+		pg       pg2mysql.DB
+		capturer pg2mysql.Capturer
+
+		config = pg2mysql.CaptureConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			BatchSize: 2,
+		}
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE items (
+				id int PRIMARY KEY,
+				name text
+			);
+			INSERT INTO items VALUES (1, 'a'), (2, 'b');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE items (id int PRIMARY KEY, name text)",
+			"INSERT INTO items VALUES (1, 'a'), (2, 'b')",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		capturer = pg2mysql.NewCapturer(pg, mysql, &pg2mysqlfakes.FakeCapturerWatcher{})
+		Expect(capturer.Install(context.Background(), config.MigrationConfig)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(capturer.Uninstall(context.Background(), config.MigrationConfig)).To(Succeed())
+
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE items")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE items")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	items := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(id, ':', name) FROM items ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var items []string
+		for rows.Next() {
+			var item string
+			Expect(rows.Scan(&item)).To(Succeed())
+			items = append(items, item)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return items
+	}
+
+	It("applies the captured changes in order and removes them from the changelog", func() {
+		for _, stmt := range []string{
+			"INSERT INTO items VALUES (3, 'c')",
+			"UPDATE items SET name = 'd' WHERE id = 1",
+			"UPDATE items SET id = 4 WHERE id = 2",
+			"INSERT INTO items VALUES (5, 'e')",
+			"DELETE FROM items WHERE id = 5",
+			"UPDATE items SET name = 'f' WHERE id = 3",
+		} {
+			_, err := pgRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		Expect(capturer.Apply(context.Background(), config)).To(Succeed())
+		Expect(items()).To(Equal([]string{"1:d", "3:f", "4:b"}))
+
+		var changes int
+		Expect(pgRunner.DB().QueryRow("SELECT COUNT(*) FROM " + pg2mysql.ChangelogTable).Scan(&changes)).To(Succeed())
+		Expect(changes).To(BeZero())
+
+		// Applying again finds nothing left to apply
+		Expect(capturer.Apply(context.Background(), config)).To(Succeed())
+		Expect(items()).To(Equal([]string{"1:d", "3:f", "4:b"}))
+	})
+
+	It("leaves the changelog out of the schema to migrate", func() {
+		schema, err := pg2mysql.BuildSchema(context.Background(), pg)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = schema.GetTable(pg2mysql.ChangelogTable)
+		Expect(err).To(HaveOccurred())
+		_, err = schema.GetTable("items")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("applies truncates", func() {
+		_, err := pgRunner.DB().Exec("TRUNCATE items")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(capturer.Apply(context.Background(), config)).To(Succeed())
+		Expect(items()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,482 @@
+package pg2mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ChangeKind string
+
+const (
+	ChangeInsert   ChangeKind = "insert"
+	ChangeUpdate   ChangeKind = "update"
+	ChangeDelete   ChangeKind = "delete"
+	ChangeTruncate ChangeKind = "truncate"
+)
+
+// Change is a change to a row of a PostgreSQL table, captured after the fact
+// to be applied to MySQL. Values are as they are decoded from JSON
+// rendered by PostgreSQL: nil, bools, json.Number or strings.
+type Change struct {
+	Kind  ChangeKind
+	Table string
+
+	// Columns holds the values of the row after an insert or update, by
+	// column name. Updates may leave out columns they didn't change, as
+	// wal2json does for TOASTed values.
+	Columns map[string]interface{}
+
+	// Key holds the primary key of the row before an update or delete, by
+	// column name. Updates without it are applied by the key in Columns.
+	Key map[string]interface{}
+}
+
+// changeApplier applies changes to MySQL by primary key: inserts and updates
+// are upserted and deletes delete the row with the key, so that applying a
+// change more than once, or to a row the snapshot already has, is harmless.
+type changeApplier struct {
+	src, dst     DB
+	srcSchema    *Schema
+	dstSchema    *Schema
+	options      ConversionOptions
+	ignoreTables []string
+	tables       map[string]*changeTable
+	childTables  map[string][]*arrayChildTable
+}
+
+type changeTable struct {
+	table       *Table
+	primaryKey  []string
+	keyIndexes  []int
+	converter   *RowConverter
+	upsertQuery string
+	deleteQuery string
+	children    []*arrayChildTable
+}
+
+func newChangeApplier(ctx context.Context, src, dst DB, migrationConfig MigrationConfig) (*changeApplier, error) {
+	srcSchema, err := BuildSchema(ctx, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build source schema: %s", err)
+	}
+
+	dstSchema, err := BuildSchema(ctx, dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	options, err := conversionOptions(ctx, dst, migrationConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	childTables := map[string][]*arrayChildTable{}
+	for _, child := range splitArrayColumns(srcSchema, options.Arrays) {
+		childTables[child.parent.Name] = append(childTables[child.parent.Name], child)
+	}
+
+	return &changeApplier{
+		src:          src,
+		dst:          dst,
+		srcSchema:    srcSchema,
+		dstSchema:    dstSchema,
+		options:      options,
+		ignoreTables: migrationConfig.IgnoreTables,
+		tables:       map[string]*changeTable{},
+		childTables:  childTables,
+	}, nil
+}
+
+// applies is whether changes to the table are applied: it has to be one of
+// the tables the migrator copies.
+func (a *changeApplier) applies(tableName string) bool {
+	_, err := a.srcSchema.GetTable(tableName)
+	return err == nil && !ignoreTable(tableName, a.ignoreTables)
+}
+
+func (a *changeApplier) table(ctx context.Context, tableName string) (*changeTable, error) {
+	if t, ok := a.tables[tableName]; ok {
+		return t, nil
+	}
+
+	table, err := a.srcSchema.GetTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	primaryKey, err := a.src.GetPrimaryKey(ctx, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+
+	keyIndexes := make([]int, len(primaryKey))
+	for i, column := range primaryKey {
+		keyIndexes[i], _, err = table.GetColumn(column)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dstTable, _ := a.dstSchema.GetTable(tableName)
+	converter := NewRowConverter(table, dstTable, a.options)
+
+	t := &changeTable{
+		table:       table,
+		primaryKey:  primaryKey,
+		keyIndexes:  keyIndexes,
+		converter:   converter,
+		upsertQuery: upsertStatement(table, primaryKey, converter),
+		deleteQuery: fmt.Sprintf("DELETE FROM `%s` WHERE %s", tableName, keyCondition(a.dst, primaryKey, "=", "?")),
+		children:    a.childTables[tableName],
+	}
+	a.tables[tableName] = t
+
+	return t, nil
+}
+
+// apply applies change in tx. Changes to tables that aren't migrated are
+// skipped.
+func (a *changeApplier) apply(ctx context.Context, tx *sql.Tx, change Change) error {
+	if !a.applies(change.Table) {
+		return nil
+	}
+
+	t, err := a.table(ctx, change.Table)
+	if err != nil {
+		return err
+	}
+
+	if change.Kind == ChangeTruncate {
+		for _, child := range t.children {
+			if _, err := tx.Exec(fmt.Sprintf("DELETE FROM `%s`", child.name())); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(fmt.Sprintf("DELETE FROM `%s`", change.Table))
+		return err
+	}
+
+	if len(t.primaryKey) == 0 && (change.Kind != ChangeInsert || len(t.children) > 0) {
+		return fmt.Errorf("cannot apply %s to table '%s' without a primary key", change.Kind, change.Table)
+	}
+
+	var oldKey []interface{}
+	if change.Key != nil {
+		oldKey, err = t.key(change.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if change.Kind == ChangeUpdate {
+		columns, present, complete := t.presentColumns(change)
+		if !complete {
+			return a.applyPartialUpdate(tx, t, oldKey, columns, present)
+		}
+		change.Columns = columns
+	}
+
+	switch change.Kind {
+	case ChangeInsert, ChangeUpdate:
+		values, err := t.row(change.Columns)
+		if err != nil {
+			return err
+		}
+
+		// A row whose key changed is deleted first, so the upsert
+		// doesn't leave it behind
+		if oldKey != nil && !sameValues(oldKey, t.keyOf(values)) {
+			if _, err := tx.Exec(t.deleteQuery, oldKey...); err != nil {
+				return fmt.Errorf("failed deleting from %s: %s", change.Table, err)
+			}
+		}
+
+		if _, err := tx.Exec(t.upsertQuery, values...); err != nil {
+			return fmt.Errorf("failed upserting into %s: %w", change.Table, err)
+		}
+
+		if err := a.replaceChildRows(tx, t, t.children, oldKey, t.keyOf(values), change.Columns); err != nil {
+			return err
+		}
+	case ChangeDelete:
+		if oldKey == nil {
+			return fmt.Errorf("delete from %s has no key", change.Table)
+		}
+
+		if err := a.replaceChildRows(tx, t, t.children, oldKey, nil, nil); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(t.deleteQuery, oldKey...); err != nil {
+			return fmt.Errorf("failed deleting from %s: %s", change.Table, err)
+		}
+	default:
+		return fmt.Errorf("unknown change '%s' to %s", change.Kind, change.Table)
+	}
+
+	return nil
+}
+
+// applyPartialUpdate applies an update that left out some columns, writing
+// only the columns in present so that those left out keep their values in
+// MySQL. A row whose key changed is updated in place rather than deleted and
+// upserted, which would lose them.
+func (a *changeApplier) applyPartialUpdate(tx *sql.Tx, t *changeTable, oldKey []interface{}, columns map[string]interface{}, present []bool) error {
+	values, err := t.row(columns)
+	if err != nil {
+		return err
+	}
+
+	key := t.keyOf(values)
+	if oldKey == nil {
+		oldKey = key
+	}
+	keyChanged := !sameValues(oldKey, key)
+
+	var children []*arrayChildTable
+	for _, child := range t.children {
+		if _, ok := columns[child.column.Name]; ok {
+			children = append(children, child)
+			continue
+		}
+		if keyChanged {
+			return fmt.Errorf("cannot apply update changing the key of %s without the value of %s; set REPLICA IDENTITY FULL on it", t.table.Name, child.column.Name)
+		}
+	}
+
+	var args []interface{}
+	for i, value := range values {
+		if present[i] {
+			args = append(args, value)
+		}
+	}
+
+	if keyChanged {
+		if _, err := tx.Exec(t.partialUpdateStatement(a.dst, present), append(args, oldKey...)...); err != nil {
+			return fmt.Errorf("failed updating %s: %w", t.table.Name, err)
+		}
+	} else if _, err := tx.Exec(t.partialUpsertStatement(present), args...); err != nil {
+		return fmt.Errorf("failed upserting into %s: %w", t.table.Name, err)
+	}
+
+	return a.replaceChildRows(tx, t, children, oldKey, key, columns)
+}
+
+// replaceChildRows deletes the rows of children, child tables of t, for the
+// row with oldKey and key, and inserts those for the arrays in columns.
+func (a *changeApplier) replaceChildRows(tx *sql.Tx, t *changeTable, children []*arrayChildTable, oldKey, key []interface{}, columns map[string]interface{}) error {
+	for _, child := range children {
+		for _, k := range [][]interface{}{oldKey, key} {
+			if k == nil {
+				continue
+			}
+			if _, err := tx.Exec(child.deleteStatement(a.dst, t.primaryKey), k...); err != nil {
+				return fmt.Errorf("failed deleting from %s: %s", child.name(), err)
+			}
+		}
+
+		if key == nil {
+			continue
+		}
+
+		value, err := decodeChangeValue(child.column, columns[child.column.Name])
+		if err != nil {
+			return fmt.Errorf("invalid value for %s.%s: %s", t.table.Name, child.column.Name, err)
+		}
+
+		rows, err := child.rows(key, value)
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			if _, err := tx.Exec(child.insertStatement(t.primaryKey), row...); err != nil {
+				return fmt.Errorf("failed inserting into %s: %s", child.name(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// presentColumns returns the columns of an update, with those it left out
+// taken from the old row where the change has them, as it does for tables
+// with REPLICA IDENTITY FULL, which columns of the table it has values for,
+// and whether it has them all.
+func (t *changeTable) presentColumns(change Change) (map[string]interface{}, []bool, bool) {
+	columns := make(map[string]interface{}, len(t.table.Columns))
+	present := make([]bool, len(t.table.Columns))
+	complete := true
+	for i, column := range t.table.Columns {
+		value, ok := change.Columns[column.Name]
+		if !ok {
+			value, ok = change.Key[column.Name]
+		}
+		if !ok {
+			complete = false
+			continue
+		}
+		columns[column.Name] = value
+		present[i] = true
+	}
+
+	return columns, present, complete
+}
+
+// partialUpsertStatement upserts the columns in present, leaving the others
+// of a row that exists as they are.
+func (t *changeTable) partialUpsertStatement(present []bool) string {
+	isKey := map[string]bool{}
+	for _, column := range t.primaryKey {
+		isKey[column] = true
+	}
+
+	var columnNames, placeholders, assignments []string
+	for i, column := range t.table.Columns {
+		if !present[i] {
+			continue
+		}
+		columnNames = append(columnNames, fmt.Sprintf("`%s`", column.Name))
+		placeholders = append(placeholders, t.converter.WritePlaceholder(i))
+		if !isKey[column.Name] {
+			assignments = append(assignments, fmt.Sprintf("`%s` = VALUES(`%s`)", column.Name, column.Name))
+		}
+	}
+	if len(assignments) == 0 {
+		for _, column := range t.primaryKey {
+			assignments = append(assignments, fmt.Sprintf("`%s` = VALUES(`%s`)", column, column))
+		}
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO `%s` (%s) VALUES (%s) ON DUPLICATE KEY UPDATE %s",
+		t.table.Name,
+		strings.Join(columnNames, ","),
+		strings.Join(placeholders, ","),
+		strings.Join(assignments, ","),
+	)
+}
+
+// partialUpdateStatement updates the columns in present, key included, of
+// the row with the key that follows their values in its arguments.
+func (t *changeTable) partialUpdateStatement(db DB, present []bool) string {
+	var assignments []string
+	for i, column := range t.table.Columns {
+		if present[i] {
+			assignments = append(assignments, fmt.Sprintf("`%s` = %s", column.Name, t.converter.WritePlaceholder(i)))
+		}
+	}
+
+	return fmt.Sprintf(
+		"UPDATE `%s` SET %s WHERE %s",
+		t.table.Name,
+		strings.Join(assignments, ","),
+		keyCondition(db, t.primaryKey, "=", "?"),
+	)
+}
+
+// row returns the converted values of every column of the table, in order.
+func (t *changeTable) row(columns map[string]interface{}) ([]interface{}, error) {
+	values := make([]interface{}, len(t.table.Columns))
+	for i, column := range t.table.Columns {
+		value, err := decodeChangeValue(column, columns[column.Name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s.%s: %s", t.table.Name, column.Name, err)
+		}
+		values[i] = value
+	}
+
+	values = t.converter.Convert(values)
+	if err := t.converter.Check(values); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %s", t.table.Name, err)
+	}
+
+	return values, nil
+}
+
+// key returns the converted values of the primary key, in order.
+func (t *changeTable) key(columns map[string]interface{}) ([]interface{}, error) {
+	values, err := t.row(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.keyOf(values), nil
+}
+
+func (t *changeTable) keyOf(values []interface{}) []interface{} {
+	key := make([]interface{}, len(t.keyIndexes))
+	for i, index := range t.keyIndexes {
+		key[i] = values[index]
+	}
+
+	return key
+}
+
+// sameValues is whether a and b hold the same converted values. Times are
+// the same instant whatever their zone.
+func sameValues(a, b []interface{}) bool {
+	for i := range a {
+		if !sameValue(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sameValue(a, b interface{}) bool {
+	switch v := a.(type) {
+	case []byte:
+		other, ok := b.([]byte)
+		return ok && bytes.Equal(v, other)
+	case time.Time:
+		other, ok := b.(time.Time)
+		return ok && v.Equal(other)
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+// decodeChangeValue turns a value of column decoded from JSON into what the
+// PostgreSQL driver would have scanned for it, so it can be converted the
+// same way as rows read from the table.
+func decodeChangeValue(column *Column, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		return v.String(), nil
+	case string:
+		switch column.Type {
+		case "json", "jsonb":
+			// Scanned as bytes, like the driver does
+			return []byte(v), nil
+		case "timestamp with time zone", "timestamp without time zone", "date":
+			// Rendered as ISO 8601 by to_json and with a space otherwise
+			return pq.ParseTimestamp(nil, strings.Replace(v, "T", " ", 1))
+		case "bytea":
+			if !strings.HasPrefix(v, `\x`) {
+				return nil, fmt.Errorf("bytea not in hex format")
+			}
+			return hex.DecodeString(v[2:])
+		}
+		return v, nil
+	case map[string]interface{}, []interface{}:
+		// row_to_json renders arrays as JSON arrays, which parseArray
+		// takes as they are, and nests json and jsonb columns
+		if isArrayColumn(column) {
+			return v, nil
+		}
+		return json.Marshal(v)
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,69 @@
+package pg2mysql
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Checkpoint records how far an interrupted migration got, so that running
+// it again can skip the tables it already finished.
+type Checkpoint struct {
+	CompletedTables  []string  `json:"completed_tables"`
+	InterruptedTable string    `json:"interrupted_table,omitempty"`
+	InterruptedAt    time.Time `json:"interrupted_at"`
+}
+
+// ReadCheckpoint reads the checkpoint at path. A missing file is an empty
+// checkpoint.
+func ReadCheckpoint(path string) (*Checkpoint, error) {
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Checkpoint{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(bs, &checkpoint); err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+func WriteCheckpoint(path string, checkpoint *Checkpoint) error {
+	bs, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, append(bs, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// RemoveCheckpoint removes the checkpoint at path, if there is one.
+func RemoveCheckpoint(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (c *Checkpoint) Completed(tableName string) bool {
+	for _, name := range c.CompletedTables {
+		if name == tableName {
+			return true
+		}
+	}
+
+	return false
+}
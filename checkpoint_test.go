@@ -0,0 +1,121 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("Checkpoint", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pg2mysql-checkpoint")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(dir, "checkpoint.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("is empty when there is no file", func() {
+		checkpoint, err := pg2mysql.ReadCheckpoint(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkpoint.Completed("some_table")).To(BeFalse())
+	})
+
+	It("reads back the tables that were completed", func() {
+		err := pg2mysql.WriteCheckpoint(path, &pg2mysql.Checkpoint{
+			CompletedTables:  []string{"some_table"},
+			InterruptedTable: "other_table",
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		checkpoint, err := pg2mysql.ReadCheckpoint(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkpoint.Completed("some_table")).To(BeTrue())
+		Expect(checkpoint.Completed("other_table")).To(BeFalse())
+		Expect(checkpoint.InterruptedTable).To(Equal("other_table"))
+
+		Expect(pg2mysql.RemoveCheckpoint(path)).To(Succeed())
+		Expect(pg2mysql.RemoveCheckpoint(path)).To(Succeed())
+	})
+})
+
+var _ = Describe("Resuming from a checkpoint", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+		dir   string
+
+		config pg2mysql.MigrationConfig
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE widgets (id int PRIMARY KEY);
+			INSERT INTO widgets VALUES (1), (2);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec("CREATE TABLE widgets (id int PRIMARY KEY)")
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		dir, err = ioutil.TempDir("", "pg2mysql-checkpoint")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables:   []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			CheckpointFile: filepath.Join(dir, "checkpoint.json"),
+		}
+		Expect(pg2mysql.WriteCheckpoint(config.CheckpointFile, &pg2mysql.Checkpoint{
+			CompletedTables: []string{"widgets"},
+		})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+		os.RemoveAll(dir)
+
+		_, err := pgRunner.DB().Exec("DROP TABLE widgets")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE widgets")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	widgets := func() int {
+		var count int
+		Expect(mysqlRunner.DB().QueryRow("SELECT COUNT(*) FROM widgets").Scan(&count)).To(Succeed())
+		return count
+	}
+
+	It("ignores and removes an earlier checkpoint unless resuming", func() {
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{}).Migrate(context.Background(), config)).To(Succeed())
+		Expect(widgets()).To(Equal(2))
+		Expect(config.CheckpointFile).NotTo(BeAnExistingFile())
+	})
+
+	It("skips the tables of the checkpoint when resuming", func() {
+		config.Resume = true
+
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{}).Migrate(context.Background(), config)).To(Succeed())
+		Expect(widgets()).To(BeZero())
+		Expect(config.CheckpointFile).NotTo(BeAnExistingFile())
+	})
+})
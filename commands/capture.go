@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"fmt"
+	"pg2mysql"
+)
+
+type CaptureCommand struct {
+	Install   CaptureInstallCommand   `command:"install" description:"Create the changelog table and a trigger on each table to fill it"`
+	Apply     CaptureApplyCommand     `command:"apply" description:"Apply the changes in the changelog to MySQL"`
+	Uninstall CaptureUninstallCommand `command:"uninstall" description:"Drop the triggers and the changelog table"`
+}
+
+type CaptureInstallCommand struct{}
+
+func (c *CaptureInstallCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	pg, err := openPostgreSQL()
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = pg2mysql.NewCapturer(pg, nil, watcher).Install(ctx, migrationConfig())
+	if err != nil {
+		return fmt.Errorf("failed installing change capture: %s", err)
+	}
+
+	return nil
+}
+
+type CaptureApplyCommand struct {
+	BatchSize int `long:"batch-size" default:"1000" description:"Number of changes to apply in each transaction"`
+}
+
+func (c *CaptureApplyCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	pg, err := openPostgreSQL()
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+
+	mysql := pg2mysql.NewMySQLDB(
+		PG2MySQL.Config.MySQL.Database,
+		PG2MySQL.Config.MySQL.Username,
+		PG2MySQL.Config.MySQL.Password,
+		PG2MySQL.Config.MySQL.Host,
+		PG2MySQL.Config.MySQL.Port,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
+	)
+
+	err = mysql.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer mysql.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	config := pg2mysql.CaptureConfig{
+		MigrationConfig: migrationConfig(),
+		BatchSize:       c.BatchSize,
+	}
+
+	err = pg2mysql.NewCapturer(pg, mysql, watcher).Apply(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed applying changes: %s", err)
+	}
+
+	return nil
+}
+
+type CaptureUninstallCommand struct{}
+
+func (c *CaptureUninstallCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	pg, err := openPostgreSQL()
+	if err != nil {
+		return err
+	}
+	defer pg.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = pg2mysql.NewCapturer(pg, nil, watcher).Uninstall(ctx, migrationConfig())
+	if err != nil {
+		return fmt.Errorf("failed uninstalling change capture: %s", err)
+	}
+
+	return nil
+}
+
+func openPostgreSQL() (pg2mysql.DB, error) {
+	pg := pg2mysql.NewPostgreSQLDB(
+		PG2MySQL.Config.PostgreSQL.Database,
+		PG2MySQL.Config.PostgreSQL.Username,
+		PG2MySQL.Config.PostgreSQL.Password,
+		PG2MySQL.Config.PostgreSQL.Host,
+		PG2MySQL.Config.PostgreSQL.Port,
+		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
+	)
+	if err := pg.Open(); err != nil {
+		return nil, fmt.Errorf("failed to open pg connection: %s", err)
+	}
+
+	return pg, nil
+}
@@ -3,20 +3,37 @@ package commands
 import (
 	"fmt"
 	"pg2mysql"
+	"time"
 )
 
 type MigrateCommand struct {
-	Truncate bool `long:"truncate" description:"Truncate destination tables before migrating data"`
+	Truncate      bool   `long:"truncate" description:"Truncate destination tables before migrating data"`
+	DeadLetterDir string `long:"dead-letter-dir" default:"dead-letters" description:"Directory to record rows that fail to insert in, one JSONL file per table"`
+
+	MaxErrors         int64 `long:"max-errors" description:"Stop once more than this many rows fail to insert (0 for no limit)"`
+	MaxErrorsPerTable int64 `long:"max-errors-per-table" description:"Stop once more than this many rows of one table fail to insert (0 for no limit)"`
+	FailFast          bool  `long:"fail-fast" description:"Stop at the first row that fails to insert"`
+
+	ProgressInterval time.Duration `long:"progress-interval" default:"10s" description:"How often to report the progress of each table"`
+	CheckpointFile   string        `long:"checkpoint-file" default:"pg2mysql-checkpoint.json" description:"File to record finished tables in when interrupted, and to resume from"`
+	Resume           bool          `long:"resume" description:"Skip the tables the checkpoint file records as finished by an interrupted migration"`
+
+	EnforceForeignKeys bool   `long:"enforce-foreign-keys" description:"Keep foreign key checks on, filling in columns that reference rows not migrated yet at the end"`
+	WriteMode          string `long:"write-mode" default:"insert" choice:"insert" choice:"insert-ignore" choice:"upsert" choice:"replace" description:"How to write rows: insert missing rows, insert ignoring conflicts, or upsert or replace rows that differ"`
 }
 
 func (c *MigrateCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
 	mysql := pg2mysql.NewMySQLDB(
 		PG2MySQL.Config.MySQL.Database,
 		PG2MySQL.Config.MySQL.Username,
 		PG2MySQL.Config.MySQL.Password,
 		PG2MySQL.Config.MySQL.Host,
 		PG2MySQL.Config.MySQL.Port,
-		PG2MySQL.Config.MySQL.Params,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
 	)
 
 	err := mysql.Open()
@@ -32,6 +49,7 @@ func (c *MigrateCommand) Execute([]string) error {
 		PG2MySQL.Config.PostgreSQL.Host,
 		PG2MySQL.Config.PostgreSQL.Port,
 		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
 	)
 	err = pg.Open()
 	if err != nil {
@@ -39,11 +57,23 @@ func (c *MigrateCommand) Execute([]string) error {
 	}
 	defer pg.Close()
 
-	watcher := pg2mysql.NewStdoutPrinter()
-	err = pg2mysql.NewMigrator(pg, mysql, c.Truncate, watcher).Migrate(
-		pg2mysql.MigrationConfig{
-			IgnoreTables: PG2MySQL.Config.PostgreSQL.IgnoredTables,
-		})
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	config := migrationConfig()
+	config.DeadLetterDir = c.DeadLetterDir
+	config.MaxErrors = c.MaxErrors
+	config.MaxErrorsPerTable = c.MaxErrorsPerTable
+	config.FailFast = c.FailFast
+	config.ProgressInterval = c.ProgressInterval
+	config.CheckpointFile = c.CheckpointFile
+	config.Resume = c.Resume
+	config.EnforceForeignKeys = c.EnforceForeignKeys
+	config.WriteMode = pg2mysql.WriteMode(c.WriteMode)
+
+	err = pg2mysql.NewMigrator(pg, mysql, c.Truncate, watcher).Migrate(ctx, config)
 	if err != nil {
 		return fmt.Errorf("failed migrating: %s", err)
 	}
@@ -1,10 +1,18 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
 	"pg2mysql"
+	"syscall"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -22,6 +30,10 @@ func (c *ConfigFilePath) UnmarshalFlag(value string) error {
 		return fmt.Errorf("failed to unmarshal config: %s", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %s", err)
+	}
+
 	PG2MySQL.Config = config
 
 	return nil
@@ -31,10 +43,136 @@ type PG2MySQLCommand struct {
 	Config pg2mysql.Config
 
 	ConfigFile ConfigFilePath `short:"c" long:"config" required:"true" description:"Path to config file"`
+	LogFormat  string         `long:"log-format" default:"text" choice:"text" choice:"json" description:"Format of the progress output"`
+
+	JSONLog     string `long:"json-log" description:"Also append JSON events to this file"`
+	MetricsAddr string `long:"metrics-addr" description:"Address to serve Prometheus metrics on while running, e.g. :9102"`
 
-	Validate ValidateCommand `command:"validate" description:"Validate that the data in PostgreSQL can be migrated to MySQL"`
-	Migrate  MigrateCommand  `command:"migrate" description:"Migrate data from PostgreSQL to MySQL"`
-	Verify   VerifyCommand   `command:"verify" description:"Verify migrated data matches"`
+	Validate  ValidateCommand  `command:"validate" description:"Validate that the data in PostgreSQL can be migrated to MySQL"`
+	Migrate   MigrateCommand   `command:"migrate" description:"Migrate data from PostgreSQL to MySQL"`
+	Verify    VerifyCommand    `command:"verify" description:"Verify migrated data matches"`
+	Repair    RepairCommand    `command:"repair" description:"Sync rows in MySQL that differ from PostgreSQL"`
+	Replay    ReplayCommand    `command:"replay" description:"Retry inserting rows that failed during migration"`
+	Sync      SyncCommand      `command:"sync" description:"Copy rows changed since the last sync from PostgreSQL to MySQL"`
+	Replicate ReplicateCommand `command:"replicate" description:"Copy the data, then keep applying changes from a logical replication slot"`
+	Capture   CaptureCommand   `command:"capture" description:"Capture changes with triggers, for when replication slots aren't available"`
 }
 
 var PG2MySQL PG2MySQLCommand
+
+// migrationConfig is the part of the config shared by every command that
+// reads from PostgreSQL and writes to or compares with MySQL.
+func migrationConfig() pg2mysql.MigrationConfig {
+	arrays := pg2mysql.ArrayConversions{}
+	specialValues := pg2mysql.SpecialValuePolicies{}
+	for name, table := range PG2MySQL.Config.Tables {
+		if len(table.Arrays) > 0 {
+			arrays[name] = table.Arrays
+		}
+		if len(table.SpecialValues) > 0 {
+			specialValues[name] = table.SpecialValues
+		}
+	}
+
+	return pg2mysql.MigrationConfig{
+		IgnoreTables:        PG2MySQL.Config.PostgreSQL.IgnoredTables,
+		TimestampRounding:   PG2MySQL.Config.MySQL.TimestampRounding,
+		SourceTimezone:      PG2MySQL.Config.SourceTimezone,
+		DestinationTimezone: PG2MySQL.Config.DestinationTimezone,
+		Arrays:              arrays,
+		UUIDSwapTimeFields:  PG2MySQL.Config.MySQL.UUIDSwapTimeFields,
+		SpecialValues:       specialValues,
+	}
+}
+
+// mysqlParams returns the DSN params of the MySQL config with the session
+// variables to set on every connection: defaults, overridden by the config's
+// session_variables. Config.Validate keeps params from overriding those.
+func mysqlParams(defaults map[string]string) map[string]string {
+	params := map[string]string{}
+	for name, value := range defaults {
+		params[name] = value
+	}
+	for name, value := range PG2MySQL.Config.MySQL.SessionVariables {
+		params[name] = value
+	}
+	for name, value := range PG2MySQL.Config.MySQL.Params {
+		params[name] = value
+	}
+
+	return params
+}
+
+// newWatcher returns the watchers asked for by the global flags: text or JSON
+// on stdout, JSON to --json-log, and metrics when --metrics-addr is set.
+func newWatcher() (pg2mysql.Watcher, error) {
+	var watchers []pg2mysql.Watcher
+	if PG2MySQL.LogFormat == "json" {
+		watchers = append(watchers, pg2mysql.NewJSONLinesWatcher(os.Stdout))
+	} else {
+		watchers = append(watchers, pg2mysql.NewStdoutPrinter())
+	}
+
+	if PG2MySQL.JSONLog != "" {
+		// Events are written as they happen, so the file is left for the
+		// process to close
+		file, err := os.OpenFile(PG2MySQL.JSONLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open json log: %s", err)
+		}
+		watchers = append(watchers, pg2mysql.NewJSONLinesWatcher(file))
+	}
+
+	if PG2MySQL.MetricsAddr != "" {
+		metricsWatcher, err := serveMetrics(PG2MySQL.MetricsAddr)
+		if err != nil {
+			return nil, err
+		}
+		watchers = append(watchers, metricsWatcher)
+	}
+
+	if len(watchers) == 1 {
+		return watchers[0], nil
+	}
+
+	children := make([]interface{}, len(watchers))
+	for i, watcher := range watchers {
+		children[i] = watcher
+	}
+
+	return pg2mysql.NewMultiWatcher(children...), nil
+}
+
+func serveMetrics(addr string) (*pg2mysql.MetricsWatcher, error) {
+	registry := prometheus.NewRegistry()
+	metricsWatcher, err := pg2mysql.NewMetricsWatcher(registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register metrics: %s", err)
+	}
+
+	// Listen before starting so that an address in use fails the command
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for metrics: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	go http.Serve(listener, mux)
+
+	return metricsWatcher, nil
+}
+
+// interruptContext returns a context that is cancelled on SIGINT or SIGTERM,
+// so that a command can stop what it's doing and clean up. A second signal
+// kills the process as usual.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	return ctx, stop
+}
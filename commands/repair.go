@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"fmt"
+	"pg2mysql"
+)
+
+type RepairOptions struct {
+	DeleteExtra bool `long:"delete-extra" description:"Delete rows from MySQL that no longer exist in PostgreSQL"`
+	DryRun      bool `long:"dry-run" description:"Print the SQL that would be run instead of running it"`
+	BatchSize   int  `long:"batch-size" default:"1000" description:"Number of statements to run in each transaction"`
+}
+
+func (o RepairOptions) RepairConfig() pg2mysql.RepairConfig {
+	return pg2mysql.RepairConfig{
+		MigrationConfig: migrationConfig(),
+		DeleteExtraRows: o.DeleteExtra,
+		DryRun:          o.DryRun,
+		BatchSize:       o.BatchSize,
+	}
+}
+
+type RepairCommand struct {
+	RepairOptions
+}
+
+func (c *RepairCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	mysql := pg2mysql.NewMySQLDB(
+		PG2MySQL.Config.MySQL.Database,
+		PG2MySQL.Config.MySQL.Username,
+		PG2MySQL.Config.MySQL.Password,
+		PG2MySQL.Config.MySQL.Host,
+		PG2MySQL.Config.MySQL.Port,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
+	)
+
+	err := mysql.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer mysql.Close()
+
+	pg := pg2mysql.NewPostgreSQLDB(
+		PG2MySQL.Config.PostgreSQL.Database,
+		PG2MySQL.Config.PostgreSQL.Username,
+		PG2MySQL.Config.PostgreSQL.Password,
+		PG2MySQL.Config.PostgreSQL.Host,
+		PG2MySQL.Config.PostgreSQL.Port,
+		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
+	)
+	err = pg.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open pg connection: %s", err)
+	}
+	defer pg.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = pg2mysql.NewRepairer(pg, mysql, watcher).Repair(ctx, c.RepairConfig())
+	if err != nil {
+		return fmt.Errorf("failed to repair: %s", err)
+	}
+
+	return nil
+}
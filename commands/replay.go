@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"pg2mysql"
+)
+
+type ReplayCommand struct {
+	DeadLetterDir string `long:"dead-letter-dir" default:"dead-letters" description:"Directory the migrate command recorded failed rows in"`
+}
+
+func (c *ReplayCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	mysql := pg2mysql.NewMySQLDB(
+		PG2MySQL.Config.MySQL.Database,
+		PG2MySQL.Config.MySQL.Username,
+		PG2MySQL.Config.MySQL.Password,
+		PG2MySQL.Config.MySQL.Host,
+		PG2MySQL.Config.MySQL.Port,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
+	)
+
+	err := mysql.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer mysql.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = pg2mysql.NewReplayer(mysql, c.DeadLetterDir, watcher).Replay(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to replay: %s", err)
+	}
+
+	return nil
+}
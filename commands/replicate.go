@@ -0,0 +1,85 @@
+package commands
+
+import (
+	"fmt"
+	"pg2mysql"
+	"time"
+)
+
+type ReplicateCommand struct {
+	Slot      string `long:"slot" default:"pg2mysql" description:"Logical replication slot to read changes from, created with wal2json if it doesn't exist"`
+	StateFile string `long:"state-file" default:"pg2mysql-replication.json" description:"File to record the snapshot and the last change applied in"`
+	DropSlot  bool   `long:"drop-slot" description:"Drop the replication slot and its state file, once MySQL has taken over"`
+
+	Truncate       bool          `long:"truncate" description:"Truncate destination tables before taking the snapshot"`
+	CheckpointFile string        `long:"checkpoint-file" default:"pg2mysql-checkpoint.json" description:"File to record tables of the snapshot finished when interrupted, and to resume from"`
+	Resume         bool          `long:"resume" description:"Skip the tables of the snapshot the checkpoint file records as finished"`
+	PollInterval   time.Duration `long:"poll-interval" default:"1s" description:"How long to wait for changes when there are none"`
+	BatchSize      int           `long:"batch-size" default:"1000" description:"Number of changes to apply in each transaction"`
+
+	ProgressInterval time.Duration `long:"progress-interval" default:"10s" description:"How often to report the lag while there are no changes"`
+}
+
+func (c *ReplicateCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	pg := pg2mysql.NewPostgreSQLDB(
+		PG2MySQL.Config.PostgreSQL.Database,
+		PG2MySQL.Config.PostgreSQL.Username,
+		PG2MySQL.Config.PostgreSQL.Password,
+		PG2MySQL.Config.PostgreSQL.Host,
+		PG2MySQL.Config.PostgreSQL.Port,
+		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
+	)
+	err := pg.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open pg connection: %s", err)
+	}
+	defer pg.Close()
+
+	if c.DropSlot {
+		return pg2mysql.DropReplicationSlot(ctx, pg, c.Slot, c.StateFile)
+	}
+
+	mysql := pg2mysql.NewMySQLDB(
+		PG2MySQL.Config.MySQL.Database,
+		PG2MySQL.Config.MySQL.Username,
+		PG2MySQL.Config.MySQL.Password,
+		PG2MySQL.Config.MySQL.Host,
+		PG2MySQL.Config.MySQL.Port,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
+	)
+
+	err = mysql.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer mysql.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	config := pg2mysql.ReplicationConfig{
+		MigrationConfig: migrationConfig(),
+		SlotName:        c.Slot,
+		StateFile:       c.StateFile,
+		PollInterval:    c.PollInterval,
+		BatchSize:       c.BatchSize,
+	}
+	config.CheckpointFile = c.CheckpointFile
+	config.Resume = c.Resume
+	config.ProgressInterval = c.ProgressInterval
+
+	snapshot := pg2mysql.NewMigrator(pg, mysql, c.Truncate, watcher)
+	err = pg2mysql.NewReplicator(pg, mysql, snapshot, watcher).Replicate(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed replicating: %s", err)
+	}
+
+	return nil
+}
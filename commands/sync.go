@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"fmt"
+	"pg2mysql"
+)
+
+type SyncCommand struct {
+	StateFile     string `long:"state-file" default:"pg2mysql-sync.json" description:"File to keep the watermark of each table in between syncs"`
+	DeadLetterDir string `long:"dead-letter-dir" default:"dead-letters" description:"Directory to record rows that fail to write in, one JSONL file per table"`
+
+	MaxErrors         int64 `long:"max-errors" description:"Stop once more than this many rows fail to write (0 for no limit)"`
+	MaxErrorsPerTable int64 `long:"max-errors-per-table" description:"Stop once more than this many rows of one table fail to write (0 for no limit)"`
+	FailFast          bool  `long:"fail-fast" description:"Stop at the first row that fails to write"`
+}
+
+func (c *SyncCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	syncColumns := map[string]string{}
+	for name, table := range PG2MySQL.Config.Tables {
+		if table.SyncColumn != "" {
+			syncColumns[name] = table.SyncColumn
+		}
+	}
+	if len(syncColumns) == 0 {
+		return fmt.Errorf("no tables to sync; set sync_column for them under tables in the config")
+	}
+
+	mysql := pg2mysql.NewMySQLDB(
+		PG2MySQL.Config.MySQL.Database,
+		PG2MySQL.Config.MySQL.Username,
+		PG2MySQL.Config.MySQL.Password,
+		PG2MySQL.Config.MySQL.Host,
+		PG2MySQL.Config.MySQL.Port,
+		mysqlParams(pg2mysql.MigrationSessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
+	)
+
+	err := mysql.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open mysql connection: %s", err)
+	}
+	defer mysql.Close()
+
+	pg := pg2mysql.NewPostgreSQLDB(
+		PG2MySQL.Config.PostgreSQL.Database,
+		PG2MySQL.Config.PostgreSQL.Username,
+		PG2MySQL.Config.PostgreSQL.Password,
+		PG2MySQL.Config.PostgreSQL.Host,
+		PG2MySQL.Config.PostgreSQL.Port,
+		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
+	)
+	err = pg.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open pg connection: %s", err)
+	}
+	defer pg.Close()
+
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	config := pg2mysql.SyncConfig{
+		MigrationConfig: migrationConfig(),
+		SyncColumns:     syncColumns,
+		StateFile:       c.StateFile,
+	}
+	config.DeadLetterDir = c.DeadLetterDir
+	config.MaxErrors = c.MaxErrors
+	config.MaxErrorsPerTable = c.MaxErrorsPerTable
+	config.FailFast = c.FailFast
+
+	err = pg2mysql.NewSyncer(pg, mysql, watcher).Sync(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed syncing: %s", err)
+	}
+
+	return nil
+}
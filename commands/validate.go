@@ -11,13 +11,17 @@ import (
 type ValidateCommand struct{}
 
 func (c *ValidateCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
 	mysql := pg2mysql.NewMySQLDB(
 		PG2MySQL.Config.MySQL.Database,
 		PG2MySQL.Config.MySQL.Username,
 		PG2MySQL.Config.MySQL.Password,
 		PG2MySQL.Config.MySQL.Host,
 		PG2MySQL.Config.MySQL.Port,
-		PG2MySQL.Config.MySQL.Params,
+		mysqlParams(nil),
+		PG2MySQL.Config.DestinationTimezone,
 	)
 
 	err := mysql.Open()
@@ -33,6 +37,7 @@ func (c *ValidateCommand) Execute([]string) error {
 		PG2MySQL.Config.PostgreSQL.Host,
 		PG2MySQL.Config.PostgreSQL.Port,
 		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
 	)
 	err = pg.Open()
 	if err != nil {
@@ -40,10 +45,7 @@ func (c *ValidateCommand) Execute([]string) error {
 	}
 	defer pg.Close()
 
-	results, err := pg2mysql.NewValidator(pg, mysql).Validate(
-		pg2mysql.MigrationConfig{
-			IgnoreTables: PG2MySQL.Config.PostgreSQL.IgnoredTables,
-		})
+	results, err := pg2mysql.NewValidator(pg, mysql).Validate(ctx, migrationConfig())
 	if err != nil {
 		return fmt.Errorf("failed to validate: %s", err)
 	}
@@ -61,6 +63,72 @@ func (c *ValidateCommand) Execute([]string) error {
 		default:
 			fmt.Printf("%s OK\n", result.TableName)
 		}
+
+		for _, ambiguous := range result.AmbiguousTimestamps {
+			if len(ambiguous.RowIDs) > 0 {
+				fmt.Printf("warning: found %d ambiguous timestamps in %s.%s with IDs %v\n", ambiguous.RowCount, result.TableName, ambiguous.ColumnName, truncateStringArray(ambiguous.RowIDs, 10))
+			} else {
+				fmt.Printf("warning: found %d ambiguous timestamps in %s.%s\n", ambiguous.RowCount, result.TableName, ambiguous.ColumnName)
+			}
+		}
+
+		for _, invalid := range result.InvalidJSON {
+			if len(invalid.RowIDs) > 0 {
+				fmt.Printf("found %d JSON documents MySQL won't accept in %s.%s with IDs %v\n", invalid.RowCount, result.TableName, invalid.ColumnName, truncateStringArray(invalid.RowIDs, 10))
+			} else {
+				fmt.Printf("found %d JSON documents MySQL won't accept in %s.%s\n", invalid.RowCount, result.TableName, invalid.ColumnName)
+			}
+		}
+
+		for _, invalid := range result.InvalidArrays {
+			switch {
+			case len(invalid.RowIDs) > 0:
+				fmt.Printf("found %d arrays in %s.%s %s with IDs %v\n", invalid.RowCount, result.TableName, invalid.ColumnName, invalid.Problem, truncateStringArray(invalid.RowIDs, 10))
+			case invalid.RowCount > 0:
+				fmt.Printf("found %d arrays in %s.%s %s\n", invalid.RowCount, result.TableName, invalid.ColumnName, invalid.Problem)
+			default:
+				fmt.Printf("cannot translate arrays in %s.%s: %s\n", result.TableName, invalid.ColumnName, invalid.Problem)
+			}
+		}
+
+		for _, unconvertible := range result.UnconvertibleValues {
+			if len(unconvertible.RowIDs) > 0 {
+				fmt.Printf("found %d values in %s.%s that can't be converted (%s) with IDs %v\n", unconvertible.RowCount, result.TableName, unconvertible.ColumnName, unconvertible.Problem, truncateStringArray(unconvertible.RowIDs, 10))
+			} else {
+				fmt.Printf("found %d values in %s.%s that can't be converted (%s)\n", unconvertible.RowCount, result.TableName, unconvertible.ColumnName, unconvertible.Problem)
+			}
+		}
+
+		for _, missing := range result.MissingEnumLabels {
+			if len(missing.RowIDs) > 0 {
+				fmt.Printf("found labels %v of %s.%s missing from the MySQL enum, in %d rows with IDs %v\n", missing.Labels, result.TableName, missing.ColumnName, missing.RowCount, truncateStringArray(missing.RowIDs, 10))
+			} else {
+				fmt.Printf("found labels %v of %s.%s missing from the MySQL enum, in %d rows\n", missing.Labels, result.TableName, missing.ColumnName, missing.RowCount)
+			}
+			fmt.Printf("\tto match, define %s.%s as %s\n", result.TableName, missing.ColumnName, missing.Definition)
+		}
+
+		for _, special := range result.SpecialValues {
+			if len(special.RowIDs) > 0 {
+				fmt.Printf("found %d values with %s in %s.%s with IDs %v\n", special.RowCount, special.Problem, result.TableName, special.ColumnName, truncateStringArray(special.RowIDs, 10))
+			} else {
+				fmt.Printf("found %d values with %s in %s.%s\n", special.RowCount, special.Problem, result.TableName, special.ColumnName)
+			}
+			if special.Action != "" {
+				fmt.Printf("\tspecial value policy: %s\n", special.Action)
+			}
+		}
+
+		for _, invalid := range result.InvalidGeometries {
+			switch {
+			case len(invalid.RowIDs) > 0:
+				fmt.Printf("found %d geometries in %s.%s %s with IDs %v\n", invalid.RowCount, result.TableName, invalid.ColumnName, invalid.Problem, truncateStringArray(invalid.RowIDs, 10))
+			case invalid.RowCount > 0:
+				fmt.Printf("found %d geometries in %s.%s %s\n", invalid.RowCount, result.TableName, invalid.ColumnName, invalid.Problem)
+			default:
+				fmt.Printf("cannot migrate geometries in %s.%s: it %s\n", result.TableName, invalid.ColumnName, invalid.Problem)
+			}
+		}
 	}
 
 	return nil
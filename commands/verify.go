@@ -5,16 +5,29 @@ import (
 	"pg2mysql"
 )
 
-type VerifyCommand struct{}
+type VerifyCommand struct {
+	Fix bool `long:"fix" description:"Repair missing and changed rows after verifying"`
+	RepairOptions
+}
 
 func (c *VerifyCommand) Execute([]string) error {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	// --fix writes to MySQL the way repair does
+	var sessionVariables map[string]string
+	if c.Fix {
+		sessionVariables = pg2mysql.MigrationSessionVariables
+	}
+
 	mysql := pg2mysql.NewMySQLDB(
 		PG2MySQL.Config.MySQL.Database,
 		PG2MySQL.Config.MySQL.Username,
 		PG2MySQL.Config.MySQL.Password,
 		PG2MySQL.Config.MySQL.Host,
 		PG2MySQL.Config.MySQL.Port,
-		PG2MySQL.Config.MySQL.Params,
+		mysqlParams(sessionVariables),
+		PG2MySQL.Config.DestinationTimezone,
 	)
 
 	err := mysql.Open()
@@ -30,6 +43,7 @@ func (c *VerifyCommand) Execute([]string) error {
 		PG2MySQL.Config.PostgreSQL.Host,
 		PG2MySQL.Config.PostgreSQL.Port,
 		PG2MySQL.Config.PostgreSQL.SSLMode,
+		PG2MySQL.Config.SourceTimezone,
 	)
 	err = pg.Open()
 	if err != nil {
@@ -37,11 +51,22 @@ func (c *VerifyCommand) Execute([]string) error {
 	}
 	defer pg.Close()
 
-	watcher := pg2mysql.NewStdoutPrinter()
-	err = pg2mysql.NewVerifier(pg, mysql, watcher).Verify()
+	watcher, err := newWatcher()
+	if err != nil {
+		return err
+	}
+
+	err = pg2mysql.NewVerifier(pg, mysql, watcher).Verify(ctx, migrationConfig())
 	if err != nil {
 		return fmt.Errorf("failed to verify: %s", err)
 	}
 
+	if c.Fix {
+		err = pg2mysql.NewRepairer(pg, mysql, watcher).Repair(ctx, c.RepairConfig())
+		if err != nil {
+			return fmt.Errorf("failed to repair: %s", err)
+		}
+	}
+
 	return nil
 }
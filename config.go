@@ -1,6 +1,17 @@
 package pg2mysql
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
 type Config struct {
+	SourceTimezone      string `yaml:"source_timezone"`
+	DestinationTimezone string `yaml:"destination_timezone"`
+
+	Tables map[string]TableConfig `yaml:"tables"`
+
 	MySQL struct {
 		Database string            `yaml:"database"`
 		Username string            `yaml:"username"`
@@ -8,6 +19,17 @@ type Config struct {
 		Host     string            `yaml:"host"`
 		Port     int               `yaml:"port"`
 		Params   map[string]string `yaml:"params" default:"{}"`
+
+		// SessionVariables are set on every connection, e.g.
+		// UNIQUE_CHECKS: "0". Values are SQL expressions, so strings have
+		// to be quoted.
+		SessionVariables map[string]string `yaml:"session_variables"`
+
+		TimestampRounding TimestampRounding `yaml:"timestamp_rounding"`
+
+		// UUIDSwapTimeFields swaps the time fields of uuid columns
+		// stored as BINARY(16), like UUID_TO_BIN(uuid, 1).
+		UUIDSwapTimeFields bool `yaml:"uuid_swap_time_fields"`
 	}
 
 	PostgreSQL struct {
@@ -20,3 +42,38 @@ type Config struct {
 		SSLMode       string   `yaml:"ssl_mode"`
 	} `yaml:"postgresql"`
 }
+
+// TableConfig is the config of a single table, by name under tables.
+type TableConfig struct {
+	// SyncColumn is the column sync uses to find the rows that changed,
+	// such as updated_at.
+	SyncColumn string `yaml:"sync_column"`
+
+	// Arrays are how array columns are translated, by column name.
+	Arrays map[string]ArrayConversion `yaml:"arrays"`
+
+	// SpecialValues are what is done with values MySQL rejects, by column
+	// name.
+	SpecialValues map[string]SpecialValuePolicy `yaml:"special_values"`
+}
+
+// Validate checks the config for settings that contradict each other.
+func (c Config) Validate() error {
+	// Both end up as variables set on every connection, and MySQL's names
+	// are case insensitive, so one would silently win over the other
+	var names []string
+	for name := range c.MySQL.SessionVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for param := range c.MySQL.Params {
+			if strings.EqualFold(name, param) {
+				return fmt.Errorf("mysql session variable '%s' is also set as param '%s'; set it in one place", name, param)
+			}
+		}
+	}
+
+	return nil
+}
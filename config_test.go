@@ -0,0 +1,20 @@
+package pg2mysql_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("Config", func() {
+	It("rejects variables set both as session variables and as params", func() {
+		var config pg2mysql.Config
+		config.MySQL.SessionVariables = map[string]string{"sql_mode": "'TRADITIONAL'", "UNIQUE_CHECKS": "0"}
+		config.MySQL.Params = map[string]string{"tls": "true"}
+		Expect(config.Validate()).To(Succeed())
+
+		config.MySQL.Params["unique_checks"] = "1"
+		Expect(config.Validate()).To(MatchError("mysql session variable 'UNIQUE_CHECKS' is also set as param 'unique_checks'; set it in one place"))
+	})
+})
@@ -0,0 +1,357 @@
+package pg2mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimestampRounding is how MySQL stores a timestamp that is more precise than
+// the fractional seconds of its column.
+type TimestampRounding string
+
+const (
+	// TimestampRoundingAuto asks the destination server how it behaves.
+	TimestampRoundingAuto     TimestampRounding = "auto"
+	TimestampRoundingRound    TimestampRounding = "round"
+	TimestampRoundingTruncate TimestampRounding = "truncate"
+)
+
+// ResolveTimestampRounding returns rounding, or when it is empty or auto, the
+// behavior of the dst server: MariaDB truncates, and so does Oracle MySQL
+// with the TIME_TRUNCATE_FRACTIONAL SQL mode; otherwise MySQL rounds.
+func ResolveTimestampRounding(ctx context.Context, dst DB, rounding TimestampRounding) (TimestampRounding, error) {
+	switch rounding {
+	case TimestampRoundingRound, TimestampRoundingTruncate:
+		return rounding, nil
+	case "", TimestampRoundingAuto:
+	default:
+		return "", fmt.Errorf("unknown timestamp rounding '%s'", rounding)
+	}
+
+	var version, sqlMode string
+	err := dst.DB().QueryRowContext(ctx, "SELECT VERSION(), @@SESSION.sql_mode").Scan(&version, &sqlMode)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect timestamp rounding: %s", err)
+	}
+
+	if strings.Contains(strings.ToLower(version), "mariadb") ||
+		strings.Contains(strings.ToUpper(sqlMode), "TIME_TRUNCATE_FRACTIONAL") {
+		return TimestampRoundingTruncate, nil
+	}
+
+	return TimestampRoundingRound, nil
+}
+
+// ConversionOptions control how values read from PostgreSQL are converted
+// before they are written to, or compared with, MySQL.
+type ConversionOptions struct {
+	TimestampRounding TimestampRounding
+
+	// SourceLocation is the zone of timestamps without time zone in
+	// PostgreSQL, DestinationLocation the zone they are stored in in MySQL.
+	// Both default to UTC.
+	SourceLocation      *time.Location
+	DestinationLocation *time.Location
+
+	// Arrays are how array columns are translated.
+	Arrays ArrayConversions
+
+	// UUIDSwapTimeFields stores uuid columns migrated to BINARY(16) with
+	// their time fields swapped, like UUID_TO_BIN(uuid, 1).
+	UUIDSwapTimeFields bool
+
+	// Converters convert the types they are registered for. Columns of
+	// other types are only converted if they are timestamps.
+	Converters *ConverterRegistry
+
+	// SpecialValues are what is done with the values of each column that
+	// MySQL rejects.
+	SpecialValues SpecialValuePolicies
+
+	// LongLatAxisOrder writes and compares geometries with MySQL 8's
+	// axis-order=long-lat option, without which it takes the coordinates
+	// of geographic SRIDs in latitude-longitude order.
+	LongLatAxisOrder bool
+}
+
+// conversionOptions resolves the options for a run against dst.
+func conversionOptions(ctx context.Context, dst DB, config MigrationConfig) (ConversionOptions, error) {
+	rounding, err := ResolveTimestampRounding(ctx, dst, config.TimestampRounding)
+	if err != nil {
+		return ConversionOptions{}, err
+	}
+
+	srcLocation, err := LoadLocation(config.SourceTimezone)
+	if err != nil {
+		return ConversionOptions{}, fmt.Errorf("invalid source time zone: %s", err)
+	}
+
+	dstLocation, err := LoadLocation(config.DestinationTimezone)
+	if err != nil {
+		return ConversionOptions{}, fmt.Errorf("invalid destination time zone: %s", err)
+	}
+
+	if err := config.Arrays.validate(); err != nil {
+		return ConversionOptions{}, err
+	}
+
+	if err := config.SpecialValues.validate(); err != nil {
+		return ConversionOptions{}, err
+	}
+
+	longLat, err := swapsGeographicAxes(ctx, dst)
+	if err != nil {
+		return ConversionOptions{}, err
+	}
+
+	return ConversionOptions{
+		TimestampRounding:   rounding,
+		SourceLocation:      srcLocation,
+		DestinationLocation: dstLocation,
+		Arrays:              config.Arrays,
+		UUIDSwapTimeFields:  config.UUIDSwapTimeFields,
+		Converters:          config.converters(),
+		SpecialValues:       config.SpecialValues,
+		LongLatAxisOrder:    longLat,
+	}, nil
+}
+
+// LoadLocation is time.LoadLocation, except that an empty name is UTC rather
+// than the local zone of the machine running the migration.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// RowConverter rewrites rows read from PostgreSQL into the values MySQL will
+// store for them, so that the migrator inserts and the verifier looks for
+// the same thing.
+type RowConverter struct {
+	conversions []func(interface{}) interface{}
+
+	// comparisons are the conditions a column of MySQL, formatted in, and
+	// a converted value bound to a placeholder are compared with, and
+	// writePlaceholders the placeholders converted values are written with
+	comparisons       []string
+	writePlaceholders []string
+
+	// reversals turn values read from MySQL back into what PostgreSQL can
+	// compare them with, for the columns that need it
+	reversals map[int]func(interface{}) interface{}
+
+	// checks fail the rows with values that must not be written, for the
+	// columns that have them
+	checks map[int]func(interface{}) error
+}
+
+func NewRowConverter(src, dst *Table, options ConversionOptions) *RowConverter {
+	if options.SourceLocation == nil {
+		options.SourceLocation = time.UTC
+	}
+	if options.DestinationLocation == nil {
+		options.DestinationLocation = time.UTC
+	}
+
+	conversions := make([]func(interface{}) interface{}, len(src.Columns))
+	comparisons := make([]string, len(src.Columns))
+	writePlaceholders := make([]string, len(src.Columns))
+	reversals := map[int]func(interface{}) interface{}{}
+	checks := map[int]func(interface{}) error{}
+	for i, srcColumn := range src.Columns {
+		var dstColumn *Column
+		if dst != nil {
+			_, dstColumn, _ = dst.GetColumn(srcColumn.Name)
+		}
+
+		comparisons[i] = "%s <=> ?"
+		writePlaceholders[i] = "?"
+		if dstColumn != nil && dstColumn.Type == "json" {
+			comparisons[i] = "%s <=> CAST(? AS JSON)"
+		}
+
+		if isGeometryColumn(srcColumn) && dstColumn != nil && isSpatialColumn(dstColumn) {
+			conversions[i] = geometryConversion
+			comparisons[i] = "ST_AsBinary(%s" + geometryOption(options) + ") <=> ?"
+			writePlaceholders[i] = fmt.Sprintf("ST_GeomFromWKB(?, %d%s)", srcColumn.SRID, geometryOption(options))
+			continue
+		}
+
+		if conversion, ok := options.Arrays.lookup(src.Name, srcColumn); ok && conversion.Mode != ArrayModeChildTable {
+			conversions[i] = arrayConversion(srcColumn, conversion)
+			continue
+		}
+
+		if isArrayColumn(srcColumn) {
+			conversions[i] = arrayLiteralConversion
+			continue
+		}
+
+		if isPackedUUIDColumn(srcColumn, dstColumn) {
+			conversions[i] = uuidConversion(options.UUIDSwapTimeFields)
+			reversals[i] = uuidReversal(options.UUIDSwapTimeFields)
+			continue
+		}
+
+		if isJSONColumn(srcColumn) {
+			conversions[i] = jsonConversion(dstColumn != nil && dstColumn.Type == "json")
+			continue
+		}
+
+		if converter, ok := options.Converters.lookupColumns(srcColumn, dstColumn); ok {
+			conversions[i] = typeConversion(converter)
+			continue
+		}
+
+		precision := int64(0)
+		if dstColumn != nil {
+			precision = dstColumn.Precision
+		}
+
+		conversions[i] = timestampConversion(srcColumn.Type, precision, options)
+	}
+
+	for i, srcColumn := range src.Columns {
+		policy, ok := options.SpecialValues.lookup(src.Name, srcColumn)
+		if !ok {
+			continue
+		}
+
+		special, name := specialValueCheck(srcColumn), srcColumn.Name
+		if policy.Action == SpecialValueFail {
+			checks[i] = func(value interface{}) error {
+				if value != nil && special(value) {
+					return fmt.Errorf("value of %s is rejected by MySQL and its special value policy", name)
+				}
+				return nil
+			}
+			continue
+		}
+
+		conversions[i] = specialValueConversion(special, policy, conversions[i])
+	}
+
+	return &RowConverter{
+		conversions:       conversions,
+		comparisons:       comparisons,
+		writePlaceholders: writePlaceholders,
+		reversals:         reversals,
+		checks:            checks,
+	}
+}
+
+// Check returns an error for a converted row with a value of a column whose
+// special values fail the row.
+func (c *RowConverter) Check(values []interface{}) error {
+	for i, check := range c.checks {
+		if err := check(values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Reverse turns a value of column i read from MySQL back into one that
+// compares equal to the value in PostgreSQL it was converted from. Only
+// packed UUIDs are changed.
+func (c *RowConverter) Reverse(i int, value interface{}) interface{} {
+	if reversal, ok := c.reversals[i]; ok {
+		return reversal(value)
+	}
+
+	return value
+}
+
+// Comparison is the condition that column, the MySQL column of column i of a
+// converted row, matches the value bound to its placeholder. JSON columns
+// compare equal to documents with the same content, but only to values that
+// are JSON themselves, and geometries by their WKB.
+func (c *RowConverter) Comparison(i int, column string) string {
+	return fmt.Sprintf(c.comparisons[i], column)
+}
+
+// WritePlaceholder is the placeholder column i of a converted row is written
+// to MySQL with. Geometries are written from their WKB, in the SRID of their
+// PostGIS column. Rows written without a converter are written as they are.
+func (c *RowConverter) WritePlaceholder(i int) string {
+	if c == nil {
+		return "?"
+	}
+	return c.writePlaceholders[i]
+}
+
+// Convert returns the converted values of a row. values is left untouched.
+func (c *RowConverter) Convert(values []interface{}) []interface{} {
+	converted := make([]interface{}, len(values))
+	for i, value := range values {
+		converted[i] = c.conversions[i](value)
+	}
+
+	return converted
+}
+
+// typeConversion converts values with converter, leaving NULLs and values it
+// can't convert as they are.
+func typeConversion(converter TypeConverter) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		if value == nil {
+			return nil
+		}
+
+		converted, err := converter(value)
+		if err != nil {
+			return value
+		}
+		return converted
+	}
+}
+
+// timestampConversion moves timestamps into the destination time zone and
+// reduces them to the fractional seconds a column of the given precision
+// keeps, the same way the server would.
+//
+// The MySQL driver writes times as wall clock times in the destination zone.
+// Values with a time zone are instants and are only moved into that zone.
+// Timestamps without one are wall clock times in the source zone, while
+// dates and times of day are kept as they are.
+func timestampConversion(srcType string, precision int64, options ConversionOptions) func(interface{}) interface{} {
+	if precision < 0 || precision > 9 {
+		precision = 0
+	}
+
+	unit := time.Second
+	for i := int64(0); i < precision; i++ {
+		unit /= 10
+	}
+
+	return func(value interface{}) interface{} {
+		t, ok := value.(time.Time)
+		if !ok {
+			return value
+		}
+
+		switch srcType {
+		case "timestamp with time zone":
+			t = t.In(options.DestinationLocation)
+		case "timestamp without time zone":
+			t = inLocation(t, options.SourceLocation).In(options.DestinationLocation)
+		case "date", "time without time zone":
+			t = inLocation(t, options.DestinationLocation)
+		}
+
+		if options.TimestampRounding == TimestampRoundingRound {
+			return t.Round(unit)
+		}
+		return t.Truncate(unit)
+	}
+}
+
+// inLocation returns the time with the same wall clock as t in loc.
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
@@ -0,0 +1,155 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("RowConverter", func() {
+	var (
+		src, dst *pg2mysql.Table
+		value    time.Time
+	)
+
+	BeforeEach(func() {
+		src = &pg2mysql.Table{
+			Name: "some_table",
+			Columns: []*pg2mysql.Column{
+				{Name: "created_at", Type: "timestamp without time zone", Precision: 6},
+				{Name: "name", Type: "text"},
+			},
+		}
+		dst = &pg2mysql.Table{
+			Name: "some_table",
+			Columns: []*pg2mysql.Column{
+				{Name: "created_at", Type: "datetime"},
+				{Name: "name", Type: "varchar", MaxChars: 255},
+			},
+		}
+		value = time.Date(2017, 3, 24, 12, 30, 15, 987654000, time.UTC)
+	})
+
+	It("rounds timestamps when the server rounds", func() {
+		converted := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{TimestampRounding: pg2mysql.TimestampRoundingRound}).Convert([]interface{}{value, "some-name"})
+		Expect(converted).To(Equal([]interface{}{time.Date(2017, 3, 24, 12, 30, 16, 0, time.UTC), "some-name"}))
+	})
+
+	It("truncates timestamps when the server truncates", func() {
+		converted := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{TimestampRounding: pg2mysql.TimestampRoundingTruncate}).Convert([]interface{}{value, "some-name"})
+		Expect(converted).To(Equal([]interface{}{time.Date(2017, 3, 24, 12, 30, 15, 0, time.UTC), "some-name"}))
+	})
+
+	It("keeps the fractional seconds of the destination column", func() {
+		dst.Columns[0].Precision = 3
+
+		converted := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{TimestampRounding: pg2mysql.TimestampRoundingRound}).Convert([]interface{}{value, nil})
+		Expect(converted).To(Equal([]interface{}{time.Date(2017, 3, 24, 12, 30, 15, 988000000, time.UTC), nil}))
+
+		dst.Columns[0].Precision = 6
+
+		converted = pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{TimestampRounding: pg2mysql.TimestampRoundingTruncate}).Convert([]interface{}{value, nil})
+		Expect(converted).To(Equal([]interface{}{value, nil}))
+	})
+
+	Describe("time zones", func() {
+		var (
+			options pg2mysql.ConversionOptions
+			at      time.Time
+		)
+
+		BeforeEach(func() {
+			srcLocation, err := pg2mysql.LoadLocation("America/New_York")
+			Expect(err).NotTo(HaveOccurred())
+			dstLocation, err := pg2mysql.LoadLocation("Europe/Amsterdam")
+			Expect(err).NotTo(HaveOccurred())
+
+			options = pg2mysql.ConversionOptions{SourceLocation: srcLocation, DestinationLocation: dstLocation}
+			at = time.Date(2017, 3, 24, 12, 30, 15, 0, time.UTC)
+		})
+
+		convert := func(srcType string) time.Time {
+			src.Columns[0].Type = srcType
+			converted := pg2mysql.NewRowConverter(src, dst, options).Convert([]interface{}{at, nil})
+			return converted[0].(time.Time)
+		}
+
+		It("reads timestamps without time zone as wall clock times of the source zone", func() {
+			converted := convert("timestamp without time zone")
+			Expect(converted.Format("2006-01-02 15:04:05")).To(Equal("2017-03-24 17:30:15"))
+			Expect(converted).To(BeTemporally("==", time.Date(2017, 3, 24, 16, 30, 15, 0, time.UTC)))
+		})
+
+		It("moves timestamps with time zone into the destination zone", func() {
+			converted := convert("timestamp with time zone")
+			Expect(converted.Format("2006-01-02 15:04:05")).To(Equal("2017-03-24 13:30:15"))
+			Expect(converted).To(BeTemporally("==", at))
+		})
+
+		It("keeps dates as they are", func() {
+			converted := convert("date")
+			Expect(converted.Format("2006-01-02 15:04:05")).To(Equal("2017-03-24 12:30:15"))
+			Expect(converted.Location()).To(Equal(options.DestinationLocation))
+		})
+
+		It("defaults to UTC", func() {
+			location, err := pg2mysql.LoadLocation("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(location).To(Equal(time.UTC))
+
+			converted := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{}).Convert([]interface{}{at, nil})
+			Expect(converted[0]).To(Equal(at))
+		})
+	})
+})
+
+var _ = Describe("GetAmbiguousTimestamps", func() {
+	var pg pg2mysql.DB
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE alarms (
+				id int PRIMARY KEY,
+				rings_at timestamp without time zone
+			);
+			INSERT INTO alarms VALUES
+				(1, '2017-03-12 01:30:00'),
+				(2, '2017-03-12 02:30:00'),
+				(3, '2017-11-05 01:30:00');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE alarms")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("finds the wall clock times that were skipped or repeated in the zone", func() {
+		table := &pg2mysql.Table{
+			Name: "alarms",
+			Columns: []*pg2mysql.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "rings_at", Type: "timestamp without time zone"},
+			},
+		}
+
+		ambiguous, err := pg2mysql.GetAmbiguousTimestamps(context.Background(), pg, table, "America/New_York")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ambiguous).To(Equal([]pg2mysql.AmbiguousTimestampMetadata{
+			{ColumnName: "rings_at", RowCount: 2, RowIDs: []string{"2", "3"}},
+		}))
+
+		ambiguous, err = pg2mysql.GetAmbiguousTimestamps(context.Background(), pg, table, "UTC")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ambiguous).To(BeEmpty())
+	})
+})
@@ -0,0 +1,528 @@
+package pg2mysql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/bits"
+	"net"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A TypeConverter converts a non-NULL value of a PostgreSQL column, as the
+// driver scans it, into the value written to a MySQL column. Values it can't
+// convert are reported by validate and written as they are.
+type TypeConverter func(value interface{}) (interface{}, error)
+
+type converterKey struct {
+	srcType, dstType string
+}
+
+// ConverterRegistry holds the TypeConverters for pairs of PostgreSQL and
+// MySQL types, as information_schema names them, e.g. "inet" and
+// "varbinary". The migrator, validator and verifier all convert with it.
+type ConverterRegistry struct {
+	converters map[converterKey]TypeConverter
+}
+
+// AnyType registers a converter for every destination type without one of
+// its own.
+const AnyType = "*"
+
+func NewConverterRegistry() *ConverterRegistry {
+	return &ConverterRegistry{
+		converters: map[converterKey]TypeConverter{},
+	}
+}
+
+// Register registers converter for values of srcType written to dstType,
+// replacing any converter already registered for them.
+func (r *ConverterRegistry) Register(srcType, dstType string, converter TypeConverter) {
+	r.converters[converterKey{srcType, dstType}] = converter
+}
+
+// Lookup returns the converter for srcType and dstType, or for srcType and
+// AnyType.
+func (r *ConverterRegistry) Lookup(srcType, dstType string) (TypeConverter, bool) {
+	if converter, ok := r.converters[converterKey{srcType, dstType}]; ok {
+		return converter, true
+	}
+
+	converter, ok := r.converters[converterKey{srcType, AnyType}]
+	return converter, ok
+}
+
+// lookupColumns returns the converter for values of src written to dst.
+func (r *ConverterRegistry) lookupColumns(src, dst *Column) (TypeConverter, bool) {
+	if r == nil || dst == nil {
+		return nil, false
+	}
+
+	return r.Lookup(src.Type, dst.Type)
+}
+
+var (
+	mysqlBinaryTypes = []string{"binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob"}
+	mysqlTextTypes   = []string{"char", "varchar", "tinytext", "text", "mediumtext", "longtext"}
+	mysqlIntTypes    = []string{"tinyint", "smallint", "mediumint", "int", "bigint"}
+	mysqlFloatTypes  = []string{"decimal", "float", "double"}
+)
+
+// DefaultConverters returns a registry with the built-in converters:
+//
+//   - bytea to binary types as bytes, and to text types in PostgreSQL's hex
+//     format, e.g. \x0102
+//   - inet and cidr to binary types packed like INET6_ATON, 4 bytes for IPv4
+//     and 16 for IPv6, without the prefix length; and to text types as text
+//   - interval to integer and decimal types as seconds, to time as hours,
+//     minutes and seconds, and to text types as text; months are 30 days and
+//     years 365.25, as in EXTRACT(EPOCH FROM interval)
+//   - money to decimal types as a number, parsed from the text formatted for
+//     the lc_monetary locale
+//   - boolean to integer and bit types as 1 or 0
+//   - bit and bit varying to bit and integer types as a number, to binary
+//     types as big-endian bytes, and to text types as the string of bits
+func DefaultConverters() *ConverterRegistry {
+	r := NewConverterRegistry()
+
+	for _, t := range mysqlBinaryTypes {
+		r.Register("bytea", t, byteaToBinary)
+		r.Register("inet", t, inetToBinary)
+		r.Register("cidr", t, inetToBinary)
+		r.Register("bit", t, bitsToBinary)
+		r.Register("bit varying", t, bitsToBinary)
+	}
+	for _, t := range mysqlTextTypes {
+		r.Register("bytea", t, byteaToText)
+		r.Register("inet", t, toText)
+		r.Register("cidr", t, toText)
+		r.Register("interval", t, toText)
+		r.Register("money", t, toText)
+		r.Register("bit", t, toText)
+		r.Register("bit varying", t, toText)
+	}
+	for _, t := range append([]string{"bit"}, mysqlIntTypes...) {
+		r.Register("boolean", t, booleanToInt)
+		r.Register("bit", t, bitsToInt)
+		r.Register("bit varying", t, bitsToInt)
+	}
+	for _, t := range mysqlIntTypes {
+		r.Register("interval", t, intervalToSeconds(true))
+	}
+	for _, t := range mysqlFloatTypes {
+		r.Register("interval", t, intervalToSeconds(false))
+		r.Register("money", t, moneyToDecimal)
+	}
+	r.Register("interval", "time", intervalToTime)
+
+	return r
+}
+
+// textValue is the text of a value the driver scans as bytes.
+func textValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case []byte:
+		return string(v), nil
+	case string:
+		return v, nil
+	default:
+		return "", fmt.Errorf("unexpected %T", value)
+	}
+}
+
+func toText(value interface{}) (interface{}, error) {
+	return textValue(value)
+}
+
+// byteaToBinary keeps the bytes the driver decodes bytea into, and decodes
+// the hex format values are rendered in as text.
+func byteaToBinary(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		if !strings.HasPrefix(v, `\x`) {
+			return nil, fmt.Errorf("bytea not in hex format")
+		}
+		return hex.DecodeString(v[2:])
+	default:
+		return nil, fmt.Errorf("unexpected %T", value)
+	}
+}
+
+func byteaToText(value interface{}) (interface{}, error) {
+	b, err := byteaToBinary(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return `\x` + hex.EncodeToString(b.([]byte)), nil
+}
+
+func inetToBinary(value interface{}) (interface{}, error) {
+	s, err := textValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		s = s[:i]
+	}
+
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address '%s'", s)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil && !strings.Contains(s, ":") {
+		return []byte(ip4), nil
+	}
+	return []byte(ip.To16()), nil
+}
+
+// booleanToInt writes a boolean as the 1 or 0 that MySQL stores in
+// TINYINT(1) and BIT(1), so that <=> compares it with either.
+func booleanToInt(value interface{}) (interface{}, error) {
+	var b bool
+	switch v := value.(type) {
+	case bool:
+		b = v
+	case []byte, string:
+		s, _ := textValue(v)
+		switch s {
+		case "t", "true":
+			b = true
+		case "f", "false":
+		default:
+			return nil, fmt.Errorf("invalid boolean '%s'", s)
+		}
+	default:
+		return nil, fmt.Errorf("unexpected %T", value)
+	}
+
+	if b {
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+// parseBits parses a bit string, e.g. "0101", which MySQL otherwise reads as
+// the characters '0' and '1' rather than as bits.
+func parseBits(value interface{}) (string, error) {
+	s, err := textValue(value)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.Trim(s, "01") != "" {
+		return "", fmt.Errorf("invalid bit string '%s'", s)
+	}
+
+	return s, nil
+}
+
+func bitsToInt(value interface{}) (interface{}, error) {
+	s, err := parseBits(value)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimLeft(s, "0")
+	if len(trimmed) > 64 {
+		return nil, fmt.Errorf("bit string of %d bits is wider than 64", len(trimmed))
+	}
+	if trimmed == "" {
+		return uint64(0), nil
+	}
+
+	return strconv.ParseUint(trimmed, 2, 64)
+}
+
+// bitsToBinary packs a bit string into bytes, the first of them padded with
+// leading zeros, e.g. "100000001" into 0x01 0x01.
+func bitsToBinary(value interface{}) (interface{}, error) {
+	s, err := parseBits(value)
+	if err != nil {
+		return nil, err
+	}
+
+	b := make([]byte, (len(s)+7)/8)
+	for i, j := len(s)-1, len(b)*8-1; i >= 0; i, j = i-1, j-1 {
+		if s[i] == '1' {
+			b[j/8] |= 1 << uint(7-j%8)
+		}
+	}
+
+	return b, nil
+}
+
+// parseInterval returns the seconds of an interval in PostgreSQL's default
+// output format, e.g. "1 year 2 mons -3 days +04:05:06.5".
+func parseInterval(s string) (float64, error) {
+	var seconds float64
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+
+		if strings.Contains(field, ":") {
+			sign := 1.0
+			if strings.HasPrefix(field, "-") {
+				sign = -1
+			}
+			parts := strings.Split(strings.TrimLeft(field, "+-"), ":")
+			if len(parts) != 3 {
+				return 0, fmt.Errorf("invalid interval '%s'", s)
+			}
+
+			var hms float64
+			for _, part := range parts {
+				n, err := strconv.ParseFloat(part, 64)
+				if err != nil {
+					return 0, fmt.Errorf("invalid interval '%s'", s)
+				}
+				hms = hms*60 + n
+			}
+			seconds += sign * hms
+			continue
+		}
+
+		n, err := strconv.ParseFloat(field, 64)
+		if err != nil || i+1 == len(fields) {
+			return 0, fmt.Errorf("invalid interval '%s'", s)
+		}
+		i++
+
+		switch strings.TrimSuffix(fields[i], "s") {
+		case "year":
+			seconds += n * 365.25 * 86400
+		case "mon":
+			seconds += n * 30 * 86400
+		case "day":
+			seconds += n * 86400
+		default:
+			return 0, fmt.Errorf("invalid interval '%s'", s)
+		}
+	}
+
+	return seconds, nil
+}
+
+func intervalToSeconds(integer bool) TypeConverter {
+	return func(value interface{}) (interface{}, error) {
+		s, err := textValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		seconds, err := parseInterval(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if integer {
+			if seconds != math.Trunc(seconds) {
+				return nil, fmt.Errorf("interval '%s' has fractional seconds", s)
+			}
+			return int64(seconds), nil
+		}
+		return strconv.FormatFloat(seconds, 'f', -1, 64), nil
+	}
+}
+
+// intervalToTime formats an interval as a MySQL time, which ranges from
+// -838:59:59 to 838:59:59.
+func intervalToTime(value interface{}) (interface{}, error) {
+	s, err := textValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds, err := parseInterval(s)
+	if err != nil {
+		return nil, err
+	}
+
+	sign := ""
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	if seconds >= 839*3600 {
+		return nil, fmt.Errorf("interval '%s' is out of the range of time", s)
+	}
+
+	micros := int64(math.Round(seconds * 1e6))
+	whole := micros / 1e6
+	return fmt.Sprintf("%s%d:%02d:%02d.%06d", sign, whole/3600, whole/60%60, whole%60, micros%1e6), nil
+}
+
+// moneyToDecimal parses money formatted for a locale, e.g. "-$1,234.56",
+// "($1,234.56)" or "1.234,56 €". The decimal separator is the last '.' or
+// ',' unless it is followed by three digits, in which case it groups
+// thousands.
+func moneyToDecimal(value interface{}) (interface{}, error) {
+	s, err := textValue(value)
+	if err != nil {
+		return nil, err
+	}
+
+	negative := strings.Contains(s, "-") || strings.Contains(s, "(")
+
+	decimal := strings.LastIndexAny(s, ".,")
+	if decimal >= 0 {
+		digits := 0
+		for _, r := range s[decimal+1:] {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits == 3 {
+			decimal = -1
+		}
+	}
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case i == decimal:
+			b.WriteByte('.')
+		}
+	}
+
+	number := b.String()
+	if _, err := strconv.ParseFloat(number, 64); err != nil {
+		return nil, fmt.Errorf("invalid money '%s'", s)
+	}
+
+	return number, nil
+}
+
+type UnconvertibleValueMetadata struct {
+	ColumnName string
+	RowCount   int64
+	RowIDs     []string
+
+	// Problem is why the first value couldn't be converted.
+	Problem string
+}
+
+// GetUnconvertibleValues finds the values of the columns of src with a
+// converter in converters that it can't convert, or that don't fit in the
+// column of dst once converted.
+func GetUnconvertibleValues(ctx context.Context, db DB, src, dst *Table, converters *ConverterRegistry) ([]UnconvertibleValueMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	columnsForSelect := make([]string, len(primaryKey)+1)
+	for i := range primaryKey {
+		columnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	var unconvertible []UnconvertibleValueMetadata
+	for _, column := range src.Columns {
+		_, dstColumn, _ := dst.GetColumn(column.Name)
+		converter, ok := converters.lookupColumns(column, dstColumn)
+		if !ok {
+			continue
+		}
+
+		columnsForSelect[len(primaryKey)] = fmt.Sprintf("\"%s\"", column.Name)
+		stmt := fmt.Sprintf(
+			"SELECT %s FROM \"%s\" WHERE \"%s\" IS NOT NULL",
+			strings.Join(columnsForSelect, ","),
+			src.Name,
+			column.Name,
+		)
+
+		rows, err := db.DB().QueryContext(ctx, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting values to convert: %s", err)
+		}
+
+		values := make([]interface{}, len(columnsForSelect))
+		scanArgs := make([]interface{}, len(columnsForSelect))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		metadata := UnconvertibleValueMetadata{
+			ColumnName: column.Name,
+		}
+		for rows.Next() {
+			if err := rows.Scan(scanArgs...); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan row: %s", err)
+			}
+
+			converted, err := converter(values[len(primaryKey)])
+			if err == nil {
+				err = checkConvertedLength(converted, dstColumn)
+			}
+			if err == nil {
+				continue
+			}
+
+			if metadata.RowCount == 0 {
+				metadata.Problem = err.Error()
+			}
+			metadata.RowCount++
+			if len(primaryKey) > 0 {
+				metadata.RowIDs = append(metadata.RowIDs, FormatRowID(values[:len(primaryKey)]))
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+
+		if metadata.RowCount > 0 {
+			unconvertible = append(unconvertible, metadata)
+		}
+	}
+
+	return unconvertible, nil
+}
+
+// checkConvertedLength checks that a converted string or bytes fits in the
+// characters or bytes of dst, and a number in the bits of a bit column.
+func checkConvertedLength(converted interface{}, dst *Column) error {
+	if dst.MaxChars == 0 {
+		return nil
+	}
+
+	var length int
+	switch v := converted.(type) {
+	case string:
+		length = utf8.RuneCountInString(v)
+	case []byte:
+		length = len(v)
+	case uint64:
+		if dst.Type != "bit" {
+			return nil
+		}
+		length = bits.Len64(v)
+	default:
+		return nil
+	}
+
+	if int64(length) > dst.MaxChars {
+		return fmt.Errorf("%d long once converted, more than the %d of %s", length, dst.MaxChars, dst.Name)
+	}
+
+	return nil
+}
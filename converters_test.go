@@ -0,0 +1,93 @@
+package pg2mysql_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("DefaultConverters", func() {
+	convert := func(srcType, dstType string, value interface{}) (interface{}, error) {
+		converter, ok := pg2mysql.DefaultConverters().Lookup(srcType, dstType)
+		Expect(ok).To(BeTrue())
+		return converter(value)
+	}
+
+	It("writes bytea as bytes or as hex text", func() {
+		Expect(convert("bytea", "varbinary", []byte{1, 2})).To(Equal([]byte{1, 2}))
+		Expect(convert("bytea", "varbinary", `\x0102`)).To(Equal([]byte{1, 2}))
+		Expect(convert("bytea", "text", []byte{1, 255})).To(Equal(`\x01ff`))
+	})
+
+	It("packs addresses like INET6_ATON", func() {
+		Expect(convert("inet", "varbinary", []byte("192.168.0.1"))).To(Equal([]byte{192, 168, 0, 1}))
+		Expect(convert("cidr", "varbinary", []byte("10.0.0.0/8"))).To(Equal([]byte{10, 0, 0, 0}))
+		Expect(convert("inet", "varbinary", []byte("::1"))).To(Equal([]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}))
+		Expect(convert("inet", "varchar", []byte("::1"))).To(Equal("::1"))
+
+		_, err := convert("inet", "varbinary", []byte("nope"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("converts intervals to seconds and times", func() {
+		Expect(convert("interval", "bigint", []byte("1 day -01:00:00"))).To(Equal(int64(82800)))
+		Expect(convert("interval", "decimal", []byte("1 mon 00:00:01.5"))).To(Equal("2592001.5"))
+		Expect(convert("interval", "time", []byte("1 day 02:03:04.5"))).To(Equal("26:03:04.500000"))
+		Expect(convert("interval", "time", []byte("-00:00:01"))).To(Equal("-0:00:01.000000"))
+
+		_, err := convert("interval", "int", []byte("00:00:00.5"))
+		Expect(err).To(HaveOccurred())
+
+		_, err = convert("interval", "time", []byte("35 days"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("parses money in the formats of different locales", func() {
+		Expect(convert("money", "decimal", []byte("$1,234.56"))).To(Equal("1234.56"))
+		Expect(convert("money", "decimal", []byte("-$1,234.56"))).To(Equal("-1234.56"))
+		Expect(convert("money", "decimal", []byte("($0.50)"))).To(Equal("-0.50"))
+		Expect(convert("money", "decimal", []byte("1.234,56 €"))).To(Equal("1234.56"))
+		Expect(convert("money", "decimal", []byte("￥1,234"))).To(Equal("1234"))
+	})
+
+	It("writes booleans as 1 or 0", func() {
+		Expect(convert("boolean", "tinyint", true)).To(Equal(int64(1)))
+		Expect(convert("boolean", "bit", false)).To(Equal(int64(0)))
+		Expect(convert("boolean", "tinyint", []byte("t"))).To(Equal(int64(1)))
+
+		_, err := convert("boolean", "tinyint", []byte("yes"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("writes bit strings as numbers, bytes or text", func() {
+		Expect(convert("bit varying", "bit", []byte("0101"))).To(Equal(uint64(5)))
+		Expect(convert("bit", "bigint", []byte("00000000"))).To(Equal(uint64(0)))
+		Expect(convert("bit", "varbinary", []byte("100000001"))).To(Equal([]byte{1, 1}))
+		Expect(convert("bit varying", "varchar", []byte("0101"))).To(Equal("0101"))
+
+		_, err := convert("bit varying", "bit", []byte("1"+strings.Repeat("0", 64)))
+		Expect(err).To(HaveOccurred())
+
+		_, err = convert("bit", "bit", []byte("012"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("lets converters be registered and replaced", func() {
+		registry := pg2mysql.NewConverterRegistry()
+		registry.Register("point", pg2mysql.AnyType, func(value interface{}) (interface{}, error) {
+			return "any", nil
+		})
+		registry.Register("point", "varchar", func(value interface{}) (interface{}, error) {
+			return "varchar", nil
+		})
+
+		src := &pg2mysql.Table{Name: "t", Columns: []*pg2mysql.Column{{Name: "a", Type: "point"}, {Name: "b", Type: "point"}}}
+		dst := &pg2mysql.Table{Name: "t", Columns: []*pg2mysql.Column{{Name: "a", Type: "varchar"}, {Name: "b", Type: "text"}}}
+
+		converter := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{Converters: registry})
+		Expect(converter.Convert([]interface{}{[]byte("(1,2)"), nil})).To(Equal([]interface{}{"varchar", nil}))
+	})
+})
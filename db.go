@@ -1,9 +1,10 @@
 package pg2mysql
 
 import (
+	"context"
+	"crypto/sha1"
 	"database/sql"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 )
@@ -11,11 +12,13 @@ import (
 type DB interface {
 	Open() error
 	Close() error
-	GetSchemaRows() (*sql.Rows, error)
-	GetPrimaryKey(tableName string) (string, error)
-	HasPrimaryKey(tableName string) (bool, error)
-	DisableConstraints() error
-	EnableConstraints() error
+	GetSchemaRows(ctx context.Context) (*sql.Rows, error)
+	GetForeignKeyRows(ctx context.Context) (*sql.Rows, error)
+	GetPrimaryKey(ctx context.Context, tableName string) ([]string, error)
+	HasPrimaryKey(ctx context.Context, tableName string) (bool, error)
+	EstimateRowCount(ctx context.Context, tableName string) (int64, error)
+	DisableConstraints(ctx context.Context) error
+	EnableConstraints(ctx context.Context) error
 	ColumnNameForSelect(columnName string) string
 	DB() *sql.DB
 }
@@ -53,9 +56,18 @@ func (t *Table) GetColumn(name string) (int, *Column, error) {
 }
 
 type Column struct {
-	Name     string
-	Type     string
-	MaxChars int64
+	Name      string
+	Type      string
+	MaxChars  int64
+	Precision int64
+
+	// Labels are the labels of an enum column, in order.
+	Labels []string
+
+	// GeometryType and SRID are those of a PostGIS column, GeometryType
+	// empty if it may hold any geometry.
+	GeometryType string
+	SRID         int64
 }
 
 func (c *Column) Compatible(other *Column) bool {
@@ -74,8 +86,8 @@ func (c *Column) Incompatible(other *Column) bool {
 	return !c.Compatible(other)
 }
 
-func BuildSchema(db DB) (*Schema, error) {
-	rows, err := db.GetSchemaRows()
+func BuildSchema(ctx context.Context, db DB) (*Schema, error) {
+	rows, err := db.GetSchemaRows(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -83,21 +95,40 @@ func BuildSchema(db DB) (*Schema, error) {
 	data := map[string][]*Column{}
 	for rows.Next() {
 		var (
-			table    sql.NullString
-			column   sql.NullString
-			datatype sql.NullString
-			maxChars sql.NullInt64
+			table      sql.NullString
+			column     sql.NullString
+			datatype   sql.NullString
+			maxChars   sql.NullInt64
+			precision  sql.NullInt64
+			definition sql.NullString
 		)
 
-		if err := rows.Scan(&table, &column, &datatype, &maxChars); err != nil {
+		if err := rows.Scan(&table, &column, &datatype, &maxChars, &precision, &definition); err != nil {
 			return nil, err
 		}
 
-		data[table.String] = append(data[table.String], &Column{
-			Name:     column.String,
-			Type:     datatype.String,
-			MaxChars: maxChars.Int64,
-		})
+		c := &Column{
+			Name:      column.String,
+			Type:      datatype.String,
+			MaxChars:  maxChars.Int64,
+			Precision: precision.Int64,
+		}
+
+		switch {
+		case !definition.Valid:
+		case isGeometryColumn(c):
+			var err error
+			if c.GeometryType, c.SRID, err = ParseGeometryType(definition.String); err != nil {
+				return nil, fmt.Errorf("failed to parse geometry type of %s.%s: %s", table.String, column.String, err)
+			}
+		default:
+			var err error
+			if c.Labels, err = ParseEnumLabels(definition.String); err != nil {
+				return nil, fmt.Errorf("failed to parse labels of %s.%s: %s", table.String, column.String, err)
+			}
+		}
+
+		data[table.String] = append(data[table.String], c)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -130,6 +161,11 @@ func GetIncompatibleColumns(src, dst *Table) ([]*Column, error) {
 			return nil, fmt.Errorf("failed to find column '%s/%s' in source schema: %s", dst.Name, dstColumn.Name, err)
 		}
 
+		// Packed UUIDs always fit
+		if isPackedUUIDColumn(srcColumn, dstColumn) {
+			continue
+		}
+
 		if dstColumn.Incompatible(srcColumn) {
 			incompatibleColumns = append(incompatibleColumns, dstColumn)
 		}
@@ -138,17 +174,51 @@ func GetIncompatibleColumns(src, dst *Table) ([]*Column, error) {
 	return incompatibleColumns, nil
 }
 
+// withoutColumns returns src and dst without the columns of src that skip
+// is true for, which are checked some other way.
+func withoutColumns(src, dst *Table, skip func(*Column) bool) (*Table, *Table) {
+	skipped := map[string]bool{}
+	for _, column := range src.Columns {
+		if skip(column) {
+			skipped[column.Name] = true
+		}
+	}
+
+	if len(skipped) == 0 {
+		return src, dst
+	}
+
+	without := func(table *Table) *Table {
+		t := &Table{Name: table.Name}
+		for _, column := range table.Columns {
+			if !skipped[column.Name] {
+				t.Columns = append(t.Columns, column)
+			}
+		}
+		return t
+	}
+
+	return without(src), without(dst)
+}
+
 type IncompatibleColumnMetadata struct {
 	ColumnName string
 	MaxChars   int64
 }
 
-// Returns incompatible rows ids and incompatible column names
-func GetIncompatibleRowIDsAndColumns(db DB, src, dst *Table) ([]string, []IncompatibleColumnMetadata, error) {
-	primaryKey, err := db.GetPrimaryKey(dst.Name)
+// Returns incompatible rows ids and incompatible column names. Tables without
+// a primary key have no row IDs, so only their columns are returned.
+func GetIncompatibleRowIDsAndColumns(ctx context.Context, db DB, src, dst *Table) ([]string, []IncompatibleColumnMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	if len(primaryKey) == 0 {
+		_, columns, err := GetIncompatibleRowCount(ctx, db, src, dst)
+		return nil, columns, err
+	}
+
 	columns, err := GetIncompatibleColumns(src, dst)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed getting incompatible columns: %s", err)
@@ -158,26 +228,36 @@ func GetIncompatibleRowIDsAndColumns(db DB, src, dst *Table) ([]string, []Incomp
 		return nil, nil, nil
 	}
 
+	keyColumnsForSelect := make([]string, len(primaryKey))
+	for i := range primaryKey {
+		keyColumnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
 	var rowIDs []string
 	var columnNamesAndMax []IncompatibleColumnMetadata
 	for _, column := range columns {
 		// Casting to handle special datatypes like enums
 		limit := fmt.Sprintf("LENGTH(\"%s\"::text) > %d", column.Name, column.MaxChars)
-		stmt := fmt.Sprintf("SELECT \"%s\" FROM \"%s\" WHERE %s", primaryKey, src.Name, limit)
+		stmt := fmt.Sprintf("SELECT %s FROM \"%s\" WHERE %s", strings.Join(keyColumnsForSelect, ","), src.Name, limit)
 
-		rows, err := db.DB().Query(stmt)
+		rows, err := db.DB().QueryContext(ctx, stmt)
 		if err != nil {
 			return nil, nil, fmt.Errorf("failed getting incompatible row ids: %s", err)
 		}
 
+		keyValues := make([]interface{}, len(primaryKey))
+		keyScanArgs := make([]interface{}, len(primaryKey))
+		for i := range keyValues {
+			keyScanArgs[i] = &keyValues[i]
+		}
+
 		rowCount := 0
 		for rows.Next() {
-			var id string
-			if err := rows.Scan(&id); err != nil {
+			if err := rows.Scan(keyScanArgs...); err != nil {
 				return nil, nil, fmt.Errorf("failed to scan row: %s", err)
 			}
 
-			rowIDs = append(rowIDs, id)
+			rowIDs = append(rowIDs, FormatRowID(keyValues))
 			rowCount++
 		}
 
@@ -187,7 +267,7 @@ func GetIncompatibleRowIDsAndColumns(db DB, src, dst *Table) ([]string, []Incomp
 
 			// Execute the SQL statement and get the result
 			var maxChars int
-			err := db.DB().QueryRow(maxStmt).Scan(&maxChars)
+			err := db.DB().QueryRowContext(ctx, maxStmt).Scan(&maxChars)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed getting max chars: %s", err)
 			}
@@ -211,7 +291,89 @@ func GetIncompatibleRowIDsAndColumns(db DB, src, dst *Table) ([]string, []Incomp
 	return rowIDs, columnNamesAndMax, nil
 }
 
-func GetIncompatibleRowCount(db DB, src, dst *Table) (int64, []IncompatibleColumnMetadata, error) {
+type AmbiguousTimestampMetadata struct {
+	ColumnName string
+	RowCount   int64
+	RowIDs     []string
+}
+
+// GetAmbiguousTimestamps finds the values of timestamp without time zone
+// columns of table that are ambiguous or don't exist as wall clock times in
+// the given zone, because they fall in a daylight saving time transition.
+func GetAmbiguousTimestamps(ctx context.Context, db DB, table *Table, timezone string) ([]AmbiguousTimestampMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	keyColumnsForSelect := make([]string, len(primaryKey))
+	for i := range primaryKey {
+		keyColumnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	var ambiguous []AmbiguousTimestampMetadata
+	for _, column := range table.Columns {
+		if column.Type != "timestamp without time zone" {
+			continue
+		}
+
+		// A wall clock time that doesn't survive a round trip through the
+		// zone was skipped; one an hour away from another instant with the
+		// same wall clock time was repeated
+		condition := fmt.Sprintf(`(
+			("%[1]s" AT TIME ZONE $1) AT TIME ZONE $1 <> "%[1]s"
+			OR (("%[1]s" AT TIME ZONE $1) + interval '1 hour') AT TIME ZONE $1 = "%[1]s"
+			OR (("%[1]s" AT TIME ZONE $1) - interval '1 hour') AT TIME ZONE $1 = "%[1]s"
+		)`, column.Name)
+
+		metadata := AmbiguousTimestampMetadata{
+			ColumnName: column.Name,
+		}
+
+		if len(primaryKey) == 0 {
+			stmt := fmt.Sprintf("SELECT count(1) FROM \"%s\" WHERE %s", table.Name, condition)
+			if err := db.DB().QueryRowContext(ctx, stmt, timezone).Scan(&metadata.RowCount); err != nil {
+				return nil, fmt.Errorf("failed counting ambiguous timestamps: %s", err)
+			}
+		} else {
+			stmt := fmt.Sprintf("SELECT %s FROM \"%s\" WHERE %s", strings.Join(keyColumnsForSelect, ","), table.Name, condition)
+			rows, err := db.DB().QueryContext(ctx, stmt, timezone)
+			if err != nil {
+				return nil, fmt.Errorf("failed getting ambiguous timestamps: %s", err)
+			}
+
+			keyValues := make([]interface{}, len(primaryKey))
+			keyScanArgs := make([]interface{}, len(primaryKey))
+			for i := range keyValues {
+				keyScanArgs[i] = &keyValues[i]
+			}
+
+			for rows.Next() {
+				if err := rows.Scan(keyScanArgs...); err != nil {
+					return nil, fmt.Errorf("failed to scan row: %s", err)
+				}
+				metadata.RowIDs = append(metadata.RowIDs, FormatRowID(keyValues))
+				metadata.RowCount++
+			}
+
+			if err := rows.Err(); err != nil {
+				return nil, err
+			}
+
+			if err := rows.Close(); err != nil {
+				return nil, err
+			}
+		}
+
+		if metadata.RowCount > 0 {
+			ambiguous = append(ambiguous, metadata)
+		}
+	}
+
+	return ambiguous, nil
+}
+
+func GetIncompatibleRowCount(ctx context.Context, db DB, src, dst *Table) (int64, []IncompatibleColumnMetadata, error) {
 	columns, err := GetIncompatibleColumns(src, dst)
 	if err != nil {
 		return 0, nil, fmt.Errorf("failed getting incompatible columns: %s", err)
@@ -228,7 +390,7 @@ func GetIncompatibleRowCount(db DB, src, dst *Table) (int64, []IncompatibleColum
 		stmt := fmt.Sprintf("SELECT count(1) FROM \"%s\" WHERE %s", src.Name, limit)
 
 		var currCount int64
-		err = db.DB().QueryRow(stmt).Scan(&currCount)
+		err = db.DB().QueryRowContext(ctx, stmt).Scan(&currCount)
 		if err != nil {
 			return 0, nil, err
 		}
@@ -239,7 +401,7 @@ func GetIncompatibleRowCount(db DB, src, dst *Table) (int64, []IncompatibleColum
 
 			// Execute the SQL statement and get the result
 			var maxChars int
-			err := db.DB().QueryRow(maxStmt).Scan(&maxChars)
+			err := db.DB().QueryRowContext(ctx, maxStmt).Scan(&maxChars)
 			if err != nil {
 				return 0, nil, fmt.Errorf("failed getting max chars: %s", err)
 			}
@@ -254,29 +416,79 @@ func GetIncompatibleRowCount(db DB, src, dst *Table) (int64, []IncompatibleColum
 	return count, columnNamesAndMax, nil
 }
 
-func EachMissingRow(src, dst DB, table *Table, f func([]interface{})) error {
+// FormatRowID formats the primary key values of a row for display. Single
+// column keys are printed as-is, composite keys as a parenthesized tuple.
+func FormatRowID(keyValues []interface{}) string {
+	formatted := make([]string, len(keyValues))
+	for i, value := range keyValues {
+		formatted[i] = formatValue(value)
+	}
+
+	if len(formatted) == 1 {
+		return formatted[0]
+	}
+
+	return "(" + strings.Join(formatted, ",") + ")"
+}
+
+// FormatRowDigest identifies a row of a table without a primary key by a
+// digest of all of its values.
+func FormatRowDigest(values []interface{}) string {
+	h := sha1.New()
+	for _, value := range values {
+		if value == nil {
+			h.Write([]byte{0})
+		} else {
+			h.Write([]byte{1})
+			h.Write([]byte(formatValue(value)))
+		}
+		h.Write([]byte{0xff})
+	}
+
+	return fmt.Sprintf("sha1:%x", h.Sum(nil))
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// EachMissingRow calls f with the converted values of every row of table in
+// src that has no exact match in dst. An error returned by f stops the
+// iteration and is returned as-is.
+func EachMissingRow(ctx context.Context, src, dst DB, table *Table, converter *RowConverter, f func([]interface{}) error) error {
 	srcColumnNamesForSelect := make([]string, len(table.Columns))
 	values := make([]interface{}, len(table.Columns))
 	scanArgs := make([]interface{}, len(table.Columns))
 	colVals := make([]string, len(table.Columns))
 	for i := range table.Columns {
-		srcColumnNamesForSelect[i] = src.ColumnNameForSelect(table.Columns[i].Name)
+		srcColumnNamesForSelect[i] = selectExpression(src.ColumnNameForSelect(table.Columns[i].Name), table.Columns[i])
 		scanArgs[i] = &values[i]
-		colVals[i] = fmt.Sprintf("%s <=> ?", dst.ColumnNameForSelect(table.Columns[i].Name))
+		colVals[i] = converter.Comparison(i, dst.ColumnNameForSelect(table.Columns[i].Name))
 	}
 
 	// select all rows in src
 	stmt := fmt.Sprintf("SELECT %s FROM \"%s\"", strings.Join(srcColumnNamesForSelect, ","), table.Name)
-	rows, err := src.DB().Query(stmt)
+	rows, err := src.DB().QueryContext(ctx, stmt)
 	if err != nil {
 		return fmt.Errorf("failed to select rows: %s", err)
 	}
+	defer rows.Close()
 
 	stmt = fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM `%s` WHERE %s)", table.Name, strings.Join(colVals, " AND "))
-	preparedStmt, err := dst.DB().Prepare(stmt)
+	preparedStmt, err := dst.DB().PrepareContext(ctx, stmt)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %s", err)
 	}
+	defer preparedStmt.Close()
 
 	var exists bool
 	for rows.Next() {
@@ -284,27 +496,18 @@ func EachMissingRow(src, dst DB, table *Table, f func([]interface{})) error {
 			return fmt.Errorf("failed to scan row: %s", err)
 		}
 
-		for i := range scanArgs {
-			arg := scanArgs[i]
-			iface, ok := arg.(*interface{})
-			if !ok {
-				log.Fatalf("received unexpected type as scanArg: %T (should be *interface{})", arg)
-			}
-
-			// replace the precise PostgreSQL time with a less precise MySQL-compatible time
-			if t1, ok := (*iface).(time.Time); ok {
-				var timeArg interface{} = t1.Truncate(time.Second)
-				scanArgs[i] = &timeArg
-			}
-		}
+		// replace the precise PostgreSQL values with what MySQL stores for them
+		args := converter.Convert(values)
 
 		// determine if the row exists in dst
-		if err = preparedStmt.QueryRow(scanArgs...).Scan(&exists); err != nil {
+		if err = preparedStmt.QueryRowContext(ctx, args...).Scan(&exists); err != nil {
 			return fmt.Errorf("failed to check if row exists: %s", err)
 		}
 
 		if !exists {
-			f(scanArgs)
+			if err = f(args); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,392 @@
+package pg2mysql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const deadLetterExtension = ".jsonl"
+
+// DeadLetter is a row that failed to insert into MySQL, as recorded in the
+// dead-letter file of its table.
+type DeadLetter struct {
+	Table        string        `json:"table"`
+	Columns      []string      `json:"columns"`
+	Values       []interface{} `json:"values"`
+	ErrorNumber  uint16        `json:"error_number,omitempty"`
+	ErrorMessage string        `json:"error_message"`
+	FailedAt     time.Time     `json:"failed_at"`
+
+	// SRIDs are the SRIDs of the PostGIS columns of the row, by name, that
+	// their WKB is written to MySQL spatial columns in when replayed.
+	SRIDs map[string]int64 `json:"srids,omitempty"`
+}
+
+// NewDeadLetter records the converted values of a row of table that failed
+// to insert with err.
+func NewDeadLetter(table *Table, values []interface{}, err error) DeadLetter {
+	deadLetter := DeadLetter{
+		Table:    table.Name,
+		Columns:  make([]string, len(table.Columns)),
+		Values:   make([]interface{}, len(values)),
+		FailedAt: time.Now().UTC(),
+	}
+
+	for i, column := range table.Columns {
+		deadLetter.Columns[i] = column.Name
+
+		if isGeometryColumn(column) {
+			if deadLetter.SRIDs == nil {
+				deadLetter.SRIDs = map[string]int64{}
+			}
+			deadLetter.SRIDs[column.Name] = column.SRID
+		}
+	}
+
+	for i, value := range values {
+		// WKB is recorded as bytes even when it happens to be valid text
+		if b, ok := value.([]byte); ok && i < len(table.Columns) && isGeometryColumn(table.Columns[i]) {
+			deadLetter.Values[i] = map[string]string{"base64": base64.StdEncoding.EncodeToString(b)}
+			continue
+		}
+		deadLetter.Values[i] = encodeDeadLetterValue(value)
+	}
+
+	deadLetter.ErrorNumber, deadLetter.ErrorMessage = mysqlError(err)
+
+	return deadLetter
+}
+
+// table is the table the row was read from, as far as the dead letter
+// records it: its columns, and the types and SRIDs of its geometries.
+func (d DeadLetter) table() *Table {
+	table := &Table{
+		Name:    d.Table,
+		Columns: make([]*Column, len(d.Columns)),
+	}
+	for i, name := range d.Columns {
+		table.Columns[i] = &Column{Name: name}
+		if srid, ok := d.SRIDs[name]; ok {
+			table.Columns[i].Type = "geometry"
+			table.Columns[i].SRID = srid
+		}
+	}
+
+	return table
+}
+
+// Args returns the values of the row, decoded so they can be inserted again.
+func (d DeadLetter) Args() []interface{} {
+	args := make([]interface{}, len(d.Values))
+	for i, value := range d.Values {
+		args[i] = decodeDeadLetterValue(value)
+	}
+
+	return args
+}
+
+// Timestamps are written as MySQL datetime literals, since they have already
+// been converted to the destination zone, bytes that aren't text as
+// {"base64": "..."} so they can be told apart from strings, and NaN and
+// infinities, which JSON has no numbers for, as {"float": "NaN"}.
+func encodeDeadLetterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		return encodeDeadLetterFloat(v)
+	case float32:
+		return encodeDeadLetterFloat(float64(v))
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05.999999")
+	case PackedUUID:
+		return map[string]string{"base64": base64.StdEncoding.EncodeToString(v.Bytes())}
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return map[string]string{"base64": base64.StdEncoding.EncodeToString(v)}
+	default:
+		return v
+	}
+}
+
+func encodeDeadLetterFloat(f float64) interface{} {
+	switch {
+	case math.IsNaN(f):
+		return map[string]string{"float": "NaN"}
+	case math.IsInf(f, 1):
+		return map[string]string{"float": "Infinity"}
+	case math.IsInf(f, -1):
+		return map[string]string{"float": "-Infinity"}
+	default:
+		return f
+	}
+}
+
+func decodeDeadLetterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		return v.String()
+	case map[string]interface{}:
+		if encoded, ok := v["base64"].(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				return b
+			}
+		}
+		if encoded, ok := v["float"].(string); ok {
+			if f, err := strconv.ParseFloat(encoded, 64); err == nil {
+				return f
+			}
+		}
+		return value
+	default:
+		return v
+	}
+}
+
+// DeadLetterWriter appends dead letters to a <table>.jsonl file per table in
+// a directory. Files and the directory are only created once a row fails.
+type DeadLetterWriter struct {
+	dir   string
+	files map[string]*os.File
+}
+
+func NewDeadLetterWriter(dir string) *DeadLetterWriter {
+	return &DeadLetterWriter{
+		dir:   dir,
+		files: map[string]*os.File{},
+	}
+}
+
+func (w *DeadLetterWriter) Write(table *Table, values []interface{}, insertErr error) error {
+	line, err := json.Marshal(NewDeadLetter(table, values, insertErr))
+	if err != nil {
+		return err
+	}
+
+	file, ok := w.files[table.Name]
+	if !ok {
+		if err := os.MkdirAll(w.dir, 0755); err != nil {
+			return err
+		}
+
+		file, err = os.OpenFile(DeadLetterPath(w.dir, table.Name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		w.files[table.Name] = file
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func (w *DeadLetterWriter) Close() error {
+	var closeErr error
+	for tableName, file := range w.files {
+		if err := file.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+		delete(w.files, tableName)
+	}
+
+	return closeErr
+}
+
+func DeadLetterPath(dir, tableName string) string {
+	return filepath.Join(dir, tableName+deadLetterExtension)
+}
+
+// ReadDeadLetters reads the dead letters recorded for a table.
+func ReadDeadLetters(path string) ([]DeadLetter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var deadLetters []DeadLetter
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		decoder.UseNumber()
+
+		var deadLetter DeadLetter
+		if err := decoder.Decode(&deadLetter); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter in %s: %s", path, err)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return deadLetters, nil
+}
+
+// writeDeadLetters replaces the dead letters at path, removing the file when
+// none are left.
+func writeDeadLetters(path string, deadLetters []DeadLetter) error {
+	if len(deadLetters) == 0 {
+		return os.Remove(path)
+	}
+
+	var buf bytes.Buffer
+	for _, deadLetter := range deadLetters {
+		line, err := json.Marshal(deadLetter)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+type Replayer interface {
+	Replay(ctx context.Context) error
+}
+
+func NewReplayer(dst DB, dir string, watcher ReplayerWatcher) Replayer {
+	return &replayer{
+		dst:     dst,
+		dir:     dir,
+		watcher: watcher,
+	}
+}
+
+type replayer struct {
+	dst     DB
+	dir     string
+	watcher ReplayerWatcher
+}
+
+// Replay retries every dead letter in the directory. Rows that insert are
+// removed from their file; rows that fail again, or weren't retried because
+// ctx was cancelled, stay with their new error.
+func (r *replayer) Replay(ctx context.Context) error {
+	paths, err := filepath.Glob(filepath.Join(r.dir, "*"+deadLetterExtension))
+	if err != nil {
+		return err
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	// Geometries are written with the placeholders the migrator would
+	// have written them with, which depend on the MySQL columns
+	schema, err := BuildSchema(ctx, r.dst)
+	if err != nil {
+		return fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	longLat, err := swapsGeographicAxes(ctx, r.dst)
+	if err != nil {
+		return err
+	}
+	options := ConversionOptions{LongLatAxisOrder: longLat}
+
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tableName := strings.TrimSuffix(filepath.Base(path), deadLetterExtension)
+
+		r.watcher.DeadLetterReplayDidStart(tableName)
+
+		replayed, remaining, err := r.replayFile(ctx, path, schema, options)
+		if err != nil {
+			r.watcher.DeadLetterReplayDidFinishWithError(tableName, err)
+			continue
+		}
+
+		r.watcher.DeadLetterReplayDidFinish(tableName, replayed, remaining)
+	}
+
+	return nil
+}
+
+func (r *replayer) replayFile(ctx context.Context, path string, schema *Schema, options ConversionOptions) (int64, int64, error) {
+	deadLetters, err := ReadDeadLetters(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Rows of the same table may have been recorded by runs with different
+	// columns, so statements are prepared per set of columns and how they
+	// are written
+	stmts := map[string]*sql.Stmt{}
+	defer func() {
+		for _, stmt := range stmts {
+			stmt.Close()
+		}
+	}()
+
+	var replayed int64
+	var remaining []DeadLetter
+	for i, deadLetter := range deadLetters {
+		if ctx.Err() != nil {
+			remaining = append(remaining, deadLetters[i:]...)
+			break
+		}
+
+		table := deadLetter.table()
+		dstTable, _ := schema.GetTable(table.Name)
+
+		query := insertStatement(table, NewRowConverter(table, dstTable, options))
+		stmt, ok := stmts[query]
+		if !ok {
+			stmt, err = r.dst.DB().PrepareContext(ctx, query)
+			if err != nil {
+				// Such as for a column that is gone from MySQL. The
+				// rows replayed so far still have to be removed
+				remaining = append(remaining, NewDeadLetter(table, deadLetter.Args(), err))
+				continue
+			}
+			stmts[query] = stmt
+		}
+
+		args := deadLetter.Args()
+		if err = insert(stmt, args); err != nil {
+			remaining = append(remaining, NewDeadLetter(table, args, err))
+			continue
+		}
+
+		replayed++
+	}
+
+	if err := writeDeadLetters(path, remaining); err != nil {
+		return replayed, int64(len(remaining)), fmt.Errorf("failed writing remaining dead letters: %s", err)
+	}
+
+	return replayed, int64(len(remaining)), nil
+}
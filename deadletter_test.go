@@ -0,0 +1,215 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"os"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("DeadLetterWriter", func() {
+	var (
+		dir    string
+		table  *pg2mysql.Table
+		writer *pg2mysql.DeadLetterWriter
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "dead-letters")
+		Expect(err).NotTo(HaveOccurred())
+
+		table = &pg2mysql.Table{
+			Name: "some_table",
+			Columns: []*pg2mysql.Column{
+				{Name: "id"},
+				{Name: "name"},
+				{Name: "data"},
+				{Name: "created_at"},
+				{Name: "deleted_at"},
+			},
+		}
+		writer = pg2mysql.NewDeadLetterWriter(dir)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("records the row and the MySQL error", func() {
+		values := []interface{}{
+			int64(3),
+			"some-name",
+			[]byte{0xff, 0x00},
+			time.Date(2017, 3, 24, 12, 30, 15, 0, time.UTC),
+			nil,
+		}
+		insertErr := &mysql.MySQLError{Number: 1366, Message: "Incorrect string value"}
+		Expect(writer.Write(table, values, insertErr)).To(Succeed())
+		Expect(writer.Write(table, values, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "some_table"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(2))
+
+		Expect(deadLetters[0].Table).To(Equal("some_table"))
+		Expect(deadLetters[0].Columns).To(Equal([]string{"id", "name", "data", "created_at", "deleted_at"}))
+		Expect(deadLetters[0].ErrorNumber).To(BeEquivalentTo(1366))
+		Expect(deadLetters[0].ErrorMessage).To(Equal("Incorrect string value"))
+		Expect(deadLetters[0].Args()).To(Equal([]interface{}{
+			int64(3),
+			"some-name",
+			[]byte{0xff, 0x00},
+			"2017-03-24 12:30:15",
+			nil,
+		}))
+
+		Expect(deadLetters[1].ErrorNumber).To(BeZero())
+		Expect(deadLetters[1].ErrorMessage).To(Equal("some-error"))
+	})
+
+	It("records NaN and infinities, which JSON has no numbers for", func() {
+		values := []interface{}{math.NaN(), math.Inf(1), math.Inf(-1), 1.5, nil}
+		Expect(writer.Write(table, values, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "some_table"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(1))
+
+		args := deadLetters[0].Args()
+		Expect(math.IsNaN(args[0].(float64))).To(BeTrue())
+		Expect(args[1:]).To(Equal([]interface{}{math.Inf(1), math.Inf(-1), "1.5", nil}))
+	})
+
+	It("records the SRIDs of geometries", func() {
+		table.Columns[2] = &pg2mysql.Column{Name: "data", Type: "geometry", SRID: 4326}
+		values := []interface{}{int64(3), nil, []byte{0x01, 0x01, 0x00, 0x00, 0x00}, nil, nil}
+		Expect(writer.Write(table, values, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "some_table"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(1))
+		Expect(deadLetters[0].SRIDs).To(Equal(map[string]int64{"data": 4326}))
+		Expect(deadLetters[0].Args()[2]).To(Equal([]byte{0x01, 0x01, 0x00, 0x00, 0x00}))
+	})
+
+	It("doesn't create any files for rows it can't record", func() {
+		values := []interface{}{make(chan int), nil, nil, nil, nil}
+		Expect(writer.Write(table, values, errors.New("some-error"))).NotTo(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		_, err := os.Stat(pg2mysql.DeadLetterPath(dir, "some_table"))
+		Expect(os.IsNotExist(err)).To(BeTrue())
+	})
+
+	It("doesn't create any files until a row fails", func() {
+		Expect(writer.Close()).To(Succeed())
+
+		files, err := ioutil.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Replaying dead letters", func() {
+	var (
+		dir     string
+		mysql   pg2mysql.DB
+		watcher *pg2mysqlfakes.FakeReplayerWatcher
+		table   *pg2mysql.Table
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "dead-letters")
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE readings (id int PRIMARY KEY, value double)",
+			"INSERT INTO readings VALUES (1, 0)",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		watcher = &pg2mysqlfakes.FakeReplayerWatcher{}
+		table = &pg2mysql.Table{
+			Name:    "readings",
+			Columns: []*pg2mysql.Column{{Name: "id"}, {Name: "value"}},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(os.RemoveAll(dir)).To(Succeed())
+
+		_, err := mysqlRunner.DB().Exec("DROP TABLE readings")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("keeps rows it can't prepare a statement for and removes those it replayed", func() {
+		writer := pg2mysql.NewDeadLetterWriter(dir)
+		Expect(writer.Write(table, []interface{}{int64(2), 2.5}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Write(&pg2mysql.Table{
+			Name:    "readings",
+			Columns: []*pg2mysql.Column{{Name: "id"}, {Name: "missing"}},
+		}, []interface{}{int64(3), "some-value"}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Write(table, []interface{}{int64(4), 4.5}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		Expect(pg2mysql.NewReplayer(mysql, dir, watcher).Replay(context.Background())).To(Succeed())
+
+		Expect(watcher.DeadLetterReplayDidFinishWithErrorCallCount()).To(BeZero())
+		_, replayed, remaining := watcher.DeadLetterReplayDidFinishArgsForCall(0)
+		Expect([]int64{replayed, remaining}).To(Equal([]int64{2, 1}))
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "readings"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(1))
+		Expect(deadLetters[0].Columns).To(Equal([]string{"id", "missing"}))
+		Expect(deadLetters[0].ErrorMessage).To(ContainSubstring("missing"))
+
+		// Replaying again doesn't insert the replayed rows twice
+		Expect(pg2mysql.NewReplayer(mysql, dir, watcher).Replay(context.Background())).To(Succeed())
+		var count int
+		Expect(mysqlRunner.DB().QueryRow("SELECT COUNT(*) FROM readings").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(3))
+	})
+
+	It("keeps rows with NaN that fail again as they were", func() {
+		writer := pg2mysql.NewDeadLetterWriter(dir)
+		Expect(writer.Write(table, []interface{}{int64(1), math.NaN()}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Write(table, []interface{}{int64(2), 2.5}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		Expect(pg2mysql.NewReplayer(mysql, dir, watcher).Replay(context.Background())).To(Succeed())
+
+		Expect(watcher.DeadLetterReplayDidFinishWithErrorCallCount()).To(BeZero())
+		Expect(watcher.DeadLetterReplayDidFinishCallCount()).To(Equal(1))
+		_, replayed, remaining := watcher.DeadLetterReplayDidFinishArgsForCall(0)
+		Expect([]int64{replayed, remaining}).To(Equal([]int64{1, 1}))
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "readings"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(1))
+
+		args := deadLetters[0].Args()
+		Expect(args[0]).To(Equal(int64(1)))
+		Expect(math.IsNaN(args[1].(float64))).To(BeTrue())
+	})
+})
@@ -0,0 +1,211 @@
+package pg2mysql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ParseEnumLabels parses the labels of an enum definition as MySQL describes
+// it, e.g. enum('a','b'), where quotes in labels are doubled. Labels quoted
+// as E'...', the way PostgreSQL quotes those with backslashes, are unescaped
+// too.
+func ParseEnumLabels(definition string) ([]string, error) {
+	lower := strings.ToLower(definition)
+	if !strings.HasPrefix(lower, "enum(") || !strings.HasSuffix(lower, ")") {
+		return nil, fmt.Errorf("invalid enum definition '%s'", definition)
+	}
+
+	s := definition[len("enum(") : len(definition)-1]
+	var labels []string
+	for len(s) > 0 {
+		escaped := false
+		if s[0] == 'E' {
+			escaped = true
+			s = s[1:]
+		}
+		if len(s) == 0 || s[0] != '\'' {
+			return nil, fmt.Errorf("invalid enum definition '%s'", definition)
+		}
+
+		var label strings.Builder
+		i := 1
+		for ; i < len(s); i++ {
+			if escaped && s[i] == '\\' && i+1 < len(s) {
+				i++
+				label.WriteByte(s[i])
+				continue
+			}
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					i++
+					label.WriteByte('\'')
+					continue
+				}
+				break
+			}
+			label.WriteByte(s[i])
+		}
+		if i == len(s) {
+			return nil, fmt.Errorf("invalid enum definition '%s'", definition)
+		}
+		labels = append(labels, label.String())
+
+		s = s[i+1:]
+		if strings.HasPrefix(s, ",") {
+			s = s[1:]
+		} else if len(s) > 0 {
+			return nil, fmt.Errorf("invalid enum definition '%s'", definition)
+		}
+	}
+
+	return labels, nil
+}
+
+// EnumDefinition is the MySQL column type of an enum with labels.
+func EnumDefinition(labels []string) string {
+	quoted := make([]string, len(labels))
+	for i, label := range labels {
+		quoted[i] = "'" + strings.Replace(label, "'", "''", -1) + "'"
+	}
+
+	return "ENUM(" + strings.Join(quoted, ",") + ")"
+}
+
+type MissingEnumLabelMetadata struct {
+	ColumnName string
+
+	// Labels are the labels, or values of a column that isn't an enum, that
+	// the MySQL enum doesn't have.
+	Labels   []string
+	RowCount int64
+	RowIDs   []string
+
+	// Definition is an enum definition that has every label: those of the
+	// PostgreSQL enum, or those of the MySQL enum and the missing values.
+	Definition string
+}
+
+// GetMissingEnumLabels finds the values of the columns of src that the enum
+// columns of dst don't have labels for, which MySQL may store as an empty
+// string without an error.
+func GetMissingEnumLabels(ctx context.Context, db DB, src, dst *Table) ([]MissingEnumLabelMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []MissingEnumLabelMetadata
+	for _, column := range src.Columns {
+		_, dstColumn, err := dst.GetColumn(column.Name)
+		if err != nil || dstColumn.Type != "enum" {
+			continue
+		}
+
+		values := column.Labels
+		if column.Type != "enum" {
+			if values, err = distinctValues(ctx, db, src, column); err != nil {
+				return nil, err
+			}
+		}
+
+		dstLabels := map[string]bool{}
+		for _, label := range dstColumn.Labels {
+			dstLabels[label] = true
+		}
+
+		metadata := MissingEnumLabelMetadata{
+			ColumnName: column.Name,
+		}
+		for _, value := range values {
+			if !dstLabels[value] {
+				metadata.Labels = append(metadata.Labels, value)
+			}
+		}
+		if len(metadata.Labels) == 0 {
+			continue
+		}
+
+		if column.Type == "enum" {
+			metadata.Definition = EnumDefinition(column.Labels)
+		} else {
+			metadata.Definition = EnumDefinition(append(append([]string{}, dstColumn.Labels...), metadata.Labels...))
+		}
+
+		if err := countRowsWithValues(ctx, db, src, column, primaryKey, metadata.Labels, &metadata); err != nil {
+			return nil, err
+		}
+
+		missing = append(missing, metadata)
+	}
+
+	return missing, nil
+}
+
+func distinctValues(ctx context.Context, db DB, table *Table, column *Column) ([]string, error) {
+	rows, err := db.DB().QueryContext(ctx, fmt.Sprintf(
+		"SELECT DISTINCT \"%[1]s\"::text FROM \"%[2]s\" WHERE \"%[1]s\" IS NOT NULL",
+		column.Name,
+		table.Name,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed getting distinct values: %s", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to scan value: %s", err)
+		}
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	return values, rows.Err()
+}
+
+func countRowsWithValues(ctx context.Context, db DB, table *Table, column *Column, primaryKey []string, values []string, metadata *MissingEnumLabelMetadata) error {
+	condition := fmt.Sprintf("\"%s\"::text = ANY($1)", column.Name)
+
+	if len(primaryKey) == 0 {
+		stmt := fmt.Sprintf("SELECT count(1) FROM \"%s\" WHERE %s", table.Name, condition)
+		if err := db.DB().QueryRowContext(ctx, stmt, pq.Array(values)).Scan(&metadata.RowCount); err != nil {
+			return fmt.Errorf("failed counting rows with missing labels: %s", err)
+		}
+		return nil
+	}
+
+	keyColumnsForSelect := make([]string, len(primaryKey))
+	for i := range primaryKey {
+		keyColumnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM \"%s\" WHERE %s", strings.Join(keyColumnsForSelect, ","), table.Name, condition)
+	rows, err := db.DB().QueryContext(ctx, stmt, pq.Array(values))
+	if err != nil {
+		return fmt.Errorf("failed getting rows with missing labels: %s", err)
+	}
+	defer rows.Close()
+
+	keyValues := make([]interface{}, len(primaryKey))
+	keyScanArgs := make([]interface{}, len(primaryKey))
+	for i := range keyValues {
+		keyScanArgs[i] = &keyValues[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(keyScanArgs...); err != nil {
+			return fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		metadata.RowIDs = append(metadata.RowIDs, FormatRowID(keyValues))
+		metadata.RowCount++
+	}
+
+	return rows.Err()
+}
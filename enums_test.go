@@ -0,0 +1,31 @@
+package pg2mysql_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("Enums", func() {
+	It("parses the labels of enum definitions", func() {
+		Expect(pg2mysql.ParseEnumLabels("enum('a','b c','')")).To(Equal([]string{"a", "b c", ""}))
+		Expect(pg2mysql.ParseEnumLabels("enum('it''s','x,y')")).To(Equal([]string{"it's", "x,y"}))
+		Expect(pg2mysql.ParseEnumLabels(`enum('a',E'b\\c')`)).To(Equal([]string{"a", `b\c`}))
+	})
+
+	It("rejects definitions that aren't enums", func() {
+		_, err := pg2mysql.ParseEnumLabels("varchar(255)")
+		Expect(err).To(HaveOccurred())
+
+		_, err = pg2mysql.ParseEnumLabels("enum('a")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("defines enums with labels that parse back", func() {
+		labels := []string{"active", "it's", "x,y"}
+		definition := pg2mysql.EnumDefinition(labels)
+		Expect(definition).To(Equal("ENUM('active','it''s','x,y')"))
+		Expect(pg2mysql.ParseEnumLabels(definition)).To(Equal(labels))
+	})
+})
@@ -0,0 +1,29 @@
+package pg2mysql
+
+import "time"
+
+// Statement exposes the statement a write mode writes rows with to tests.
+func (w WriteMode) Statement(table *Table, primaryKey []string, converter *RowConverter) (string, error) {
+	return w.statement(table, primaryKey, converter)
+}
+
+// DecodeChangeValue exposes decodeChangeValue to tests.
+var DecodeChangeValue = decodeChangeValue
+
+// SameValues exposes sameValues to tests.
+var SameValues = sameValues
+
+// ProgressTracker exposes progressTracker to tests, on a clock of their own.
+type ProgressTracker = progressTracker
+
+func NewProgressTracker(watcher MigratorWatcher, interval time.Duration, total int64, now func() time.Time) *ProgressTracker {
+	t := newProgressTracker(watcher, interval, total)
+	t.now = now
+	t.started = now()
+	t.lastReported = t.started
+	return t
+}
+
+func (t *progressTracker) StartTable(tableName string, total int64) { t.startTable(tableName, total) }
+func (t *progressTracker) RowDone()                                 { t.rowDone() }
+func (t *progressTracker) FinishTable()                             { t.finishTable() }
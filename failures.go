@@ -0,0 +1,111 @@
+package pg2mysql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// RowFailures tallies the rows that failed to insert during a migration, by
+// table and by MySQL error number.
+type RowFailures struct {
+	Total   int64
+	ByTable map[string]int64
+	ByError map[uint16]*ErrorFailures
+}
+
+// ErrorFailures are the failed rows sharing a MySQL error number. Errors that
+// didn't come from MySQL are counted under number 0.
+type ErrorFailures struct {
+	Number  uint16
+	Message string // the message of the first failure
+	Rows    int64
+	Tables  map[string]int64
+}
+
+func NewRowFailures() *RowFailures {
+	return &RowFailures{
+		ByTable: map[string]int64{},
+		ByError: map[uint16]*ErrorFailures{},
+	}
+}
+
+func (f *RowFailures) Add(tableName string, err error) {
+	number, message := mysqlError(err)
+
+	f.Total++
+	f.ByTable[tableName]++
+
+	errorFailures, ok := f.ByError[number]
+	if !ok {
+		errorFailures = &ErrorFailures{
+			Number:  number,
+			Message: message,
+			Tables:  map[string]int64{},
+		}
+		f.ByError[number] = errorFailures
+	}
+	errorFailures.Rows++
+	errorFailures.Tables[tableName]++
+}
+
+// Errors returns the failures grouped by error number, most rows first.
+func (f *RowFailures) Errors() []*ErrorFailures {
+	errorFailures := make([]*ErrorFailures, 0, len(f.ByError))
+	for _, e := range f.ByError {
+		errorFailures = append(errorFailures, e)
+	}
+
+	sort.Slice(errorFailures, func(i, j int) bool {
+		if errorFailures[i].Rows != errorFailures[j].Rows {
+			return errorFailures[i].Rows > errorFailures[j].Rows
+		}
+		return errorFailures[i].Number < errorFailures[j].Number
+	})
+
+	return errorFailures
+}
+
+// TableNames returns the names of the tables in e, sorted.
+func (e *ErrorFailures) TableNames() []string {
+	names := make([]string, 0, len(e.Tables))
+	for name := range e.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// RowFailuresError is returned by a migration that finished, or was stopped,
+// with rows that failed to insert.
+type RowFailuresError struct {
+	Failures *RowFailures
+	Reason   string
+}
+
+func (e *RowFailuresError) Error() string {
+	rows := "rows"
+	if e.Failures.Total == 1 {
+		rows = "row"
+	}
+
+	if e.Reason != "" {
+		return fmt.Sprintf("%s after %d %s failed to insert", e.Reason, e.Failures.Total, rows)
+	}
+
+	return fmt.Sprintf("%d %s failed to insert", e.Failures.Total, rows)
+}
+
+// mysqlError returns the MySQL error number and message of err, or 0 and the
+// whole error if it didn't come from the server.
+func mysqlError(err error) (uint16, string) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number, mysqlErr.Message
+	}
+
+	return 0, err.Error()
+}
@@ -0,0 +1,121 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("RowFailures", func() {
+	It("tallies failures by table and by MySQL error number", func() {
+		failures := pg2mysql.NewRowFailures()
+		failures.Add("a", &mysql.MySQLError{Number: 1366, Message: "Incorrect string value"})
+		failures.Add("b", fmt.Errorf("failed to exec stmt: %w", &mysql.MySQLError{Number: 1366, Message: "Incorrect string value: 2"}))
+		failures.Add("b", &mysql.MySQLError{Number: 1366, Message: "Incorrect string value: 3"})
+		failures.Add("a", &mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"})
+		failures.Add("a", errors.New("some-error"))
+
+		Expect(failures.Total).To(BeEquivalentTo(5))
+		Expect(failures.ByTable).To(Equal(map[string]int64{"a": 3, "b": 2}))
+
+		errorFailures := failures.Errors()
+		Expect(errorFailures).To(HaveLen(3))
+
+		Expect(errorFailures[0].Number).To(BeEquivalentTo(1366))
+		Expect(errorFailures[0].Message).To(Equal("Incorrect string value"))
+		Expect(errorFailures[0].Rows).To(BeEquivalentTo(3))
+		Expect(errorFailures[0].TableNames()).To(Equal([]string{"a", "b"}))
+
+		Expect(errorFailures[1].Number).To(BeZero())
+		Expect(errorFailures[1].Message).To(Equal("some-error"))
+		Expect(errorFailures[2].Number).To(BeEquivalentTo(1048))
+	})
+
+	It("describes why a migration stopped", func() {
+		failures := pg2mysql.NewRowFailures()
+		failures.Add("a", errors.New("some-error"))
+
+		Expect((&pg2mysql.RowFailuresError{Failures: failures}).Error()).To(Equal("1 row failed to insert"))
+
+		failures.Add("a", errors.New("some-error"))
+		Expect((&pg2mysql.RowFailuresError{Failures: failures, Reason: "stopped"}).Error()).To(Equal("stopped after 2 rows failed to insert"))
+	})
+})
+
+var _ = Describe("Error budget", func() {
+	var (
+		mysql   pg2mysql.DB
+		pg      pg2mysql.DB
+		watcher *pg2mysqlfakes.FakeMigratorWatcher
+
+		config pg2mysql.MigrationConfig
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE notes (
+				id int PRIMARY KEY,
+				body text
+			);
+			INSERT INTO notes VALUES (1, 'a'), (2, NULL), (3, NULL), (4, 'b');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec("CREATE TABLE notes (id int PRIMARY KEY, body text NOT NULL)")
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		watcher = &pg2mysqlfakes.FakeMigratorWatcher{}
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE notes")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE notes")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("migrates the other rows and reports the failures at the end", func() {
+		err := pg2mysql.NewMigrator(pg, mysql, false, watcher).Migrate(context.Background(), config)
+		Expect(err).To(MatchError("2 rows failed to insert"))
+
+		Expect(watcher.DidFailToMigrateRowWithErrorCallCount()).To(Equal(2))
+		Expect(watcher.MigrationDidFinishWithFailuresCallCount()).To(Equal(1))
+
+		var count int
+		Expect(mysqlRunner.DB().QueryRow("SELECT COUNT(*) FROM notes").Scan(&count)).To(Succeed())
+		Expect(count).To(Equal(2))
+	})
+
+	It("stops at the first failure when failing fast", func() {
+		config.FailFast = true
+
+		err := pg2mysql.NewMigrator(pg, mysql, false, watcher).Migrate(context.Background(), config)
+		Expect(err).To(MatchError(ContainSubstring("stopped after 1 row failed to insert")))
+		Expect(watcher.DidFailToMigrateRowWithErrorCallCount()).To(Equal(1))
+	})
+
+	It("stops once more rows fail than the budget allows", func() {
+		config.MaxErrors = 1
+
+		err := pg2mysql.NewMigrator(pg, mysql, false, watcher).Migrate(context.Background(), config)
+		Expect(err).To(MatchError(ContainSubstring("stopped after exceeding --max-errors of 1 after 2 rows failed to insert")))
+	})
+})
@@ -0,0 +1,133 @@
+package pg2mysql
+
+import (
+	"context"
+	"sort"
+)
+
+// ForeignKey is a foreign key constraint of Table, whose Columns reference
+// ReferencedColumns of ReferencedTable in the same order.
+type ForeignKey struct {
+	Name              string
+	Table             string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+func (fk ForeignKey) SelfReferencing() bool {
+	return fk.Table == fk.ReferencedTable
+}
+
+// GetForeignKeys reads the foreign keys of db from its GetForeignKeyRows: the
+// constraint name, table, column, referenced table and referenced column,
+// ordered by table, constraint and position in the constraint.
+func GetForeignKeys(ctx context.Context, db DB) ([]ForeignKey, error) {
+	rows, err := db.GetForeignKeyRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKey
+	for rows.Next() {
+		var name, table, column, referencedTable, referencedColumn string
+		if err := rows.Scan(&name, &table, &column, &referencedTable, &referencedColumn); err != nil {
+			return nil, err
+		}
+
+		last := len(foreignKeys) - 1
+		if last < 0 || foreignKeys[last].Name != name || foreignKeys[last].Table != table {
+			foreignKeys = append(foreignKeys, ForeignKey{
+				Name:            name,
+				Table:           table,
+				ReferencedTable: referencedTable,
+			})
+			last++
+		}
+
+		foreignKeys[last].Columns = append(foreignKeys[last].Columns, column)
+		foreignKeys[last].ReferencedColumns = append(foreignKeys[last].ReferencedColumns, referencedColumn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return foreignKeys, nil
+}
+
+// OrderTables sorts tables so that every table comes after the tables its
+// foreign keys reference, and otherwise by name. Foreign keys that can't be
+// satisfied by the order, because a table references itself or is part of
+// a cycle, are returned as deferred: their columns have to be filled in
+// once the tables they reference have been migrated. Foreign keys to
+// tables that aren't in tables are ignored.
+func OrderTables(tables []*Table, foreignKeys []ForeignKey) ([]*Table, []ForeignKey) {
+	remaining := map[string]*Table{}
+	for _, table := range tables {
+		remaining[table.Name] = table
+	}
+
+	keysByTable := map[string][]ForeignKey{}
+	var deferred []ForeignKey
+	for _, fk := range foreignKeys {
+		if remaining[fk.Table] == nil || remaining[fk.ReferencedTable] == nil {
+			continue
+		}
+
+		if fk.SelfReferencing() {
+			deferred = append(deferred, fk)
+			continue
+		}
+
+		keysByTable[fk.Table] = append(keysByTable[fk.Table], fk)
+	}
+
+	ready := func(name string) bool {
+		for _, fk := range keysByTable[name] {
+			if remaining[fk.ReferencedTable] != nil {
+				return false
+			}
+		}
+		return true
+	}
+
+	ordered := make([]*Table, 0, len(tables))
+	for len(remaining) > 0 {
+		names := make([]string, 0, len(remaining))
+		for name := range remaining {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		next := ""
+		for _, name := range names {
+			if ready(name) {
+				next = name
+				break
+			}
+		}
+
+		// Every remaining table is waiting on another, so break the cycle
+		// at the first one by deferring the keys it is waiting on
+		if next == "" {
+			next = names[0]
+
+			var kept []ForeignKey
+			for _, fk := range keysByTable[next] {
+				if remaining[fk.ReferencedTable] != nil {
+					deferred = append(deferred, fk)
+				} else {
+					kept = append(kept, fk)
+				}
+			}
+			keysByTable[next] = kept
+		}
+
+		ordered = append(ordered, remaining[next])
+		delete(remaining, next)
+	}
+
+	return ordered, deferred
+}
@@ -0,0 +1,145 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("OrderTables", func() {
+	tables := func(names ...string) []*pg2mysql.Table {
+		var tables []*pg2mysql.Table
+		for _, name := range names {
+			tables = append(tables, &pg2mysql.Table{Name: name})
+		}
+		return tables
+	}
+
+	names := func(tables []*pg2mysql.Table) []string {
+		var names []string
+		for _, table := range tables {
+			names = append(names, table.Name)
+		}
+		return names
+	}
+
+	fk := func(table, referencedTable string) pg2mysql.ForeignKey {
+		return pg2mysql.ForeignKey{
+			Name:              table + "_" + referencedTable + "_fkey",
+			Table:             table,
+			Columns:           []string{referencedTable + "_id"},
+			ReferencedTable:   referencedTable,
+			ReferencedColumns: []string{"id"},
+		}
+	}
+
+	It("orders tables without foreign keys by name", func() {
+		ordered, deferred := pg2mysql.OrderTables(tables("spaces", "apps", "organizations"), nil)
+		Expect(names(ordered)).To(Equal([]string{"apps", "organizations", "spaces"}))
+		Expect(deferred).To(BeEmpty())
+	})
+
+	It("puts referenced tables first", func() {
+		ordered, deferred := pg2mysql.OrderTables(
+			tables("apps", "organizations", "spaces"),
+			[]pg2mysql.ForeignKey{fk("apps", "spaces"), fk("spaces", "organizations")},
+		)
+		Expect(names(ordered)).To(Equal([]string{"organizations", "spaces", "apps"}))
+		Expect(deferred).To(BeEmpty())
+	})
+
+	It("defers foreign keys of a table to itself", func() {
+		selfReference := fk("spaces", "spaces")
+		ordered, deferred := pg2mysql.OrderTables(tables("spaces"), []pg2mysql.ForeignKey{selfReference})
+		Expect(names(ordered)).To(Equal([]string{"spaces"}))
+		Expect(deferred).To(Equal([]pg2mysql.ForeignKey{selfReference}))
+	})
+
+	It("breaks cycles at the first table by name", func() {
+		ordered, deferred := pg2mysql.OrderTables(
+			tables("apps", "droplets"),
+			[]pg2mysql.ForeignKey{fk("apps", "droplets"), fk("droplets", "apps")},
+		)
+		Expect(names(ordered)).To(Equal([]string{"apps", "droplets"}))
+		Expect(deferred).To(Equal([]pg2mysql.ForeignKey{fk("apps", "droplets")}))
+	})
+
+	It("ignores foreign keys to other tables", func() {
+		ordered, deferred := pg2mysql.OrderTables(
+			tables("spaces", "apps"),
+			[]pg2mysql.ForeignKey{fk("apps", "spaces"), fk("spaces", "ignored")},
+		)
+		Expect(names(ordered)).To(Equal([]string{"spaces", "apps"}))
+		Expect(deferred).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Deferred foreign key columns", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+		dir   string
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE nodes (
+				id int PRIMARY KEY,
+				parent_id int REFERENCES nodes (id),
+				name text
+			);
+			INSERT INTO nodes VALUES (1, NULL, 'root'), (2, 1, NULL);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec(`
+			CREATE TABLE nodes (
+				id int PRIMARY KEY,
+				parent_id int,
+				name text NOT NULL,
+				FOREIGN KEY (parent_id) REFERENCES nodes (id)
+			)`)
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		dir, err = ioutil.TempDir("", "dead-letters")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+		os.RemoveAll(dir)
+
+		_, err := pgRunner.DB().Exec("DROP TABLE nodes")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE nodes")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("dead-letters failed rows with the values of their deferred columns", func() {
+		config := pg2mysql.MigrationConfig{
+			IgnoreTables:       []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			EnforceForeignKeys: true,
+			DeadLetterDir:      dir,
+		}
+
+		err := pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{}).Migrate(context.Background(), config)
+		Expect(err).To(MatchError("1 row failed to insert"))
+
+		deadLetters, err := pg2mysql.ReadDeadLetters(pg2mysql.DeadLetterPath(dir, "nodes"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(deadLetters).To(HaveLen(1))
+		Expect(deadLetters[0].Args()).To(Equal([]interface{}{int64(2), int64(1), nil}))
+	})
+})
@@ -0,0 +1,255 @@
+package pg2mysql
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// isGeometryColumn is whether column is a PostGIS geometry or geography.
+func isGeometryColumn(column *Column) bool {
+	return column.Type == "geometry" || column.Type == "geography"
+}
+
+// mysqlSpatialTypes are the types of MySQL spatial columns, MySQL 8 calling
+// geometrycollection geomcollection.
+var mysqlSpatialTypes = []string{
+	"geometry", "point", "linestring", "polygon",
+	"multipoint", "multilinestring", "multipolygon", "geometrycollection", "geomcollection",
+}
+
+func isSpatialColumn(column *Column) bool {
+	for _, t := range mysqlSpatialTypes {
+		if column.Type == t {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGeometryType parses the type of a PostGIS column as format_type
+// describes it, e.g. geometry(Point,4326), into its geometry type, in lower
+// case and empty if it may hold any, and its SRID. Geography columns without
+// an SRID are in 4326, geometry columns in 0.
+func ParseGeometryType(definition string) (string, int64, error) {
+	name, modifiers := definition, ""
+	if i := strings.IndexByte(definition, '('); i >= 0 && strings.HasSuffix(definition, ")") {
+		name, modifiers = definition[:i], definition[i+1:len(definition)-1]
+	}
+
+	var srid int64
+	switch name {
+	case "geometry":
+	case "geography":
+		srid = 4326
+	default:
+		return "", 0, fmt.Errorf("invalid geometry type '%s'", definition)
+	}
+
+	if modifiers == "" {
+		return "", srid, nil
+	}
+
+	parts := strings.Split(modifiers, ",")
+	geometryType := strings.ToLower(strings.TrimSpace(parts[0]))
+	if geometryType == "geometry" {
+		geometryType = ""
+	}
+
+	if len(parts) > 1 {
+		var err error
+		if srid, err = strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64); err != nil {
+			return "", 0, fmt.Errorf("invalid geometry type '%s'", definition)
+		}
+	}
+
+	return geometryType, srid, nil
+}
+
+// selectExpression is the expression a column named name is selected from
+// PostgreSQL with: the name, or the WKB of a geometry, little-endian as
+// MySQL writes it.
+func selectExpression(name string, column *Column) string {
+	if isGeometryColumn(column) {
+		return fmt.Sprintf("ST_AsBinary(%s, 'NDR')", name)
+	}
+	return name
+}
+
+// geometryOption is the option MySQL 8 is told to read and write WKB with,
+// for geometries of geographic SRIDs to keep PostGIS's longitude-latitude
+// axis order.
+func geometryOption(options ConversionOptions) string {
+	if options.LongLatAxisOrder {
+		return ", 'axis-order=long-lat'"
+	}
+	return ""
+}
+
+// swapsGeographicAxes is whether dst takes the WKB of geographic SRIDs in
+// latitude-longitude order unless told otherwise, as MySQL 8 does.
+func swapsGeographicAxes(ctx context.Context, dst DB) (bool, error) {
+	var version string
+	if err := dst.DB().QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return false, fmt.Errorf("failed to detect axis order: %s", err)
+	}
+
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		return false, nil
+	}
+
+	major, _ := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	return major >= 8, nil
+}
+
+// geometryConversion keeps the WKB geometries are selected as, and turns the
+// hex EWKB of a geometry from a change into WKB.
+func geometryConversion(value interface{}) interface{} {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		if len(v) > 0 && v[0] <= 1 {
+			return v
+		}
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return value
+	}
+
+	ewkb, err := hex.DecodeString(s)
+	if err != nil {
+		return value
+	}
+
+	wkb, err := ewkbToWKB(ewkb)
+	if err != nil {
+		return value
+	}
+	return wkb
+}
+
+// ewkbSRIDFlag marks the type of an EWKB geometry that is followed by its
+// SRID.
+const ewkbSRIDFlag = 0x20000000
+
+// ewkbToWKB removes the SRID from an EWKB geometry. The flags of Z and M
+// coordinates are kept, and MySQL, which only supports two dimensions, will
+// reject them.
+func ewkbToWKB(ewkb []byte) ([]byte, error) {
+	if len(ewkb) < 5 || ewkb[0] > 1 {
+		return nil, fmt.Errorf("invalid EWKB")
+	}
+
+	var order binary.ByteOrder = binary.BigEndian
+	if ewkb[0] == 1 {
+		order = binary.LittleEndian
+	}
+
+	geometryType := order.Uint32(ewkb[1:5])
+	if geometryType&ewkbSRIDFlag == 0 {
+		return ewkb, nil
+	}
+	if len(ewkb) < 9 {
+		return nil, fmt.Errorf("invalid EWKB")
+	}
+
+	wkb := make([]byte, 5, len(ewkb)-4)
+	wkb[0] = ewkb[0]
+	order.PutUint32(wkb[1:5], geometryType&^ewkbSRIDFlag)
+	return append(wkb, ewkb[9:]...), nil
+}
+
+type InvalidGeometryMetadata struct {
+	ColumnName string
+
+	// Problem is what is wrong with the geometries, or with the column if
+	// RowCount is 0.
+	Problem  string
+	RowCount int64
+	RowIDs   []string
+}
+
+// GetInvalidGeometries finds the geometries of the columns of src that the
+// spatial columns of dst can't hold: those of types or with coordinates MySQL
+// doesn't support, of another type than the column, or in another SRID than
+// that of the PostGIS column, which they are written in.
+func GetInvalidGeometries(ctx context.Context, db DB, src, dst *Table) ([]InvalidGeometryMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	type check struct {
+		problem, condition string
+	}
+
+	var invalid []InvalidGeometryMetadata
+	for _, column := range src.Columns {
+		if !isGeometryColumn(column) {
+			continue
+		}
+
+		_, dstColumn, err := dst.GetColumn(column.Name)
+		if err != nil {
+			continue
+		}
+
+		if !isSpatialColumn(dstColumn) {
+			invalid = append(invalid, InvalidGeometryMetadata{
+				ColumnName: column.Name,
+				Problem:    fmt.Sprintf("is %s in MySQL rather than a spatial type", dstColumn.Type),
+			})
+			continue
+		}
+
+		geometry := fmt.Sprintf("\"%s\"::geometry", column.Name)
+		checks := []check{
+			{
+				problem: "of types or with coordinates MySQL doesn't support",
+				condition: fmt.Sprintf(
+					"ST_GeometryType(%[1]s) NOT IN ('ST_Point', 'ST_LineString', 'ST_Polygon', 'ST_MultiPoint', 'ST_MultiLineString', 'ST_MultiPolygon', 'ST_GeometryCollection') OR ST_NDims(%[1]s) > 2",
+					geometry,
+				),
+			},
+			{
+				problem:   fmt.Sprintf("in another SRID than %d", column.SRID),
+				condition: fmt.Sprintf("ST_SRID(%s) <> %d", geometry, column.SRID),
+			},
+		}
+
+		dstType := dstColumn.Type
+		if dstType == "geomcollection" {
+			dstType = "geometrycollection"
+		}
+		if dstType != "geometry" {
+			checks = append(checks, check{
+				problem:   fmt.Sprintf("that aren't of type %s", dstColumn.Type),
+				condition: fmt.Sprintf("lower(ST_GeometryType(%s)) <> 'st_%s'", geometry, dstType),
+			})
+		}
+
+		for _, c := range checks {
+			metadata := InvalidGeometryMetadata{
+				ColumnName: column.Name,
+				Problem:    c.problem,
+			}
+
+			condition := fmt.Sprintf("\"%s\" IS NOT NULL AND (%s)", column.Name, c.condition)
+			metadata.RowCount, metadata.RowIDs, err = rowsMatching(ctx, db, src, primaryKey, condition)
+			if err != nil {
+				return nil, fmt.Errorf("failed getting invalid geometries: %s", err)
+			}
+
+			if metadata.RowCount > 0 {
+				invalid = append(invalid, metadata)
+			}
+		}
+	}
+
+	return invalid, nil
+}
@@ -0,0 +1,77 @@
+package pg2mysql_test
+
+import (
+	"encoding/hex"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("Geometries", func() {
+	Describe("ParseGeometryType", func() {
+		It("parses the geometry type and SRID of PostGIS columns", func() {
+			geometryType, srid, err := pg2mysql.ParseGeometryType("geometry(Point,4326)")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(geometryType).To(Equal("point"))
+			Expect(srid).To(Equal(int64(4326)))
+
+			geometryType, srid, err = pg2mysql.ParseGeometryType("geometry")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(geometryType).To(BeEmpty())
+			Expect(srid).To(BeZero())
+
+			geometryType, srid, err = pg2mysql.ParseGeometryType("geography(MultiPolygon)")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(geometryType).To(Equal("multipolygon"))
+			Expect(srid).To(Equal(int64(4326)))
+
+			_, _, err = pg2mysql.ParseGeometryType("point")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("RowConverter", func() {
+		var src, dst *pg2mysql.Table
+
+		BeforeEach(func() {
+			src = &pg2mysql.Table{
+				Name:    "places",
+				Columns: []*pg2mysql.Column{{Name: "location", Type: "geometry", GeometryType: "point", SRID: 4326}},
+			}
+			dst = &pg2mysql.Table{
+				Name:    "places",
+				Columns: []*pg2mysql.Column{{Name: "location", Type: "point"}},
+			}
+		})
+
+		It("writes geometries from their WKB in the SRID of their column", func() {
+			converter := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{})
+			Expect(converter.WritePlaceholder(0)).To(Equal("ST_GeomFromWKB(?, 4326)"))
+			Expect(converter.Comparison(0, "`location`")).To(Equal("ST_AsBinary(`location`) <=> ?"))
+
+			converter = pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{LongLatAxisOrder: true})
+			Expect(converter.WritePlaceholder(0)).To(Equal("ST_GeomFromWKB(?, 4326, 'axis-order=long-lat')"))
+			Expect(converter.Comparison(0, "`location`")).To(Equal("ST_AsBinary(`location`, 'axis-order=long-lat') <=> ?"))
+		})
+
+		It("keeps WKB and removes the SRID of EWKB from changes", func() {
+			wkb, err := hex.DecodeString("0101000000000000000000F03F0000000000000040")
+			Expect(err).NotTo(HaveOccurred())
+
+			converter := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{})
+			Expect(converter.Convert([]interface{}{wkb})).To(Equal([]interface{}{wkb}))
+			Expect(converter.Convert([]interface{}{"0101000020E6100000000000000000F03F0000000000000040"})).To(Equal([]interface{}{wkb}))
+			Expect(converter.Convert([]interface{}{nil})).To(Equal([]interface{}{nil}))
+		})
+
+		It("leaves geometries alone when MySQL doesn't have a spatial column", func() {
+			dst.Columns[0].Type = "longblob"
+
+			converter := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{})
+			Expect(converter.WritePlaceholder(0)).To(Equal("?"))
+			Expect(converter.Comparison(0, "`location`")).To(Equal("`location` <=> ?"))
+		})
+	})
+})
@@ -0,0 +1,186 @@
+package pg2mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mysqlJSONMaxDepth is how deeply MySQL lets JSON documents nest.
+const mysqlJSONMaxDepth = 100
+
+// ConvertJSON rewrites a json or jsonb document from PostgreSQL into one
+// MySQL will store in a JSON column, or returns why MySQL would reject it.
+// NUL characters, which MySQL can't store in JSON strings, are replaced with
+// U+FFFD, and the document is re-encoded as UTF-8 text; MySQL normalizes key
+// order and whitespace itself.
+func ConvertJSON(data []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return "", fmt.Errorf("invalid JSON: %s", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return "", errors.New("invalid JSON: data after the document")
+	}
+
+	document, depth := rewriteJSON(document)
+	if depth > mysqlJSONMaxDepth {
+		return "", fmt.Errorf("JSON nested %d levels deep, more than the %d MySQL allows", depth, mysqlJSONMaxDepth)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// rewriteJSON replaces the NUL characters in the strings of a decoded
+// document and returns it with its depth.
+func rewriteJSON(value interface{}) (interface{}, int) {
+	switch v := value.(type) {
+	case string:
+		return strings.Replace(v, "\x00", "\uFFFD", -1), 1
+	case []interface{}:
+		depth := 0
+		for i := range v {
+			var d int
+			v[i], d = rewriteJSON(v[i])
+			if d > depth {
+				depth = d
+			}
+		}
+		return v, depth + 1
+	case map[string]interface{}:
+		rewritten := make(map[string]interface{}, len(v))
+		depth := 0
+		for key, member := range v {
+			member, d := rewriteJSON(member)
+			if d > depth {
+				depth = d
+			}
+			rewritten[strings.Replace(key, "\x00", "\uFFFD", -1)] = member
+		}
+		return rewritten, depth + 1
+	default:
+		return v, 1
+	}
+}
+
+func isJSONColumn(column *Column) bool {
+	return column.Type == "json" || column.Type == "jsonb"
+}
+
+// jsonConversion turns json and jsonb values, which the PostgreSQL driver
+// scans as bytes, into text MySQL won't take for binary data. Documents
+// going into a JSON column are rewritten with ConvertJSON; those MySQL would
+// reject are left as they are, to fail when they are inserted.
+func jsonConversion(toJSONColumn bool) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		data, ok := value.([]byte)
+		if !ok {
+			return value
+		}
+
+		if toJSONColumn {
+			if document, err := ConvertJSON(data); err == nil {
+				return document
+			}
+		}
+
+		return string(data)
+	}
+}
+
+type InvalidJSONMetadata struct {
+	ColumnName string
+	RowCount   int64
+	RowIDs     []string
+}
+
+// GetInvalidJSON finds the json and jsonb values of src that MySQL won't
+// accept in the JSON columns of dst.
+func GetInvalidJSON(ctx context.Context, db DB, src, dst *Table) ([]InvalidJSONMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	columnsForSelect := make([]string, len(primaryKey)+1)
+	for i := range primaryKey {
+		columnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	var invalid []InvalidJSONMetadata
+	for _, column := range src.Columns {
+		if !isJSONColumn(column) {
+			continue
+		}
+		if _, dstColumn, err := dst.GetColumn(column.Name); err != nil || dstColumn.Type != "json" {
+			continue
+		}
+
+		columnsForSelect[len(primaryKey)] = fmt.Sprintf("\"%s\"", column.Name)
+		stmt := fmt.Sprintf(
+			"SELECT %s FROM \"%s\" WHERE \"%s\" IS NOT NULL",
+			strings.Join(columnsForSelect, ","),
+			src.Name,
+			column.Name,
+		)
+
+		rows, err := db.DB().QueryContext(ctx, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting JSON values: %s", err)
+		}
+
+		values := make([]interface{}, len(columnsForSelect))
+		scanArgs := make([]interface{}, len(columnsForSelect))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+
+		metadata := InvalidJSONMetadata{
+			ColumnName: column.Name,
+		}
+		for rows.Next() {
+			if err := rows.Scan(scanArgs...); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan row: %s", err)
+			}
+
+			data, _ := values[len(primaryKey)].([]byte)
+			if _, err := ConvertJSON(data); err == nil {
+				continue
+			}
+
+			metadata.RowCount++
+			if len(primaryKey) > 0 {
+				metadata.RowIDs = append(metadata.RowIDs, FormatRowID(values[:len(primaryKey)]))
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+
+		if metadata.RowCount > 0 {
+			invalid = append(invalid, metadata)
+		}
+	}
+
+	return invalid, nil
+}
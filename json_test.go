@@ -0,0 +1,42 @@
+package pg2mysql_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("ConvertJSON", func() {
+	It("keeps valid documents", func() {
+		converted, err := pg2mysql.ConvertJSON([]byte(`{"a": [1, 2.50, "<b>"], "c": null}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(converted).To(MatchJSON(`{"a": [1, 2.50, "<b>"], "c": null}`))
+		Expect(converted).To(ContainSubstring("2.50"))
+		Expect(converted).To(ContainSubstring("<b>"))
+	})
+
+	It("replaces NUL characters", func() {
+		converted, err := pg2mysql.ConvertJSON([]byte(`{"a\u0000": "b\u0000c"}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(converted).To(Equal(`{"a�":"b�c"}`))
+	})
+
+	It("rejects invalid documents", func() {
+		_, err := pg2mysql.ConvertJSON([]byte(`{"a": `))
+		Expect(err).To(HaveOccurred())
+
+		_, err = pg2mysql.ConvertJSON([]byte(`{} {}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects documents nested more deeply than MySQL allows", func() {
+		_, err := pg2mysql.ConvertJSON([]byte(strings.Repeat("[", 99) + strings.Repeat("]", 99)))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = pg2mysql.ConvertJSON([]byte(strings.Repeat("[", 101) + strings.Repeat("]", 101)))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,254 @@
+package pg2mysql
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// JSONLinesWatcher writes every event as a JSON object on its own line, for
+// log pipelines rather than people. Each object has the time, phase and name
+// of the event, the table it is about if any, and fields specific to it.
+// Events that finish something that started carry its duration in seconds.
+type JSONLinesWatcher struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	now     func() time.Time
+	started map[string]time.Time
+}
+
+func NewJSONLinesWatcher(w io.Writer) *JSONLinesWatcher {
+	return &JSONLinesWatcher{
+		encoder: json.NewEncoder(w),
+		now:     time.Now,
+		started: map[string]time.Time{},
+	}
+}
+
+type jsonFields map[string]interface{}
+
+func (j *JSONLinesWatcher) emit(phase, event, tableName string, fields jsonFields) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+
+	record := jsonFields{
+		"time":  now.UTC().Format(time.RFC3339Nano),
+		"phase": phase,
+		"event": event,
+	}
+	if tableName != "" {
+		record["table"] = tableName
+	}
+	for k, v := range fields {
+		record[k] = v
+	}
+
+	key := phase + "/" + tableName
+	switch event {
+	case "started":
+		j.started[key] = now
+	case "finished", "failed":
+		if started, ok := j.started[key]; ok {
+			record["duration_seconds"] = now.Sub(started).Seconds()
+			delete(j.started, key)
+		}
+	}
+
+	// There's nowhere left to report a failure to write the log
+	_ = j.encoder.Encode(record)
+}
+
+func (j *JSONLinesWatcher) TableVerificationDidStart(tableName string) {
+	j.emit("verify", "started", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TableVerificationDidFinish(tableName string, missingRows int64, missingIDs []string) {
+	fields := jsonFields{"missing_rows": missingRows}
+	if missingIDs != nil {
+		fields["missing_ids"] = missingIDs
+	}
+
+	j.emit("verify", "finished", tableName, fields)
+}
+
+func (j *JSONLinesWatcher) TableVerificationDidFinishWithError(tableName string, err error) {
+	j.emit("verify", "failed", tableName, jsonFields{"error": err.Error()})
+}
+
+func (j *JSONLinesWatcher) TableRepairDidStart(tableName string) {
+	j.emit("repair", "started", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TableRepairDidFinish(tableName string, rowsInserted, rowsUpdated, rowsDeleted int64) {
+	j.emit("repair", "finished", tableName, jsonFields{
+		"rows_inserted": rowsInserted,
+		"rows_updated":  rowsUpdated,
+		"rows_deleted":  rowsDeleted,
+	})
+}
+
+func (j *JSONLinesWatcher) TableRepairDidFinishWithError(tableName string, err error) {
+	j.emit("repair", "failed", tableName, jsonFields{"error": err.Error()})
+}
+
+func (j *JSONLinesWatcher) WouldExecuteStatement(tableName string, stmt string) {
+	j.emit("repair", "would_execute", tableName, jsonFields{"statement": stmt})
+}
+
+func (j *JSONLinesWatcher) DeadLetterReplayDidStart(tableName string) {
+	j.emit("replay", "started", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) DeadLetterReplayDidFinish(tableName string, rowsReplayed, rowsRemaining int64) {
+	j.emit("replay", "finished", tableName, jsonFields{
+		"rows_replayed":  rowsReplayed,
+		"rows_remaining": rowsRemaining,
+	})
+}
+
+func (j *JSONLinesWatcher) DeadLetterReplayDidFinishWithError(tableName string, err error) {
+	j.emit("replay", "failed", tableName, jsonFields{"error": err.Error()})
+}
+
+func (j *JSONLinesWatcher) ReplicationSlotDidCreate(slotName, lsn string) {
+	j.emit("replicate", "slot_created", "", jsonFields{"slot": slotName, "lsn": lsn})
+}
+
+func (j *JSONLinesWatcher) ReplicationDidProgress(progress ReplicationProgress) {
+	j.emit("replicate", "progress", "", jsonFields{
+		"changes_applied": progress.ChangesApplied,
+		"confirmed_lsn":   progress.ConfirmedLSN,
+		"lag_bytes":       progress.LagBytes,
+	})
+}
+
+func (j *JSONLinesWatcher) ReplicationDidStop(confirmedLSN string) {
+	j.emit("replicate", "stopped", "", jsonFields{"confirmed_lsn": confirmedLSN})
+}
+
+func (j *JSONLinesWatcher) CaptureDidInstall(tableNames []string) {
+	j.emit("capture", "installed", "", jsonFields{"tables": tableNames})
+}
+
+func (j *JSONLinesWatcher) CaptureDidApplyChanges(changesApplied int64) {
+	j.emit("capture", "applied", "", jsonFields{"changes_applied": changesApplied})
+}
+
+func (j *JSONLinesWatcher) CaptureDidUninstall(tableNames []string) {
+	j.emit("capture", "uninstalled", "", jsonFields{"tables": tableNames})
+}
+
+func (j *JSONLinesWatcher) WillBuildSchema() {
+	j.emit("build_schema", "started", "", nil)
+}
+
+func (j *JSONLinesWatcher) DidBuildSchema() {
+	j.emit("build_schema", "finished", "", nil)
+}
+
+func (j *JSONLinesWatcher) WillDisableConstraints() {
+	j.emit("disable_constraints", "started", "", nil)
+}
+
+func (j *JSONLinesWatcher) DidDisableConstraints() {
+	j.emit("disable_constraints", "finished", "", nil)
+}
+
+func (j *JSONLinesWatcher) WillEnableConstraints() {
+	j.emit("enable_constraints", "started", "", nil)
+}
+
+func (j *JSONLinesWatcher) EnableConstraintsDidFinish() {
+	j.emit("enable_constraints", "finished", "", nil)
+}
+
+func (j *JSONLinesWatcher) EnableConstraintsDidFailWithError(err error) {
+	j.emit("enable_constraints", "failed", "", jsonFields{"error": err.Error()})
+}
+
+func (j *JSONLinesWatcher) WillTruncateTable(tableName string) {
+	j.emit("truncate", "started", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TruncateTableDidFinish(tableName string) {
+	j.emit("truncate", "finished", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TableMigrationDidStart(tableName string) {
+	j.emit("migrate", "started", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TableMigrationDidSkip(tableName string) {
+	j.emit("migrate", "skipped", tableName, nil)
+}
+
+func (j *JSONLinesWatcher) TableMigrationDidFinish(tableName string, recordsInserted int64) {
+	j.emit("migrate", "finished", tableName, jsonFields{"rows_inserted": recordsInserted})
+}
+
+func (j *JSONLinesWatcher) WillUpdateDeferredColumns(tableName string, columns []string) {
+	j.emit("update_deferred", "started", tableName, jsonFields{"columns": columns})
+}
+
+func (j *JSONLinesWatcher) DeferredColumnsDidUpdate(tableName string, rowsUpdated int64) {
+	j.emit("update_deferred", "finished", tableName, jsonFields{"rows_updated": rowsUpdated})
+}
+
+func (j *JSONLinesWatcher) TableMigrationDidProgress(progress MigrationProgress) {
+	fields := jsonFields{
+		"table_rows":            progress.TableRows,
+		"table_total":           progress.TableTotal,
+		"table_rows_per_sec":    progress.TableRate(),
+		"table_elapsed_seconds": progress.TableElapsed.Seconds(),
+		"rows":                  progress.Rows,
+		"total":                 progress.Total,
+		"rows_per_sec":          progress.Rate(),
+		"elapsed_seconds":       progress.Elapsed.Seconds(),
+	}
+	if eta, ok := progress.TableETA(); ok {
+		fields["table_eta_seconds"] = eta.Seconds()
+	}
+	if eta, ok := progress.ETA(); ok {
+		fields["eta_seconds"] = eta.Seconds()
+	}
+
+	j.emit("migrate", "progress", progress.TableName, fields)
+}
+
+// Rows that migrate are only counted in progress events, but every row that
+// fails gets its own event.
+func (j *JSONLinesWatcher) DidMigrateRow(tableName string) {}
+
+func (j *JSONLinesWatcher) DidInsertBytes(tableName string, bytes int64) {}
+
+func (j *JSONLinesWatcher) DidFailToMigrateRowWithError(tableName string, err error) {
+	number, message := mysqlError(err)
+
+	fields := jsonFields{"error": message}
+	if number != 0 {
+		fields["error_number"] = number
+	}
+
+	j.emit("migrate", "row_failed", tableName, fields)
+}
+
+func (j *JSONLinesWatcher) MigrationDidFinishWithFailures(failures *RowFailures) {
+	errors := make([]jsonFields, 0, len(failures.ByError))
+	for _, errorFailures := range failures.Errors() {
+		errors = append(errors, jsonFields{
+			"error_number": errorFailures.Number,
+			"error":        errorFailures.Message,
+			"rows":         errorFailures.Rows,
+			"tables":       errorFailures.Tables,
+		})
+	}
+
+	j.emit("migrate", "rows_failed", "", jsonFields{
+		"rows_failed": failures.Total,
+		"tables":      failures.ByTable,
+		"errors":      errors,
+	})
+}
@@ -0,0 +1,60 @@
+package pg2mysql_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("JSONLinesWatcher", func() {
+	var (
+		buf     *bytes.Buffer
+		watcher *pg2mysql.JSONLinesWatcher
+	)
+
+	BeforeEach(func() {
+		buf = &bytes.Buffer{}
+		watcher = pg2mysql.NewJSONLinesWatcher(buf)
+	})
+
+	events := func() []map[string]interface{} {
+		var events []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var event map[string]interface{}
+			Expect(json.Unmarshal([]byte(line), &event)).To(Succeed())
+			events = append(events, event)
+		}
+		return events
+	}
+
+	It("writes an event per line with the duration of what finished", func() {
+		watcher.TableMigrationDidStart("some_table")
+		watcher.TableMigrationDidFinish("some_table", 3)
+
+		Expect(events()).To(HaveLen(2))
+
+		started, finished := events()[0], events()[1]
+		Expect(started).To(HaveKeyWithValue("phase", "migrate"))
+		Expect(started).To(HaveKeyWithValue("event", "started"))
+		Expect(started).To(HaveKeyWithValue("table", "some_table"))
+		Expect(started).To(HaveKey("time"))
+		Expect(started).NotTo(HaveKey("duration_seconds"))
+
+		Expect(finished).To(HaveKeyWithValue("event", "finished"))
+		Expect(finished).To(HaveKeyWithValue("rows_inserted", 3.0))
+		Expect(finished).To(HaveKey("duration_seconds"))
+	})
+
+	It("includes errors", func() {
+		watcher.TableVerificationDidFinishWithError("some_table", errors.New("some-error"))
+
+		Expect(events()[0]).To(HaveKeyWithValue("event", "failed"))
+		Expect(events()[0]).To(HaveKeyWithValue("error", "some-error"))
+	})
+})
@@ -0,0 +1,241 @@
+package pg2mysql
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsWatcher records Prometheus metrics about a run.
+type MetricsWatcher struct {
+	now     func() time.Time
+	started map[string]time.Time
+
+	rowsMigrated     *prometheus.CounterVec
+	rowsFailed       *prometheus.CounterVec
+	bytesTransferred *prometheus.CounterVec
+	currentTable     *prometheus.GaugeVec
+	phase            *prometheus.GaugeVec
+	missingRows      *prometheus.GaugeVec
+	phaseDuration    *prometheus.HistogramVec
+	changesApplied   prometheus.Counter
+	replicationLag   prometheus.Gauge
+}
+
+// NewMetricsWatcher registers the metrics with registerer.
+func NewMetricsWatcher(registerer prometheus.Registerer) (*MetricsWatcher, error) {
+	m := &MetricsWatcher{
+		now:     time.Now,
+		started: map[string]time.Time{},
+
+		rowsMigrated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pg2mysql_rows_migrated_total",
+			Help: "Rows inserted into MySQL.",
+		}, []string{"table"}),
+		rowsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pg2mysql_rows_failed_total",
+			Help: "Rows that failed to insert into MySQL, by MySQL error number (0 for other errors).",
+		}, []string{"table", "error_number"}),
+		bytesTransferred: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "pg2mysql_bytes_transferred_total",
+			Help: "Approximate size of the rows inserted into MySQL.",
+		}, []string{"table"}),
+		currentTable: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pg2mysql_current_table",
+			Help: "1 for the table being migrated, verified, repaired or replayed.",
+		}, []string{"table"}),
+		phase: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pg2mysql_phase",
+			Help: "1 for the phase the run is in.",
+		}, []string{"phase"}),
+		missingRows: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "pg2mysql_verification_missing_rows",
+			Help: "Rows missing from MySQL found by the last verification of each table.",
+		}, []string{"table"}),
+		phaseDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "pg2mysql_phase_duration_seconds",
+			Help:    "How long each phase took, per table where it applies.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 4, 10),
+		}, []string{"phase"}),
+		changesApplied: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "pg2mysql_replication_changes_applied_total",
+			Help: "Changes from the replication slot or the changelog applied to MySQL.",
+		}),
+		replicationLag: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "pg2mysql_replication_lag_bytes",
+			Help: "Bytes of WAL the replication slot has yet to confirm.",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{
+		m.rowsMigrated,
+		m.rowsFailed,
+		m.bytesTransferred,
+		m.currentTable,
+		m.phase,
+		m.missingRows,
+		m.phaseDuration,
+		m.changesApplied,
+		m.replicationLag,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *MetricsWatcher) startPhase(phase, tableName string) {
+	m.phase.Reset()
+	m.phase.WithLabelValues(phase).Set(1)
+
+	if tableName != "" {
+		m.currentTable.Reset()
+		m.currentTable.WithLabelValues(tableName).Set(1)
+	}
+
+	m.started[phase+"/"+tableName] = m.now()
+}
+
+func (m *MetricsWatcher) finishPhase(phase, tableName string) {
+	m.phase.Reset()
+
+	if tableName != "" {
+		m.currentTable.Reset()
+	}
+
+	key := phase + "/" + tableName
+	if started, ok := m.started[key]; ok {
+		m.phaseDuration.WithLabelValues(phase).Observe(m.now().Sub(started).Seconds())
+		delete(m.started, key)
+	}
+}
+
+func (m *MetricsWatcher) ReplicationSlotDidCreate(slotName, lsn string) {}
+
+func (m *MetricsWatcher) ReplicationDidProgress(progress ReplicationProgress) {
+	m.changesApplied.Add(float64(progress.ChangesApplied))
+	m.replicationLag.Set(float64(progress.LagBytes))
+}
+
+func (m *MetricsWatcher) ReplicationDidStop(confirmedLSN string) {}
+
+func (m *MetricsWatcher) CaptureDidInstall(tableNames []string) {}
+
+func (m *MetricsWatcher) CaptureDidApplyChanges(changesApplied int64) {
+	m.changesApplied.Add(float64(changesApplied))
+}
+
+func (m *MetricsWatcher) CaptureDidUninstall(tableNames []string) {}
+
+func (m *MetricsWatcher) TableVerificationDidStart(tableName string) {
+	m.startPhase("verify", tableName)
+}
+
+func (m *MetricsWatcher) TableVerificationDidFinish(tableName string, missingRows int64, missingIDs []string) {
+	m.finishPhase("verify", tableName)
+	m.missingRows.WithLabelValues(tableName).Set(float64(missingRows))
+}
+
+func (m *MetricsWatcher) TableVerificationDidFinishWithError(tableName string, err error) {
+	m.finishPhase("verify", tableName)
+}
+
+func (m *MetricsWatcher) WouldExecuteStatement(tableName string, stmt string) {}
+
+func (m *MetricsWatcher) TableRepairDidStart(tableName string) {
+	m.startPhase("repair", tableName)
+}
+
+func (m *MetricsWatcher) TableRepairDidFinish(tableName string, rowsInserted, rowsUpdated, rowsDeleted int64) {
+	m.finishPhase("repair", tableName)
+}
+
+func (m *MetricsWatcher) TableRepairDidFinishWithError(tableName string, err error) {
+	m.finishPhase("repair", tableName)
+}
+
+func (m *MetricsWatcher) DeadLetterReplayDidStart(tableName string) {
+	m.startPhase("replay", tableName)
+}
+
+func (m *MetricsWatcher) DeadLetterReplayDidFinish(tableName string, rowsReplayed, rowsRemaining int64) {
+	m.finishPhase("replay", tableName)
+}
+
+func (m *MetricsWatcher) DeadLetterReplayDidFinishWithError(tableName string, err error) {
+	m.finishPhase("replay", tableName)
+}
+
+func (m *MetricsWatcher) WillBuildSchema() {
+	m.startPhase("build_schema", "")
+}
+
+func (m *MetricsWatcher) DidBuildSchema() {
+	m.finishPhase("build_schema", "")
+}
+
+func (m *MetricsWatcher) WillDisableConstraints() {
+	m.startPhase("disable_constraints", "")
+}
+
+func (m *MetricsWatcher) DidDisableConstraints() {
+	m.finishPhase("disable_constraints", "")
+}
+
+func (m *MetricsWatcher) WillEnableConstraints() {
+	m.startPhase("enable_constraints", "")
+}
+
+func (m *MetricsWatcher) EnableConstraintsDidFinish() {
+	m.finishPhase("enable_constraints", "")
+}
+
+func (m *MetricsWatcher) EnableConstraintsDidFailWithError(err error) {
+	m.finishPhase("enable_constraints", "")
+}
+
+func (m *MetricsWatcher) WillTruncateTable(tableName string) {
+	m.startPhase("truncate", tableName)
+}
+
+func (m *MetricsWatcher) TruncateTableDidFinish(tableName string) {
+	m.finishPhase("truncate", tableName)
+}
+
+func (m *MetricsWatcher) TableMigrationDidStart(tableName string) {
+	m.startPhase("migrate", tableName)
+}
+
+func (m *MetricsWatcher) TableMigrationDidSkip(tableName string) {}
+
+func (m *MetricsWatcher) TableMigrationDidFinish(tableName string, recordsInserted int64) {
+	m.finishPhase("migrate", tableName)
+}
+
+func (m *MetricsWatcher) WillUpdateDeferredColumns(tableName string, columns []string) {
+	m.startPhase("update_deferred", tableName)
+}
+
+func (m *MetricsWatcher) DeferredColumnsDidUpdate(tableName string, rowsUpdated int64) {
+	m.finishPhase("update_deferred", tableName)
+}
+
+func (m *MetricsWatcher) TableMigrationDidProgress(progress MigrationProgress) {}
+
+func (m *MetricsWatcher) MigrationDidFinishWithFailures(failures *RowFailures) {}
+
+func (m *MetricsWatcher) DidMigrateRow(tableName string) {
+	m.rowsMigrated.WithLabelValues(tableName).Inc()
+}
+
+func (m *MetricsWatcher) DidInsertBytes(tableName string, bytes int64) {
+	m.bytesTransferred.WithLabelValues(tableName).Add(float64(bytes))
+}
+
+func (m *MetricsWatcher) DidFailToMigrateRowWithError(tableName string, err error) {
+	number, _ := mysqlError(err)
+	m.rowsFailed.WithLabelValues(tableName, strconv.Itoa(int(number))).Inc()
+}
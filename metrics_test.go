@@ -0,0 +1,76 @@
+package pg2mysql_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("MetricsWatcher", func() {
+	var (
+		watcher *pg2mysql.MetricsWatcher
+		server  *httptest.Server
+	)
+
+	BeforeEach(func() {
+		registry := prometheus.NewRegistry()
+
+		var err error
+		watcher, err = pg2mysql.NewMetricsWatcher(registry)
+		Expect(err).NotTo(HaveOccurred())
+
+		server = httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	scrape := func() string {
+		resp, err := http.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return string(body)
+	}
+
+	It("exposes the progress of a migration", func() {
+		watcher.TableMigrationDidStart("some_table")
+		watcher.DidMigrateRow("some_table")
+		watcher.DidInsertBytes("some_table", 42)
+		watcher.DidMigrateRow("some_table")
+		watcher.DidInsertBytes("some_table", 8)
+		watcher.DidFailToMigrateRowWithError("some_table", errors.New("some-error"))
+
+		metrics := scrape()
+		Expect(metrics).To(ContainSubstring(`pg2mysql_rows_migrated_total{table="some_table"} 2`))
+		Expect(metrics).To(ContainSubstring(`pg2mysql_bytes_transferred_total{table="some_table"} 50`))
+		Expect(metrics).To(ContainSubstring(`pg2mysql_rows_failed_total{error_number="0",table="some_table"} 1`))
+		Expect(metrics).To(ContainSubstring(`pg2mysql_current_table{table="some_table"} 1`))
+		Expect(metrics).To(ContainSubstring(`pg2mysql_phase{phase="migrate"} 1`))
+
+		watcher.TableMigrationDidFinish("some_table", 2)
+
+		metrics = scrape()
+		Expect(metrics).NotTo(ContainSubstring(`pg2mysql_current_table{`))
+		Expect(metrics).To(ContainSubstring(`pg2mysql_phase_duration_seconds_count{phase="migrate"} 1`))
+	})
+
+	It("exposes verification mismatches", func() {
+		watcher.TableVerificationDidStart("some_table")
+		watcher.TableVerificationDidFinish("some_table", 3, []string{"1", "2", "3"})
+
+		Expect(scrape()).To(ContainSubstring(`pg2mysql_verification_missing_rows{table="some_table"} 3`))
+	})
+})
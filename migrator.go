@@ -1,15 +1,16 @@
 package pg2mysql
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 )
 
 type Migrator interface {
-	Migrate(migrationConfig MigrationConfig) error
+	Migrate(ctx context.Context, migrationConfig MigrationConfig) error
 }
 
 func NewMigrator(src, dst DB, truncateFirst bool, watcher MigratorWatcher) Migrator {
@@ -25,101 +26,507 @@ type migrator struct {
 	src, dst      DB
 	truncateFirst bool
 	watcher       MigratorWatcher
+	deadLetters   *DeadLetterWriter
+	failures      *RowFailures
+	errorBudget   errorBudget
+	progress      *progressTracker
+	writeMode     WriteMode
+
+	// deferredColumns are the indexes of the columns of each table that
+	// are inserted as NULL and filled in once every table is migrated
+	deferredColumns map[string][]int
 }
 
-func (m *migrator) Migrate(migrationConfig MigrationConfig) error {
-	srcSchema, err := BuildSchema(m.src)
+// errorBudget is how many rows may fail to insert before a migration is
+// stopped. Zero means no limit.
+type errorBudget struct {
+	maxErrors         int64
+	maxErrorsPerTable int64
+	failFast          bool
+}
+
+func (m *migrator) Migrate(ctx context.Context, migrationConfig MigrationConfig) error {
+	m.watcher.WillBuildSchema()
+
+	srcSchema, err := BuildSchema(ctx, m.src)
 	if err != nil {
 		return fmt.Errorf("failed to build source schema: %s", err)
 	}
 
+	dstSchema, err := BuildSchema(ctx, m.dst)
+	if err != nil {
+		return fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	m.watcher.DidBuildSchema()
+
+	options, err := conversionOptions(ctx, m.dst, migrationConfig)
+	if err != nil {
+		return err
+	}
+
+	// Array columns stored in child tables aren't columns of their tables
+	// in MySQL; the child tables are filled in after every table
+	var childTables []*arrayChildTable
+	for _, child := range splitArrayColumns(srcSchema, options.Arrays) {
+		if !ignoreTable(child.parent.Name, migrationConfig.IgnoreTables) {
+			childTables = append(childTables, child)
+		}
+	}
+
+	checkpoint := &Checkpoint{}
+	if migrationConfig.CheckpointFile != "" && migrationConfig.Resume {
+		checkpoint, err = ReadCheckpoint(migrationConfig.CheckpointFile)
+		if err != nil {
+			return fmt.Errorf("failed to read checkpoint: %s", err)
+		}
+	}
+
+	defer m.startRun(migrationConfig)()
+
+	var tables []*Table
+	for _, table := range srcSchema.Tables {
+		if !ignoreTable(table.Name, migrationConfig.IgnoreTables) {
+			tables = append(tables, table)
+		}
+	}
+
+	foreignKeys, err := GetForeignKeys(ctx, m.src)
+	if err != nil {
+		return fmt.Errorf("failed to get foreign keys from source: %s", err)
+	}
+
+	tables, deferredKeys := OrderTables(tables, foreignKeys)
+
+	// With foreign key checks off the order is enough; otherwise columns
+	// that reference rows that may not have been migrated yet are deferred
+	enforceForeignKeys := migrationConfig.EnforceForeignKeys
+	m.deferredColumns = map[string][]int{}
+	if !enforceForeignKeys {
+		deferredKeys = nil
+	}
+	for _, fk := range deferredKeys {
+		for _, column := range fk.Columns {
+			index, _, err := srcSchema.Tables[fk.Table].GetColumn(column)
+			if err != nil {
+				return err
+			}
+			m.deferredColumns[fk.Table] = append(m.deferredColumns[fk.Table], index)
+		}
+	}
+
+	var pending []*Table
+	var tableTotals []int64
+	var total int64
+	for _, table := range tables {
+		if checkpoint.Completed(table.Name) {
+			m.watcher.TableMigrationDidSkip(table.Name)
+			continue
+		}
+
+		tableTotal, err := m.src.EstimateRowCount(ctx, table.Name)
+		if err != nil {
+			return fmt.Errorf("failed to estimate rows in %s: %s", table.Name, err)
+		}
+
+		pending = append(pending, table)
+		tableTotals = append(tableTotals, tableTotal)
+		total += tableTotal
+	}
+
+	m.progress = newProgressTracker(m.watcher, migrationConfig.ProgressInterval, total)
+
+	// Child tables are rebuilt on every run, and emptied first as their
+	// rows reference the rows of their parent tables
+	if err := m.emptyArrayChildTables(ctx, childTables); err != nil {
+		return err
+	}
+
+	if enforceForeignKeys {
+		// TRUNCATE isn't allowed on tables that are referenced by foreign
+		// keys, so they are emptied up front, referencing tables first
+		if m.truncateFirst {
+			if err := m.emptyTables(ctx, pending, deferredKeys); err != nil {
+				return err
+			}
+		}
+	} else {
+		enableConstraints, err := m.disableConstraints(ctx)
+		if err != nil {
+			return err
+		}
+		defer enableConstraints()
+	}
+
+	defer func() {
+		if m.failures.Total > 0 {
+			m.watcher.MigrationDidFinishWithFailures(m.failures)
+		}
+	}()
+
+	for i, table := range pending {
+		if ctx.Err() != nil {
+			return m.interrupted(migrationConfig.CheckpointFile, checkpoint, "")
+		}
+
+		err := m.migrateTable(ctx, table, dstSchema, options, tableTotals[i], !enforceForeignKeys && m.truncateFirst)
+		if err != nil {
+			if ctx.Err() != nil {
+				return m.interrupted(migrationConfig.CheckpointFile, checkpoint, table.Name)
+			}
+			return err
+		}
+
+		checkpoint.CompletedTables = append(checkpoint.CompletedTables, table.Name)
+	}
+
+	// Deferred columns are filled in for every table, including those
+	// finished before a checkpoint, since the run that finished them may
+	// have been interrupted before getting here
+	for _, fk := range deferredKeys {
+		err := m.updateDeferredColumns(ctx, srcSchema.Tables[fk.Table], dstSchema, options, fk)
+		if err != nil {
+			if ctx.Err() != nil {
+				return m.interrupted(migrationConfig.CheckpointFile, checkpoint, "")
+			}
+			return err
+		}
+	}
+
+	for _, child := range childTables {
+		err := m.migrateArrayChildTable(ctx, child)
+		if err != nil {
+			if ctx.Err() != nil {
+				return m.interrupted(migrationConfig.CheckpointFile, checkpoint, "")
+			}
+			return err
+		}
+	}
+
+	if migrationConfig.CheckpointFile != "" {
+		if err := RemoveCheckpoint(migrationConfig.CheckpointFile); err != nil {
+			return fmt.Errorf("failed to remove checkpoint: %s", err)
+		}
+	}
+
+	if m.failures.Total > 0 {
+		return &RowFailuresError{Failures: m.failures}
+	}
+
+	return nil
+}
+
+// startRun resets the state rows are written with for a run of the migrator.
+// The returned function closes the dead letter files once the run is over.
+func (m *migrator) startRun(migrationConfig MigrationConfig) func() {
+	m.writeMode = migrationConfig.WriteMode
+	m.failures = NewRowFailures()
+	m.errorBudget = errorBudget{
+		maxErrors:         migrationConfig.MaxErrors,
+		maxErrorsPerTable: migrationConfig.MaxErrorsPerTable,
+		failFast:          migrationConfig.FailFast,
+	}
+
+	if migrationConfig.DeadLetterDir == "" {
+		return func() {}
+	}
+
+	m.deadLetters = NewDeadLetterWriter(migrationConfig.DeadLetterDir)
+	return func() {
+		m.deadLetters.Close()
+		m.deadLetters = nil
+	}
+}
+
+// disableConstraints disables the constraints of the destination. The
+// returned function enables them again, even when ctx has been cancelled.
+func (m *migrator) disableConstraints(ctx context.Context) (func(), error) {
 	m.watcher.WillDisableConstraints()
-	err = m.dst.DisableConstraints()
+	err := m.dst.DisableConstraints(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to disable constraints: %s", err)
+		return nil, fmt.Errorf("failed to disable constraints: %s", err)
 	}
 	m.watcher.DidDisableConstraints()
 
-	defer func() {
+	return func() {
 		m.watcher.WillEnableConstraints()
-		err = m.dst.EnableConstraints()
+		err := m.dst.EnableConstraints(context.Background())
 		if err != nil {
 			m.watcher.EnableConstraintsDidFailWithError(err)
 		} else {
 			m.watcher.EnableConstraintsDidFinish()
 		}
-	}()
+	}, nil
+}
 
-	for _, table := range srcSchema.Tables {
-		if ignoreTable(table.Name, migrationConfig.IgnoreTables) {
-			continue
+func (m *migrator) migrateTable(ctx context.Context, table *Table, dstSchema *Schema, options ConversionOptions, tableTotal int64, truncate bool) error {
+	primaryKey, err := m.src.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+
+	dstTable, _ := dstSchema.GetTable(table.Name)
+	converter := NewRowConverter(table, dstTable, options)
+
+	query, err := m.writeMode.statement(table, primaryKey, converter)
+	if err != nil {
+		return err
+	}
+
+	if truncate {
+		m.watcher.WillTruncateTable(table.Name)
+		_, err = m.dst.DB().ExecContext(ctx, fmt.Sprintf("TRUNCATE TABLE %s", table.Name))
+		if err != nil {
+			return fmt.Errorf("failed truncating: %s", err)
 		}
+		m.watcher.TruncateTableDidFinish(table.Name)
+	}
 
-		if m.truncateFirst {
-			m.watcher.WillTruncateTable(table.Name)
-			_, err := m.dst.DB().Exec(fmt.Sprintf("TRUNCATE TABLE %s", table.Name))
+	preparedStmt, err := m.dst.DB().PrepareContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed creating prepared statement: %s", err)
+	}
+	defer preparedStmt.Close()
+
+	var recordsInserted int64
+
+	m.watcher.TableMigrationDidStart(table.Name)
+	m.progress.startTable(table.Name, tableTotal)
+
+	// Composite keys can't be matched against the destination with a
+	// single NOT IN, so those tables are compared row by row instead, as
+	// are all tables when rows that changed are to be written again
+	if len(primaryKey) == 1 && !m.writeMode.rewritesRows() {
+		err = m.migrateWithPrimaryKeys(ctx, table, primaryKey[0], converter, &recordsInserted, preparedStmt)
+		if err != nil {
+			return fmt.Errorf("failed migrating table with ids: %s", err)
+		}
+	} else {
+		err = EachMissingRow(ctx, m.src, m.dst, table, converter, func(values []interface{}) error {
+			written := m.deferColumns(table, values)
+			err := converter.Check(written)
+			if err == nil {
+				err = m.write(preparedStmt, written)
+			}
 			if err != nil {
-				return fmt.Errorf("failed truncating: %s", err)
+				return m.rowFailed(table, values, err)
 			}
-			m.watcher.TruncateTableDidFinish(table.Name)
+			m.rowMigrated(table, written)
+			recordsInserted++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed migrating table without ids: %s", err)
+		}
+	}
+
+	m.progress.finishTable()
+	m.watcher.TableMigrationDidFinish(table.Name, recordsInserted)
+
+	return nil
+}
+
+// deferColumns returns the row of table to write first: a copy of values
+// with the deferred columns set to NULL. The values themselves are left
+// alone, so that a row that fails is dead-lettered in full.
+func (m *migrator) deferColumns(table *Table, values []interface{}) []interface{} {
+	indexes := m.deferredColumns[table.Name]
+	if len(indexes) == 0 {
+		return values
+	}
+
+	deferred := make([]interface{}, len(values))
+	copy(deferred, values)
+	for _, index := range indexes {
+		deferred[index] = nil
+	}
+	return deferred
+}
+
+// emptyTables deletes the rows of tables in reverse order, so that rows are
+// deleted before the rows they reference. Deferred columns are cleared
+// first, as rows of a table may reference each other.
+func (m *migrator) emptyTables(ctx context.Context, tables []*Table, deferredKeys []ForeignKey) error {
+	for _, fk := range deferredKeys {
+		assignments := make([]string, len(fk.Columns))
+		for i, column := range fk.Columns {
+			assignments[i] = fmt.Sprintf("`%s` = NULL", column)
 		}
 
-		columnNamesForInsert := make([]string, len(table.Columns))
-		placeholders := make([]string, len(table.Columns))
-		for i := range table.Columns {
-			columnNamesForInsert[i] = fmt.Sprintf("`%s`", table.Columns[i].Name)
-			placeholders[i] = "?"
+		_, err := m.dst.DB().ExecContext(ctx, fmt.Sprintf("UPDATE `%s` SET %s", fk.Table, strings.Join(assignments, ",")))
+		if err != nil {
+			return fmt.Errorf("failed clearing deferred columns of %s: %s", fk.Table, err)
 		}
+	}
 
-		preparedStmt, err := m.dst.DB().Prepare(fmt.Sprintf(
-			"INSERT INTO `%s` (%s) VALUES (%s)",
-			table.Name,
-			strings.Join(columnNamesForInsert, ","),
-			strings.Join(placeholders, ","),
-		))
+	for i := len(tables) - 1; i >= 0; i-- {
+		m.watcher.WillTruncateTable(tables[i].Name)
+		_, err := m.dst.DB().ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s`", tables[i].Name))
+		if err != nil {
+			return fmt.Errorf("failed truncating: %s", err)
+		}
+		m.watcher.TruncateTableDidFinish(tables[i].Name)
+	}
+
+	return nil
+}
 
+// emptyArrayChildTables deletes the rows of the child tables of array
+// columns.
+func (m *migrator) emptyArrayChildTables(ctx context.Context, childTables []*arrayChildTable) error {
+	for _, child := range childTables {
+		m.watcher.WillTruncateTable(child.name())
+		_, err := m.dst.DB().ExecContext(ctx, fmt.Sprintf("DELETE FROM `%s`", child.name()))
 		if err != nil {
-			return fmt.Errorf("failed creating prepared statement: %s", err)
+			return fmt.Errorf("failed truncating: %s", err)
+		}
+		m.watcher.TruncateTableDidFinish(child.name())
+	}
+
+	return nil
+}
+
+// migrateArrayChildTable fills the child table of an array column with the
+// elements of the arrays of every row of its parent table.
+func (m *migrator) migrateArrayChildTable(ctx context.Context, child *arrayChildTable) error {
+	primaryKey, err := m.src.GetPrimaryKey(ctx, child.parent.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+
+	preparedStmt, err := m.dst.DB().PrepareContext(ctx, child.insertStatement(primaryKey))
+	if err != nil {
+		return fmt.Errorf("failed creating prepared statement: %s", err)
+	}
+	defer preparedStmt.Close()
+
+	var recordsInserted int64
+
+	m.watcher.TableMigrationDidStart(child.name())
+
+	err = child.eachRow(ctx, m.src, primaryKey, func(row []interface{}) error {
+		if _, err := preparedStmt.ExecContext(ctx, row...); err != nil {
+			return fmt.Errorf("failed inserting into %s: %s", child.name(), err)
 		}
+		recordsInserted++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed migrating array column %s.%s: %s", child.parent.Name, child.column.Name, err)
+	}
+
+	m.watcher.TableMigrationDidFinish(child.name(), recordsInserted)
+
+	return nil
+}
 
-		var recordsInserted int64
+// updateDeferredColumns copies the columns of a deferred foreign key from
+// every row of table in PostgreSQL to the same row in MySQL, by primary key.
+func (m *migrator) updateDeferredColumns(ctx context.Context, table *Table, dstSchema *Schema, options ConversionOptions, fk ForeignKey) error {
+	primaryKey, err := m.src.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+	if len(primaryKey) == 0 {
+		return fmt.Errorf("cannot defer foreign key %s of table '%s' without a primary key", fk.Name, table.Name)
+	}
 
-		m.watcher.TableMigrationDidStart(table.Name)
+	m.watcher.WillUpdateDeferredColumns(table.Name, fk.Columns)
 
-		hasSrcPrimaryKey, err := m.src.HasPrimaryKey(table.Name)
+	// The selected columns are converted like the rest of their rows
+	selected := &Table{Name: table.Name}
+	srcColumns := make([]string, 0, len(fk.Columns)+len(primaryKey))
+	conditions := make([]string, len(fk.Columns))
+	assignments := make([]string, len(fk.Columns))
+	for i, column := range append(append([]string{}, fk.Columns...), primaryKey...) {
+		_, srcColumn, err := table.GetColumn(column)
 		if err != nil {
-			return fmt.Errorf("failed to get primary key from source table: %s", err)
+			return err
 		}
-		if hasSrcPrimaryKey {
-			err = migrateWithPrimaryKeys(m.watcher, m.src, m.dst, table, &recordsInserted, preparedStmt)
-			if err != nil {
-				return fmt.Errorf("failed migrating table with ids: %s", err)
-			}
-		} else {
-			err = EachMissingRow(m.src, m.dst, table, func(scanArgs []interface{}) {
-				err = insert(preparedStmt, scanArgs)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "failed to insert into %s: %s\n", table.Name, err)
-					return
-				}
-				recordsInserted++
-			})
-			if err != nil {
-				return fmt.Errorf("failed migrating table without ids: %s", err)
-			}
+		selected.Columns = append(selected.Columns, srcColumn)
+		srcColumns = append(srcColumns, m.src.ColumnNameForSelect(column))
+
+		if i < len(fk.Columns) {
+			conditions[i] = fmt.Sprintf("%s IS NOT NULL", m.src.ColumnNameForSelect(column))
+			assignments[i] = fmt.Sprintf("`%s` = ?", column)
 		}
+	}
+
+	dstTable, _ := dstSchema.GetTable(table.Name)
+	converter := NewRowConverter(selected, dstTable, options)
 
-		m.watcher.TableMigrationDidFinish(table.Name, recordsInserted)
+	stmt, err := m.dst.DB().PrepareContext(ctx, fmt.Sprintf(
+		"UPDATE `%s` SET %s WHERE %s",
+		table.Name,
+		strings.Join(assignments, ","),
+		keyCondition(m.dst, primaryKey, "=", "?"),
+	))
+	if err != nil {
+		return fmt.Errorf("failed creating prepared statement: %s", err)
+	}
+	defer stmt.Close()
+
+	rows, err := m.src.DB().QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s FROM \"%s\" WHERE %s",
+		strings.Join(srcColumns, ","),
+		table.Name,
+		strings.Join(conditions, " AND "),
+	))
+	if err != nil {
+		return fmt.Errorf("failed to select deferred columns: %s", err)
 	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(srcColumns))
+	scanArgs := make([]interface{}, len(srcColumns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+
+	var rowsUpdated int64
+	for rows.Next() {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		// Like inserts, updates are finished even when the run is
+		// interrupted
+		if _, err = stmt.Exec(converter.Convert(values)...); err != nil {
+			return fmt.Errorf("failed updating deferred columns of %s: %s", table.Name, err)
+		}
+		rowsUpdated++
+	}
+
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed iterating through rows: %s", err)
+	}
+
+	m.watcher.DeferredColumnsDidUpdate(table.Name, rowsUpdated)
 
 	return nil
 }
 
-func migrateWithPrimaryKeys(
-	watcher MigratorWatcher,
-	src DB,
-	dst DB,
+// interrupted saves the checkpoint of a migration stopped by its context,
+// if it has a checkpoint file, and returns the error to stop it with.
+func (m *migrator) interrupted(checkpointFile string, checkpoint *Checkpoint, tableName string) error {
+	if checkpointFile == "" {
+		return errors.New("interrupted")
+	}
+
+	checkpoint.InterruptedTable = tableName
+	checkpoint.InterruptedAt = time.Now().UTC()
+	if err := WriteCheckpoint(checkpointFile, checkpoint); err != nil {
+		return fmt.Errorf("interrupted, and failed to write checkpoint: %s", err)
+	}
+
+	return fmt.Errorf("interrupted; progress saved to %s, migrate again with --resume to resume", checkpointFile)
+}
+
+func (m *migrator) migrateWithPrimaryKeys(
+	ctx context.Context,
 	table *Table,
+	primaryKey string,
+	converter *RowConverter,
 	recordsInserted *int64,
 	preparedStmt *sql.Stmt,
 ) error {
@@ -127,17 +534,17 @@ func migrateWithPrimaryKeys(
 	values := make([]interface{}, len(table.Columns))
 	scanArgs := make([]interface{}, len(table.Columns))
 	for i := range table.Columns {
-		columnNamesForSelect[i] = fmt.Sprintf("\"%s\"", table.Columns[i].Name)
+		columnNamesForSelect[i] = selectExpression(fmt.Sprintf("\"%s\"", table.Columns[i].Name), table.Columns[i])
 		scanArgs[i] = &values[i]
 	}
 
-	// find ids already in dst
-	primaryKey, err := src.GetPrimaryKey(table.Name)
+	keyIndex, _, err := table.GetColumn(primaryKey)
 	if err != nil {
 		return err
 	}
 
-	rows, err := dst.DB().Query(fmt.Sprintf("SELECT `%s` FROM `%s`", primaryKey, table.Name))
+	// find ids already in dst
+	rows, err := m.dst.DB().QueryContext(ctx, fmt.Sprintf("SELECT `%s` FROM `%s`", primaryKey, table.Name))
 	if err != nil {
 		return fmt.Errorf("failed to select primary key from rows: %s", err)
 	}
@@ -148,7 +555,7 @@ func migrateWithPrimaryKeys(
 		if err = rows.Scan(&id); err != nil {
 			return fmt.Errorf("failed to scan id from row: %s", err)
 		}
-		dstIDs = append(dstIDs, id)
+		dstIDs = append(dstIDs, converter.Reverse(keyIndex, id))
 	}
 
 	if err = rows.Err(); err != nil {
@@ -175,7 +582,7 @@ func migrateWithPrimaryKeys(
 		)
 	`, strings.Join(columnNamesForSelect, ","), table.Name, primaryKey, strings.Join(placeholders, ","))
 
-	rows, err = src.DB().Query(stmt, dstIDs...)
+	rows, err = m.src.DB().QueryContext(ctx, stmt, dstIDs...)
 	if err != nil {
 		return fmt.Errorf("failed to select rows: %s", err)
 	}
@@ -185,12 +592,20 @@ func migrateWithPrimaryKeys(
 			return fmt.Errorf("failed to scan row: %s", err)
 		}
 
-		err = insert(preparedStmt, scanArgs)
+		args := converter.Convert(values)
+		written := m.deferColumns(table, args)
+		err = converter.Check(written)
+		if err == nil {
+			err = m.write(preparedStmt, written)
+		}
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed to insert into %s: %s\n", table.Name, err)
+			if err = m.rowFailed(table, args, err); err != nil {
+				return err
+			}
 			continue
 		}
 
+		m.rowMigrated(table, written)
 		*recordsInserted++
 	}
 
@@ -205,10 +620,101 @@ func migrateWithPrimaryKeys(
 	return nil
 }
 
+// insertStatement builds the statement used to insert a full row of table
+// into MySQL, with one placeholder per column, those of converter.
+func insertStatement(table *Table, converter *RowConverter) string {
+	columnNamesForInsert := make([]string, len(table.Columns))
+	placeholders := make([]string, len(table.Columns))
+	for i := range table.Columns {
+		columnNamesForInsert[i] = fmt.Sprintf("`%s`", table.Columns[i].Name)
+		placeholders[i] = converter.WritePlaceholder(i)
+	}
+
+	return fmt.Sprintf(
+		"INSERT INTO `%s` (%s) VALUES (%s)",
+		table.Name,
+		strings.Join(columnNamesForInsert, ","),
+		strings.Join(placeholders, ","),
+	)
+}
+
+func (m *migrator) rowMigrated(table *Table, values []interface{}) {
+	m.watcher.DidMigrateRow(table.Name)
+	m.watcher.DidInsertBytes(table.Name, rowSize(values))
+	m.progress.rowDone()
+}
+
+// rowSize is roughly how many bytes a row takes to send to MySQL: the length
+// of text and binary values, and 8 bytes for everything else.
+func rowSize(values []interface{}) int64 {
+	var size int64
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+		case []byte:
+			size += int64(len(v))
+		case string:
+			size += int64(len(v))
+		default:
+			size += 8
+		}
+	}
+
+	return size
+}
+
+// rowFailed reports a row that could not be inserted and records it in the
+// dead-letter file of its table, if there is one. It returns an error once
+// the error budget is spent.
+func (m *migrator) rowFailed(table *Table, values []interface{}, err error) error {
+	m.watcher.DidFailToMigrateRowWithError(table.Name, err)
+	m.progress.rowDone()
+	m.failures.Add(table.Name, err)
+
+	if m.deadLetters != nil {
+		if err := m.deadLetters.Write(table, values, err); err != nil {
+			return fmt.Errorf("failed writing dead letter: %s", err)
+		}
+	}
+
+	switch {
+	case m.errorBudget.failFast:
+		return &RowFailuresError{Failures: m.failures, Reason: "stopped"}
+	case m.errorBudget.maxErrors > 0 && m.failures.Total > m.errorBudget.maxErrors:
+		return &RowFailuresError{
+			Failures: m.failures,
+			Reason:   fmt.Sprintf("stopped after exceeding --max-errors of %d", m.errorBudget.maxErrors),
+		}
+	case m.errorBudget.maxErrorsPerTable > 0 && m.failures.ByTable[table.Name] > m.errorBudget.maxErrorsPerTable:
+		return &RowFailuresError{
+			Failures: m.failures,
+			Reason:   fmt.Sprintf("stopped after exceeding --max-errors-per-table of %d in %s", m.errorBudget.maxErrorsPerTable, table.Name),
+		}
+	}
+
+	return nil
+}
+
+// write writes a row with the statement of the write mode. Rows that
+// INSERT IGNORE skips, or that an upsert leaves unchanged, affect no rows.
+func (m *migrator) write(stmt *sql.Stmt, values []interface{}) error {
+	if m.writeMode == "" || m.writeMode == WriteModeInsert {
+		return insert(stmt, values)
+	}
+
+	if _, err := stmt.Exec(values...); err != nil {
+		return fmt.Errorf("failed to exec stmt: %w", err)
+	}
+
+	return nil
+}
+
+// insert isn't given the context of the run, so that a row being written
+// when the run is interrupted is written in full.
 func insert(stmt *sql.Stmt, values []interface{}) error {
 	result, err := stmt.Exec(values...)
 	if err != nil {
-		return fmt.Errorf("failed to exec stmt: %s", err)
+		return fmt.Errorf("failed to exec stmt: %w", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
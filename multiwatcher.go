@@ -0,0 +1,227 @@
+package pg2mysql
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// MultiWatcher passes every event on to each of its watchers, in order. A
+// watcher only gets the events of the watcher interfaces it implements, such
+// as MigratorWatcher or VerifierWatcher. A watcher that panics is reported
+// and doesn't stop the others from being notified, nor the run.
+type MultiWatcher struct {
+	watchers []interface{}
+	stderr   io.Writer
+}
+
+func NewMultiWatcher(watchers ...interface{}) *MultiWatcher {
+	return &MultiWatcher{
+		watchers: watchers,
+		stderr:   os.Stderr,
+	}
+}
+
+func (m *MultiWatcher) each(event string, notify func(interface{})) {
+	for _, watcher := range m.watchers {
+		m.notify(watcher, event, notify)
+	}
+}
+
+func (m *MultiWatcher) notify(watcher interface{}, event string, notify func(interface{})) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(m.stderr, "watcher %T panicked on %s: %v\n", watcher, event, r)
+		}
+	}()
+
+	notify(watcher)
+}
+
+func (m *MultiWatcher) eachMigrator(event string, notify func(MigratorWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(MigratorWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) eachVerifier(event string, notify func(VerifierWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(VerifierWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) eachRepairer(event string, notify func(RepairerWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(RepairerWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) eachReplayer(event string, notify func(ReplayerWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(ReplayerWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) eachReplicator(event string, notify func(ReplicatorWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(ReplicatorWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) eachCapturer(event string, notify func(CapturerWatcher)) {
+	m.each(event, func(watcher interface{}) {
+		if w, ok := watcher.(CapturerWatcher); ok {
+			notify(w)
+		}
+	})
+}
+
+func (m *MultiWatcher) WillBuildSchema() {
+	m.eachMigrator("WillBuildSchema", func(w MigratorWatcher) { w.WillBuildSchema() })
+}
+
+func (m *MultiWatcher) DidBuildSchema() {
+	m.eachMigrator("DidBuildSchema", func(w MigratorWatcher) { w.DidBuildSchema() })
+}
+
+func (m *MultiWatcher) WillDisableConstraints() {
+	m.eachMigrator("WillDisableConstraints", func(w MigratorWatcher) { w.WillDisableConstraints() })
+}
+
+func (m *MultiWatcher) DidDisableConstraints() {
+	m.eachMigrator("DidDisableConstraints", func(w MigratorWatcher) { w.DidDisableConstraints() })
+}
+
+func (m *MultiWatcher) WillEnableConstraints() {
+	m.eachMigrator("WillEnableConstraints", func(w MigratorWatcher) { w.WillEnableConstraints() })
+}
+
+func (m *MultiWatcher) EnableConstraintsDidFinish() {
+	m.eachMigrator("EnableConstraintsDidFinish", func(w MigratorWatcher) { w.EnableConstraintsDidFinish() })
+}
+
+func (m *MultiWatcher) EnableConstraintsDidFailWithError(err error) {
+	m.eachMigrator("EnableConstraintsDidFailWithError", func(w MigratorWatcher) { w.EnableConstraintsDidFailWithError(err) })
+}
+
+func (m *MultiWatcher) WillTruncateTable(tableName string) {
+	m.eachMigrator("WillTruncateTable", func(w MigratorWatcher) { w.WillTruncateTable(tableName) })
+}
+
+func (m *MultiWatcher) TruncateTableDidFinish(tableName string) {
+	m.eachMigrator("TruncateTableDidFinish", func(w MigratorWatcher) { w.TruncateTableDidFinish(tableName) })
+}
+
+func (m *MultiWatcher) TableMigrationDidStart(tableName string) {
+	m.eachMigrator("TableMigrationDidStart", func(w MigratorWatcher) { w.TableMigrationDidStart(tableName) })
+}
+
+func (m *MultiWatcher) TableMigrationDidSkip(tableName string) {
+	m.eachMigrator("TableMigrationDidSkip", func(w MigratorWatcher) { w.TableMigrationDidSkip(tableName) })
+}
+
+func (m *MultiWatcher) TableMigrationDidFinish(tableName string, recordsInserted int64) {
+	m.eachMigrator("TableMigrationDidFinish", func(w MigratorWatcher) { w.TableMigrationDidFinish(tableName, recordsInserted) })
+}
+
+func (m *MultiWatcher) WillUpdateDeferredColumns(tableName string, columns []string) {
+	m.eachMigrator("WillUpdateDeferredColumns", func(w MigratorWatcher) { w.WillUpdateDeferredColumns(tableName, columns) })
+}
+
+func (m *MultiWatcher) DeferredColumnsDidUpdate(tableName string, rowsUpdated int64) {
+	m.eachMigrator("DeferredColumnsDidUpdate", func(w MigratorWatcher) { w.DeferredColumnsDidUpdate(tableName, rowsUpdated) })
+}
+
+func (m *MultiWatcher) TableMigrationDidProgress(progress MigrationProgress) {
+	m.eachMigrator("TableMigrationDidProgress", func(w MigratorWatcher) { w.TableMigrationDidProgress(progress) })
+}
+
+func (m *MultiWatcher) DidMigrateRow(tableName string) {
+	m.eachMigrator("DidMigrateRow", func(w MigratorWatcher) { w.DidMigrateRow(tableName) })
+}
+
+func (m *MultiWatcher) DidInsertBytes(tableName string, bytes int64) {
+	m.eachMigrator("DidInsertBytes", func(w MigratorWatcher) { w.DidInsertBytes(tableName, bytes) })
+}
+
+func (m *MultiWatcher) DidFailToMigrateRowWithError(tableName string, err error) {
+	m.eachMigrator("DidFailToMigrateRowWithError", func(w MigratorWatcher) { w.DidFailToMigrateRowWithError(tableName, err) })
+}
+
+func (m *MultiWatcher) MigrationDidFinishWithFailures(failures *RowFailures) {
+	m.eachMigrator("MigrationDidFinishWithFailures", func(w MigratorWatcher) { w.MigrationDidFinishWithFailures(failures) })
+}
+
+func (m *MultiWatcher) TableVerificationDidStart(tableName string) {
+	m.eachVerifier("TableVerificationDidStart", func(w VerifierWatcher) { w.TableVerificationDidStart(tableName) })
+}
+
+func (m *MultiWatcher) TableVerificationDidFinish(tableName string, missingRows int64, missingIDs []string) {
+	m.eachVerifier("TableVerificationDidFinish", func(w VerifierWatcher) { w.TableVerificationDidFinish(tableName, missingRows, missingIDs) })
+}
+
+func (m *MultiWatcher) TableVerificationDidFinishWithError(tableName string, err error) {
+	m.eachVerifier("TableVerificationDidFinishWithError", func(w VerifierWatcher) { w.TableVerificationDidFinishWithError(tableName, err) })
+}
+
+func (m *MultiWatcher) TableRepairDidStart(tableName string) {
+	m.eachRepairer("TableRepairDidStart", func(w RepairerWatcher) { w.TableRepairDidStart(tableName) })
+}
+
+func (m *MultiWatcher) TableRepairDidFinish(tableName string, rowsInserted, rowsUpdated, rowsDeleted int64) {
+	m.eachRepairer("TableRepairDidFinish", func(w RepairerWatcher) { w.TableRepairDidFinish(tableName, rowsInserted, rowsUpdated, rowsDeleted) })
+}
+
+func (m *MultiWatcher) TableRepairDidFinishWithError(tableName string, err error) {
+	m.eachRepairer("TableRepairDidFinishWithError", func(w RepairerWatcher) { w.TableRepairDidFinishWithError(tableName, err) })
+}
+
+func (m *MultiWatcher) WouldExecuteStatement(tableName string, stmt string) {
+	m.eachRepairer("WouldExecuteStatement", func(w RepairerWatcher) { w.WouldExecuteStatement(tableName, stmt) })
+}
+
+func (m *MultiWatcher) ReplicationSlotDidCreate(slotName, lsn string) {
+	m.eachReplicator("ReplicationSlotDidCreate", func(w ReplicatorWatcher) { w.ReplicationSlotDidCreate(slotName, lsn) })
+}
+
+func (m *MultiWatcher) ReplicationDidProgress(progress ReplicationProgress) {
+	m.eachReplicator("ReplicationDidProgress", func(w ReplicatorWatcher) { w.ReplicationDidProgress(progress) })
+}
+
+func (m *MultiWatcher) ReplicationDidStop(confirmedLSN string) {
+	m.eachReplicator("ReplicationDidStop", func(w ReplicatorWatcher) { w.ReplicationDidStop(confirmedLSN) })
+}
+
+func (m *MultiWatcher) CaptureDidInstall(tableNames []string) {
+	m.eachCapturer("CaptureDidInstall", func(w CapturerWatcher) { w.CaptureDidInstall(tableNames) })
+}
+
+func (m *MultiWatcher) CaptureDidApplyChanges(changesApplied int64) {
+	m.eachCapturer("CaptureDidApplyChanges", func(w CapturerWatcher) { w.CaptureDidApplyChanges(changesApplied) })
+}
+
+func (m *MultiWatcher) CaptureDidUninstall(tableNames []string) {
+	m.eachCapturer("CaptureDidUninstall", func(w CapturerWatcher) { w.CaptureDidUninstall(tableNames) })
+}
+
+func (m *MultiWatcher) DeadLetterReplayDidStart(tableName string) {
+	m.eachReplayer("DeadLetterReplayDidStart", func(w ReplayerWatcher) { w.DeadLetterReplayDidStart(tableName) })
+}
+
+func (m *MultiWatcher) DeadLetterReplayDidFinish(tableName string, rowsReplayed, rowsRemaining int64) {
+	m.eachReplayer("DeadLetterReplayDidFinish", func(w ReplayerWatcher) { w.DeadLetterReplayDidFinish(tableName, rowsReplayed, rowsRemaining) })
+}
+
+func (m *MultiWatcher) DeadLetterReplayDidFinishWithError(tableName string, err error) {
+	m.eachReplayer("DeadLetterReplayDidFinishWithError", func(w ReplayerWatcher) { w.DeadLetterReplayDidFinishWithError(tableName, err) })
+}
@@ -0,0 +1,70 @@
+package pg2mysql_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+type panickingWatcher struct {
+	pg2mysql.Watcher
+}
+
+func (p *panickingWatcher) TableMigrationDidStart(tableName string) {
+	panic("some-panic")
+}
+
+var _ = Describe("MultiWatcher", func() {
+	var (
+		first, second *bytes.Buffer
+		watcher       *pg2mysql.MultiWatcher
+	)
+
+	BeforeEach(func() {
+		first = &bytes.Buffer{}
+		second = &bytes.Buffer{}
+
+		watcher = pg2mysql.NewMultiWatcher(
+			pg2mysql.NewJSONLinesWatcher(first),
+			&panickingWatcher{},
+			pg2mysql.NewJSONLinesWatcher(second),
+		)
+	})
+
+	It("notifies every watcher", func() {
+		watcher.TableVerificationDidFinish("some_table", 1, []string{"1"})
+
+		Expect(first.String()).To(ContainSubstring(`"missing_rows":1`))
+		Expect(second.String()).To(ContainSubstring(`"missing_rows":1`))
+	})
+
+	It("only passes on the events of the watcher interfaces each implements", func() {
+		migratorWatcher := &pg2mysqlfakes.FakeMigratorWatcher{}
+		verifierWatcher := &pg2mysqlfakes.FakeVerifierWatcher{}
+		watcher = pg2mysql.NewMultiWatcher(migratorWatcher, verifierWatcher, pg2mysql.NewJSONLinesWatcher(first))
+
+		watcher.TableMigrationDidStart("some_table")
+		watcher.TableVerificationDidFinish("some_table", 1, []string{"1"})
+		watcher.ReplicationDidStop("0/16B3748")
+
+		Expect(migratorWatcher.TableMigrationDidStartCallCount()).To(Equal(1))
+		Expect(migratorWatcher.TableMigrationDidStartArgsForCall(0)).To(Equal("some_table"))
+		Expect(verifierWatcher.TableVerificationDidFinishCallCount()).To(Equal(1))
+		Expect(verifierWatcher.TableVerificationDidStartCallCount()).To(BeZero())
+		Expect(first.String()).To(ContainSubstring(`"table":"some_table"`))
+		Expect(first.String()).To(ContainSubstring(`"missing_rows":1`))
+	})
+
+	It("keeps notifying the others when one panics", func() {
+		Expect(func() {
+			watcher.TableMigrationDidStart("some_table")
+		}).NotTo(Panic())
+
+		Expect(first.String()).To(ContainSubstring(`"table":"some_table"`))
+		Expect(second.String()).To(ContainSubstring(`"table":"some_table"`))
+	})
+})
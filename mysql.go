@@ -2,10 +2,14 @@ package pg2mysql
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"net/url"
 	"sort"
+	"sync"
 
 	"github.com/go-sql-driver/mysql"
 )
@@ -17,14 +21,31 @@ func NewMySQLDB(
 	host string,
 	port int,
 	params map[string]string,
+	timezone string,
 ) DB {
-	if params == nil {
-		params = make(map[string]string)
+	// The params are copied, as callers may share them between databases
+	copied := make(map[string]string, len(params))
+	for name, value := range params {
+		copied[name] = value
 	}
+	params = copied
+
 	params["parseTime"] = "true"
 	params["charset"] = "utf8"
 	params["multiStatements"] = "true"
 
+	// Read and write times in the destination zone, and have the server
+	// convert TIMESTAMP columns from that same zone rather than its own. A
+	// named zone is sent as it is, rather than as its current offset, so
+	// that the server follows its daylight saving time
+	if timezone == "" || timezone == "UTC" {
+		params["loc"] = "UTC"
+		params["time_zone"] = "'+00:00'"
+	} else {
+		params["loc"] = timezone
+		params["time_zone"] = fmt.Sprintf("'%s'", timezone)
+	}
+
 	config := mysql.Config{
 		User:   username,
 		Passwd: password,
@@ -35,24 +56,148 @@ func NewMySQLDB(
 	}
 
 	return &mySQLDB{
-		dsn:    FormatDSN(config),
+		config: config,
 		dbName: database,
 	}
 }
 
+// MigrationSessionVariables are the session variables set on every MySQL
+// connection of the commands that write to MySQL, unless the config's
+// session_variables override them. Values are SQL expressions.
+var MigrationSessionVariables = map[string]string{
+	// Keep rows whose id is 0 rather than giving them the next
+	// AUTO_INCREMENT value
+	"sql_mode": "CONCAT(@@sql_mode, ',NO_AUTO_VALUE_ON_ZERO')",
+
+	// Tables are written in large batches that can wait on each other
+	"innodb_lock_wait_timeout": "600",
+}
+
 type mySQLDB struct {
-	dsn    string
+	config mysql.Config
 	db     *sql.DB
 	dbName string
+
+	// sessionVariables are set on every connection, after the session
+	// variables of the DSN. Their version is bumped whenever they change.
+	sessionVariablesMutex   sync.Mutex
+	sessionVariables        map[string]string
+	sessionVariablesVersion int
 }
 
 func (m *mySQLDB) Open() error {
-	db, err := sql.Open("mysql", m.dsn)
+	config, err := mysql.ParseDSN(FormatDSN(m.config))
+	if err != nil {
+		return err
+	}
+
+	connector, err := mysql.NewConnector(config)
 	if err != nil {
 		return err
 	}
 
-	m.db = db
+	m.db = sql.OpenDB(&sessionConnector{Connector: connector, db: m})
+
+	return nil
+}
+
+// sessionConnector opens connections that keep up with the session variables
+// of db.
+type sessionConnector struct {
+	driver.Connector
+	db *mySQLDB
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if isUnknownTimeZone(err) {
+		return nil, fmt.Errorf("MySQL doesn't know the time zone %s; load its time zone tables, e.g. with mysql_tzinfo_to_sql, or use UTC: %s", c.db.config.Params["time_zone"], err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	mysqlConn, ok := conn.(driverConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected MySQL connection %T", conn)
+	}
+
+	sessionConn := &sessionConn{driverConn: mysqlConn, db: c.db}
+	if err := sessionConn.setSessionVariables(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return sessionConn, nil
+}
+
+// isUnknownTimeZone is whether err is MySQL rejecting the time_zone of a
+// connection, as it does for named zones without its time zone tables.
+func isUnknownTimeZone(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1298
+}
+
+// driverConn is everything a connection of the MySQL driver implements, for
+// sessionConn to keep all of it.
+type driverConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.NamedValueChecker
+	driver.SessionResetter
+	driver.Validator
+}
+
+// sessionConn is a connection that sets the session variables of db when it
+// is opened, and again when they have changed by the time it is taken from
+// the pool.
+type sessionConn struct {
+	driverConn
+	db      *mySQLDB
+	version int
+}
+
+// ResetSession sets the session variables that changed since the connection
+// last set them. database/sql ignores every error but driver.ErrBadConn, so
+// a connection that fails to set them is discarded, and the error surfaces
+// when a new connection fails to set them as well.
+func (c *sessionConn) ResetSession(ctx context.Context) error {
+	if err := c.driverConn.ResetSession(ctx); err != nil {
+		return err
+	}
+
+	if err := c.setSessionVariables(ctx); err != nil {
+		return driver.ErrBadConn
+	}
+
+	return nil
+}
+
+func (c *sessionConn) setSessionVariables(ctx context.Context) error {
+	c.db.sessionVariablesMutex.Lock()
+	version := c.db.sessionVariablesVersion
+	variables := make(map[string]string, len(c.db.sessionVariables))
+	for name, value := range c.db.sessionVariables {
+		variables[name] = value
+	}
+	c.db.sessionVariablesMutex.Unlock()
+
+	if c.version == version {
+		return nil
+	}
+
+	for name, value := range variables {
+		_, err := c.ExecContext(ctx, fmt.Sprintf("SET %s = %s", name, value), nil)
+		if err != nil {
+			return fmt.Errorf("failed setting %s: %s", name, err)
+		}
+	}
+	c.version = version
 
 	return nil
 }
@@ -61,48 +206,109 @@ func (m *mySQLDB) Close() error {
 	return m.db.Close()
 }
 
-func (m *mySQLDB) HasPrimaryKey(tableName string) (bool, error) {
-	primaryKey, err := m.GetPrimaryKey(tableName)
+func (m *mySQLDB) HasPrimaryKey(ctx context.Context, tableName string) (bool, error) {
+	primaryKey, err := m.GetPrimaryKey(ctx, tableName)
 	if err != nil {
 		return false, err
 	}
 
-	return primaryKey != "", nil
+	return len(primaryKey) > 0, nil
 }
 
-func (m *mySQLDB) GetPrimaryKey(tableName string) (string, error) {
+func (m *mySQLDB) GetPrimaryKey(ctx context.Context, tableName string) ([]string, error) {
 	query := `
 		SELECT COLUMN_NAME
 		FROM   INFORMATION_SCHEMA.KEY_COLUMN_USAGE
 		WHERE  TABLE_SCHEMA = ?
 		       AND TABLE_NAME = ?
-		       AND CONSTRAINT_NAME = 'PRIMARY'`
+		       AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER  BY ORDINAL_POSITION`
 
-	var primaryKey string
-	err := m.db.QueryRow(query, m.dbName, tableName).Scan(&primaryKey)
+	rows, err := m.db.QueryContext(ctx, query, m.dbName, tableName)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			// No primary key found
-			return "", fmt.Errorf("table '%s' has no primary key", tableName)
-		} else {
-			// Other error occurred
-			return "", err
+		return nil, err
+	}
+
+	var primaryKey []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
 		}
+		primaryKey = append(primaryKey, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(primaryKey) == 0 {
+		// No primary key found
+		return nil, fmt.Errorf("table '%s' has no primary key", tableName)
 	}
 
-	// Primary key found
 	return primaryKey, nil
 }
 
-func (m *mySQLDB) GetSchemaRows() (*sql.Rows, error) {
+// EstimateRowCount returns the approximate number of rows in the table that
+// the storage engine reports.
+func (m *mySQLDB) EstimateRowCount(ctx context.Context, tableName string) (int64, error) {
+	query := `
+		SELECT TABLE_ROWS
+		FROM   INFORMATION_SCHEMA.TABLES
+		WHERE  TABLE_SCHEMA = ?
+		       AND TABLE_NAME = ?`
+
+	var count sql.NullInt64
+	err := m.db.QueryRowContext(ctx, query, m.dbName, tableName).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return count.Int64, nil
+}
+
+// GetSchemaRows describes the columns of the database. The width of a bit
+// column is described as its maximum length, as PostgreSQL describes bit
+// strings.
+func (m *mySQLDB) GetSchemaRows(ctx context.Context) (*sql.Rows, error) {
 	query := `
 	SELECT table_name,
 				 column_name,
 				 data_type,
-				 character_maximum_length
+				 IF(data_type = 'bit', numeric_precision, character_maximum_length),
+				 datetime_precision,
+				 IF(data_type = 'enum', column_type, NULL)
 	FROM   information_schema.columns
 	WHERE  table_schema = ?`
-	rows, err := m.db.Query(query, m.dbName)
+	rows, err := m.db.QueryContext(ctx, query, m.dbName)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (m *mySQLDB) GetForeignKeyRows(ctx context.Context) (*sql.Rows, error) {
+	query := `
+	SELECT CONSTRAINT_NAME,
+	       TABLE_NAME,
+	       COLUMN_NAME,
+	       REFERENCED_TABLE_NAME,
+	       REFERENCED_COLUMN_NAME
+	FROM   INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+	WHERE  TABLE_SCHEMA = ?
+	       AND REFERENCED_TABLE_NAME IS NOT NULL
+	ORDER  BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION`
+
+	rows, err := m.db.QueryContext(ctx, query, m.dbName)
 	if err != nil {
 		return nil, err
 	}
@@ -118,14 +324,32 @@ func (m *mySQLDB) ColumnNameForSelect(name string) string {
 	return fmt.Sprintf("`%s`", name)
 }
 
-func (m *mySQLDB) EnableConstraints() error {
-	_, err := m.db.Exec("SET FOREIGN_KEY_CHECKS = 1;")
-	return err
+func (m *mySQLDB) EnableConstraints(ctx context.Context) error {
+	return m.setSessionVariable(ctx, "FOREIGN_KEY_CHECKS", "1")
+}
+
+func (m *mySQLDB) DisableConstraints(ctx context.Context) error {
+	return m.setSessionVariable(ctx, "FOREIGN_KEY_CHECKS", "0")
 }
 
-func (m *mySQLDB) DisableConstraints() error {
-	_, err := m.db.Exec("SET FOREIGN_KEY_CHECKS = 0;")
-	return err
+// setSessionVariable sets a session variable on every connection; a SET
+// would only reach whichever pooled connection happened to run it. Each
+// connection sets it the next time it is taken from the pool, as one is
+// right away for an invalid value to fail here.
+func (m *mySQLDB) setSessionVariable(ctx context.Context, name, value string) error {
+	m.sessionVariablesMutex.Lock()
+	if m.sessionVariables == nil {
+		m.sessionVariables = map[string]string{}
+	}
+	m.sessionVariables[name] = value
+	m.sessionVariablesVersion++
+	m.sessionVariablesMutex.Unlock()
+
+	if m.db == nil {
+		return nil
+	}
+
+	return m.db.PingContext(ctx)
 }
 
 // Custom define format dsn function so my-sql-driver doesn't add unwanted fields
@@ -0,0 +1,124 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"database/sql"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("NewMySQLDB", func() {
+	It("leaves the params it is given alone", func() {
+		params := map[string]string{"sql_mode": "'TRADITIONAL'"}
+		pg2mysql.NewMySQLDB("some-db", "some-user", "", "127.0.0.1", 3306, params, "Europe/Amsterdam")
+		Expect(params).To(Equal(map[string]string{"sql_mode": "'TRADITIONAL'"}))
+	})
+})
+
+var _ = Describe("MySQL constraints", func() {
+	var (
+		mysql  pg2mysql.DB
+		params map[string]string
+		ctx    context.Context
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+
+		params = map[string]string{"innodb_lock_wait_timeout": "600"}
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, params, "")
+		Expect(mysql.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+	})
+
+	foreignKeyChecks := func(conns ...*sql.Conn) []int {
+		var checks []int
+		for _, conn := range conns {
+			var check int
+			Expect(conn.QueryRowContext(ctx, "SELECT @@SESSION.foreign_key_checks").Scan(&check)).To(Succeed())
+			checks = append(checks, check)
+		}
+		return checks
+	}
+
+	conns := func(n int) []*sql.Conn {
+		var conns []*sql.Conn
+		for i := 0; i < n; i++ {
+			conn, err := mysql.DB().Conn(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			conns = append(conns, conn)
+		}
+		return conns
+	}
+
+	release := func(conns []*sql.Conn) {
+		for _, conn := range conns {
+			Expect(conn.Close()).To(Succeed())
+		}
+	}
+
+	It("sets them on pooled connections without reopening the pool", func() {
+		stmt, err := mysql.DB().PrepareContext(ctx, "SELECT 1")
+		Expect(err).NotTo(HaveOccurred())
+		defer stmt.Close()
+
+		pooled := conns(2)
+		Expect(foreignKeyChecks(pooled...)).To(Equal([]int{1, 1}))
+		release(pooled)
+
+		Expect(mysql.DisableConstraints(ctx)).To(Succeed())
+
+		pooled = conns(3)
+		Expect(foreignKeyChecks(pooled...)).To(Equal([]int{0, 0, 0}))
+		release(pooled)
+
+		Expect(mysql.EnableConstraints(ctx)).To(Succeed())
+
+		pooled = conns(3)
+		Expect(foreignKeyChecks(pooled...)).To(Equal([]int{1, 1, 1}))
+		release(pooled)
+
+		var one int
+		Expect(stmt.QueryRowContext(ctx).Scan(&one)).To(Succeed())
+		Expect(params).To(Equal(map[string]string{"innodb_lock_wait_timeout": "600"}))
+	})
+
+	It("gives connections in use the variable once they are taken again", func() {
+		inUse := conns(1)
+
+		Expect(mysql.DisableConstraints(ctx)).To(Succeed())
+		Expect(foreignKeyChecks(inUse...)).To(Equal([]int{1}))
+		release(inUse)
+
+		pooled := conns(2)
+		Expect(foreignKeyChecks(pooled...)).To(Equal([]int{0, 0}))
+		release(pooled)
+	})
+
+	It("fails for the context it is given", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		Expect(mysql.DisableConstraints(cancelled)).To(MatchError(context.Canceled))
+	})
+})
+
+var _ = Describe("MySQL time zones", func() {
+	It("fails to connect with a clear error for a named zone without the time zone tables", func() {
+		mysql := pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "America/New_York")
+		Expect(mysql.Open()).To(Succeed())
+		defer mysql.Close()
+
+		err := mysql.DB().Ping()
+		if err == nil {
+			Skip("MySQL has its time zone tables loaded")
+		}
+		Expect(err).To(MatchError(ContainSubstring("MySQL doesn't know the time zone 'America/New_York'; load its time zone tables")))
+	})
+})
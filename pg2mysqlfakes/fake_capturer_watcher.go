@@ -0,0 +1,136 @@
+// This file was generated by counterfeiter
+package pg2mysqlfakes
+
+import (
+	"sync"
+
+	"pg2mysql"
+)
+
+type FakeCapturerWatcher struct {
+	CaptureDidInstallStub        func(tableNames []string)
+	captureDidInstallMutex       sync.RWMutex
+	captureDidInstallArgsForCall []struct {
+		tableNames []string
+	}
+	CaptureDidApplyChangesStub        func(changesApplied int64)
+	captureDidApplyChangesMutex       sync.RWMutex
+	captureDidApplyChangesArgsForCall []struct {
+		changesApplied int64
+	}
+	CaptureDidUninstallStub        func(tableNames []string)
+	captureDidUninstallMutex       sync.RWMutex
+	captureDidUninstallArgsForCall []struct {
+		tableNames []string
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidInstall(tableNames []string) {
+	var tableNamesCopy []string
+	if tableNames != nil {
+		tableNamesCopy = make([]string, len(tableNames))
+		copy(tableNamesCopy, tableNames)
+	}
+	fake.captureDidInstallMutex.Lock()
+	fake.captureDidInstallArgsForCall = append(fake.captureDidInstallArgsForCall, struct {
+		tableNames []string
+	}{tableNamesCopy})
+	fake.recordInvocation("CaptureDidInstall", []interface{}{tableNamesCopy})
+	fake.captureDidInstallMutex.Unlock()
+	if fake.CaptureDidInstallStub != nil {
+		fake.CaptureDidInstallStub(tableNames)
+	}
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidInstallCallCount() int {
+	fake.captureDidInstallMutex.RLock()
+	defer fake.captureDidInstallMutex.RUnlock()
+	return len(fake.captureDidInstallArgsForCall)
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidInstallArgsForCall(i int) []string {
+	fake.captureDidInstallMutex.RLock()
+	defer fake.captureDidInstallMutex.RUnlock()
+	return fake.captureDidInstallArgsForCall[i].tableNames
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidApplyChanges(changesApplied int64) {
+	fake.captureDidApplyChangesMutex.Lock()
+	fake.captureDidApplyChangesArgsForCall = append(fake.captureDidApplyChangesArgsForCall, struct {
+		changesApplied int64
+	}{changesApplied})
+	fake.recordInvocation("CaptureDidApplyChanges", []interface{}{changesApplied})
+	fake.captureDidApplyChangesMutex.Unlock()
+	if fake.CaptureDidApplyChangesStub != nil {
+		fake.CaptureDidApplyChangesStub(changesApplied)
+	}
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidApplyChangesCallCount() int {
+	fake.captureDidApplyChangesMutex.RLock()
+	defer fake.captureDidApplyChangesMutex.RUnlock()
+	return len(fake.captureDidApplyChangesArgsForCall)
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidApplyChangesArgsForCall(i int) int64 {
+	fake.captureDidApplyChangesMutex.RLock()
+	defer fake.captureDidApplyChangesMutex.RUnlock()
+	return fake.captureDidApplyChangesArgsForCall[i].changesApplied
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidUninstall(tableNames []string) {
+	var tableNamesCopy []string
+	if tableNames != nil {
+		tableNamesCopy = make([]string, len(tableNames))
+		copy(tableNamesCopy, tableNames)
+	}
+	fake.captureDidUninstallMutex.Lock()
+	fake.captureDidUninstallArgsForCall = append(fake.captureDidUninstallArgsForCall, struct {
+		tableNames []string
+	}{tableNamesCopy})
+	fake.recordInvocation("CaptureDidUninstall", []interface{}{tableNamesCopy})
+	fake.captureDidUninstallMutex.Unlock()
+	if fake.CaptureDidUninstallStub != nil {
+		fake.CaptureDidUninstallStub(tableNames)
+	}
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidUninstallCallCount() int {
+	fake.captureDidUninstallMutex.RLock()
+	defer fake.captureDidUninstallMutex.RUnlock()
+	return len(fake.captureDidUninstallArgsForCall)
+}
+
+func (fake *FakeCapturerWatcher) CaptureDidUninstallArgsForCall(i int) []string {
+	fake.captureDidUninstallMutex.RLock()
+	defer fake.captureDidUninstallMutex.RUnlock()
+	return fake.captureDidUninstallArgsForCall[i].tableNames
+}
+
+func (fake *FakeCapturerWatcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.captureDidInstallMutex.RLock()
+	defer fake.captureDidInstallMutex.RUnlock()
+	fake.captureDidApplyChangesMutex.RLock()
+	defer fake.captureDidApplyChangesMutex.RUnlock()
+	fake.captureDidUninstallMutex.RLock()
+	defer fake.captureDidUninstallMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeCapturerWatcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ pg2mysql.CapturerWatcher = new(FakeCapturerWatcher)
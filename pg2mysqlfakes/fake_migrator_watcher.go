@@ -4,7 +4,7 @@ package pg2mysqlfakes
 import (
 	"sync"
 
-	"github.com/pivotal-cf/pg2mysql"
+	"pg2mysql"
 )
 
 type FakeMigratorWatcher struct {
@@ -46,23 +46,56 @@ type FakeMigratorWatcher struct {
 	tableMigrationDidStartArgsForCall []struct {
 		tableName string
 	}
+	TableMigrationDidSkipStub        func(tableName string)
+	tableMigrationDidSkipMutex       sync.RWMutex
+	tableMigrationDidSkipArgsForCall []struct {
+		tableName string
+	}
 	TableMigrationDidFinishStub        func(tableName string, recordsInserted int64)
 	tableMigrationDidFinishMutex       sync.RWMutex
 	tableMigrationDidFinishArgsForCall []struct {
 		tableName       string
 		recordsInserted int64
 	}
+	TableMigrationDidProgressStub        func(progress pg2mysql.MigrationProgress)
+	tableMigrationDidProgressMutex       sync.RWMutex
+	tableMigrationDidProgressArgsForCall []struct {
+		progress pg2mysql.MigrationProgress
+	}
+	WillUpdateDeferredColumnsStub        func(tableName string, columns []string)
+	willUpdateDeferredColumnsMutex       sync.RWMutex
+	willUpdateDeferredColumnsArgsForCall []struct {
+		tableName string
+		columns   []string
+	}
+	DeferredColumnsDidUpdateStub        func(tableName string, rowsUpdated int64)
+	deferredColumnsDidUpdateMutex       sync.RWMutex
+	deferredColumnsDidUpdateArgsForCall []struct {
+		tableName   string
+		rowsUpdated int64
+	}
 	DidMigrateRowStub        func(tableName string)
 	didMigrateRowMutex       sync.RWMutex
 	didMigrateRowArgsForCall []struct {
 		tableName string
 	}
+	DidInsertBytesStub        func(tableName string, bytes int64)
+	didInsertBytesMutex       sync.RWMutex
+	didInsertBytesArgsForCall []struct {
+		tableName string
+		bytes     int64
+	}
 	DidFailToMigrateRowWithErrorStub        func(tableName string, err error)
 	didFailToMigrateRowWithErrorMutex       sync.RWMutex
 	didFailToMigrateRowWithErrorArgsForCall []struct {
 		tableName string
 		err       error
 	}
+	MigrationDidFinishWithFailuresStub        func(failures *pg2mysql.RowFailures)
+	migrationDidFinishWithFailuresMutex       sync.RWMutex
+	migrationDidFinishWithFailuresArgsForCall []struct {
+		failures *pg2mysql.RowFailures
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -259,6 +292,30 @@ func (fake *FakeMigratorWatcher) TableMigrationDidStartArgsForCall(i int) string
 	return fake.tableMigrationDidStartArgsForCall[i].tableName
 }
 
+func (fake *FakeMigratorWatcher) TableMigrationDidSkip(tableName string) {
+	fake.tableMigrationDidSkipMutex.Lock()
+	fake.tableMigrationDidSkipArgsForCall = append(fake.tableMigrationDidSkipArgsForCall, struct {
+		tableName string
+	}{tableName})
+	fake.recordInvocation("TableMigrationDidSkip", []interface{}{tableName})
+	fake.tableMigrationDidSkipMutex.Unlock()
+	if fake.TableMigrationDidSkipStub != nil {
+		fake.TableMigrationDidSkipStub(tableName)
+	}
+}
+
+func (fake *FakeMigratorWatcher) TableMigrationDidSkipCallCount() int {
+	fake.tableMigrationDidSkipMutex.RLock()
+	defer fake.tableMigrationDidSkipMutex.RUnlock()
+	return len(fake.tableMigrationDidSkipArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) TableMigrationDidSkipArgsForCall(i int) string {
+	fake.tableMigrationDidSkipMutex.RLock()
+	defer fake.tableMigrationDidSkipMutex.RUnlock()
+	return fake.tableMigrationDidSkipArgsForCall[i].tableName
+}
+
 func (fake *FakeMigratorWatcher) TableMigrationDidFinish(tableName string, recordsInserted int64) {
 	fake.tableMigrationDidFinishMutex.Lock()
 	fake.tableMigrationDidFinishArgsForCall = append(fake.tableMigrationDidFinishArgsForCall, struct {
@@ -284,6 +341,85 @@ func (fake *FakeMigratorWatcher) TableMigrationDidFinishArgsForCall(i int) (stri
 	return fake.tableMigrationDidFinishArgsForCall[i].tableName, fake.tableMigrationDidFinishArgsForCall[i].recordsInserted
 }
 
+func (fake *FakeMigratorWatcher) TableMigrationDidProgress(progress pg2mysql.MigrationProgress) {
+	fake.tableMigrationDidProgressMutex.Lock()
+	fake.tableMigrationDidProgressArgsForCall = append(fake.tableMigrationDidProgressArgsForCall, struct {
+		progress pg2mysql.MigrationProgress
+	}{progress})
+	fake.recordInvocation("TableMigrationDidProgress", []interface{}{progress})
+	fake.tableMigrationDidProgressMutex.Unlock()
+	if fake.TableMigrationDidProgressStub != nil {
+		fake.TableMigrationDidProgressStub(progress)
+	}
+}
+
+func (fake *FakeMigratorWatcher) TableMigrationDidProgressCallCount() int {
+	fake.tableMigrationDidProgressMutex.RLock()
+	defer fake.tableMigrationDidProgressMutex.RUnlock()
+	return len(fake.tableMigrationDidProgressArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) TableMigrationDidProgressArgsForCall(i int) pg2mysql.MigrationProgress {
+	fake.tableMigrationDidProgressMutex.RLock()
+	defer fake.tableMigrationDidProgressMutex.RUnlock()
+	return fake.tableMigrationDidProgressArgsForCall[i].progress
+}
+
+func (fake *FakeMigratorWatcher) WillUpdateDeferredColumns(tableName string, columns []string) {
+	var columnsCopy []string
+	if columns != nil {
+		columnsCopy = make([]string, len(columns))
+		copy(columnsCopy, columns)
+	}
+	fake.willUpdateDeferredColumnsMutex.Lock()
+	fake.willUpdateDeferredColumnsArgsForCall = append(fake.willUpdateDeferredColumnsArgsForCall, struct {
+		tableName string
+		columns   []string
+	}{tableName, columnsCopy})
+	fake.recordInvocation("WillUpdateDeferredColumns", []interface{}{tableName, columnsCopy})
+	fake.willUpdateDeferredColumnsMutex.Unlock()
+	if fake.WillUpdateDeferredColumnsStub != nil {
+		fake.WillUpdateDeferredColumnsStub(tableName, columns)
+	}
+}
+
+func (fake *FakeMigratorWatcher) WillUpdateDeferredColumnsCallCount() int {
+	fake.willUpdateDeferredColumnsMutex.RLock()
+	defer fake.willUpdateDeferredColumnsMutex.RUnlock()
+	return len(fake.willUpdateDeferredColumnsArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) WillUpdateDeferredColumnsArgsForCall(i int) (string, []string) {
+	fake.willUpdateDeferredColumnsMutex.RLock()
+	defer fake.willUpdateDeferredColumnsMutex.RUnlock()
+	return fake.willUpdateDeferredColumnsArgsForCall[i].tableName, fake.willUpdateDeferredColumnsArgsForCall[i].columns
+}
+
+func (fake *FakeMigratorWatcher) DeferredColumnsDidUpdate(tableName string, rowsUpdated int64) {
+	fake.deferredColumnsDidUpdateMutex.Lock()
+	fake.deferredColumnsDidUpdateArgsForCall = append(fake.deferredColumnsDidUpdateArgsForCall, struct {
+		tableName   string
+		rowsUpdated int64
+	}{tableName, rowsUpdated})
+	fake.recordInvocation("DeferredColumnsDidUpdate", []interface{}{tableName, rowsUpdated})
+	fake.deferredColumnsDidUpdateMutex.Unlock()
+	if fake.DeferredColumnsDidUpdateStub != nil {
+		fake.DeferredColumnsDidUpdateStub(tableName, rowsUpdated)
+	}
+}
+
+func (fake *FakeMigratorWatcher) DeferredColumnsDidUpdateCallCount() int {
+	fake.deferredColumnsDidUpdateMutex.RLock()
+	defer fake.deferredColumnsDidUpdateMutex.RUnlock()
+	return len(fake.deferredColumnsDidUpdateArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) DeferredColumnsDidUpdateArgsForCall(i int) (string, int64) {
+	fake.deferredColumnsDidUpdateMutex.RLock()
+	defer fake.deferredColumnsDidUpdateMutex.RUnlock()
+	return fake.deferredColumnsDidUpdateArgsForCall[i].tableName, fake.deferredColumnsDidUpdateArgsForCall[i].rowsUpdated
+}
+
 func (fake *FakeMigratorWatcher) DidMigrateRow(tableName string) {
 	fake.didMigrateRowMutex.Lock()
 	fake.didMigrateRowArgsForCall = append(fake.didMigrateRowArgsForCall, struct {
@@ -308,6 +444,31 @@ func (fake *FakeMigratorWatcher) DidMigrateRowArgsForCall(i int) string {
 	return fake.didMigrateRowArgsForCall[i].tableName
 }
 
+func (fake *FakeMigratorWatcher) DidInsertBytes(tableName string, bytes int64) {
+	fake.didInsertBytesMutex.Lock()
+	fake.didInsertBytesArgsForCall = append(fake.didInsertBytesArgsForCall, struct {
+		tableName string
+		bytes     int64
+	}{tableName, bytes})
+	fake.recordInvocation("DidInsertBytes", []interface{}{tableName, bytes})
+	fake.didInsertBytesMutex.Unlock()
+	if fake.DidInsertBytesStub != nil {
+		fake.DidInsertBytesStub(tableName, bytes)
+	}
+}
+
+func (fake *FakeMigratorWatcher) DidInsertBytesCallCount() int {
+	fake.didInsertBytesMutex.RLock()
+	defer fake.didInsertBytesMutex.RUnlock()
+	return len(fake.didInsertBytesArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) DidInsertBytesArgsForCall(i int) (string, int64) {
+	fake.didInsertBytesMutex.RLock()
+	defer fake.didInsertBytesMutex.RUnlock()
+	return fake.didInsertBytesArgsForCall[i].tableName, fake.didInsertBytesArgsForCall[i].bytes
+}
+
 func (fake *FakeMigratorWatcher) DidFailToMigrateRowWithError(tableName string, err error) {
 	fake.didFailToMigrateRowWithErrorMutex.Lock()
 	fake.didFailToMigrateRowWithErrorArgsForCall = append(fake.didFailToMigrateRowWithErrorArgsForCall, struct {
@@ -333,6 +494,30 @@ func (fake *FakeMigratorWatcher) DidFailToMigrateRowWithErrorArgsForCall(i int)
 	return fake.didFailToMigrateRowWithErrorArgsForCall[i].tableName, fake.didFailToMigrateRowWithErrorArgsForCall[i].err
 }
 
+func (fake *FakeMigratorWatcher) MigrationDidFinishWithFailures(failures *pg2mysql.RowFailures) {
+	fake.migrationDidFinishWithFailuresMutex.Lock()
+	fake.migrationDidFinishWithFailuresArgsForCall = append(fake.migrationDidFinishWithFailuresArgsForCall, struct {
+		failures *pg2mysql.RowFailures
+	}{failures})
+	fake.recordInvocation("MigrationDidFinishWithFailures", []interface{}{failures})
+	fake.migrationDidFinishWithFailuresMutex.Unlock()
+	if fake.MigrationDidFinishWithFailuresStub != nil {
+		fake.MigrationDidFinishWithFailuresStub(failures)
+	}
+}
+
+func (fake *FakeMigratorWatcher) MigrationDidFinishWithFailuresCallCount() int {
+	fake.migrationDidFinishWithFailuresMutex.RLock()
+	defer fake.migrationDidFinishWithFailuresMutex.RUnlock()
+	return len(fake.migrationDidFinishWithFailuresArgsForCall)
+}
+
+func (fake *FakeMigratorWatcher) MigrationDidFinishWithFailuresArgsForCall(i int) *pg2mysql.RowFailures {
+	fake.migrationDidFinishWithFailuresMutex.RLock()
+	defer fake.migrationDidFinishWithFailuresMutex.RUnlock()
+	return fake.migrationDidFinishWithFailuresArgsForCall[i].failures
+}
+
 func (fake *FakeMigratorWatcher) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -356,12 +541,24 @@ func (fake *FakeMigratorWatcher) Invocations() map[string][][]interface{} {
 	defer fake.truncateTableDidFinishMutex.RUnlock()
 	fake.tableMigrationDidStartMutex.RLock()
 	defer fake.tableMigrationDidStartMutex.RUnlock()
+	fake.tableMigrationDidSkipMutex.RLock()
+	defer fake.tableMigrationDidSkipMutex.RUnlock()
 	fake.tableMigrationDidFinishMutex.RLock()
 	defer fake.tableMigrationDidFinishMutex.RUnlock()
+	fake.tableMigrationDidProgressMutex.RLock()
+	defer fake.tableMigrationDidProgressMutex.RUnlock()
+	fake.willUpdateDeferredColumnsMutex.RLock()
+	defer fake.willUpdateDeferredColumnsMutex.RUnlock()
+	fake.deferredColumnsDidUpdateMutex.RLock()
+	defer fake.deferredColumnsDidUpdateMutex.RUnlock()
 	fake.didMigrateRowMutex.RLock()
 	defer fake.didMigrateRowMutex.RUnlock()
+	fake.didInsertBytesMutex.RLock()
+	defer fake.didInsertBytesMutex.RUnlock()
 	fake.didFailToMigrateRowWithErrorMutex.RLock()
 	defer fake.didFailToMigrateRowWithErrorMutex.RUnlock()
+	fake.migrationDidFinishWithFailuresMutex.RLock()
+	defer fake.migrationDidFinishWithFailuresMutex.RUnlock()
 	return fake.invocations
 }
 
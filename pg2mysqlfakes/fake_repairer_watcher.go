@@ -0,0 +1,167 @@
+// This file was generated by counterfeiter
+package pg2mysqlfakes
+
+import (
+	"sync"
+
+	"pg2mysql"
+)
+
+type FakeRepairerWatcher struct {
+	TableRepairDidStartStub        func(tableName string)
+	tableRepairDidStartMutex       sync.RWMutex
+	tableRepairDidStartArgsForCall []struct {
+		tableName string
+	}
+	TableRepairDidFinishStub        func(tableName string, rowsInserted int64, rowsUpdated int64, rowsDeleted int64)
+	tableRepairDidFinishMutex       sync.RWMutex
+	tableRepairDidFinishArgsForCall []struct {
+		tableName    string
+		rowsInserted int64
+		rowsUpdated  int64
+		rowsDeleted  int64
+	}
+	TableRepairDidFinishWithErrorStub        func(tableName string, err error)
+	tableRepairDidFinishWithErrorMutex       sync.RWMutex
+	tableRepairDidFinishWithErrorArgsForCall []struct {
+		tableName string
+		err       error
+	}
+	WouldExecuteStatementStub        func(tableName string, stmt string)
+	wouldExecuteStatementMutex       sync.RWMutex
+	wouldExecuteStatementArgsForCall []struct {
+		tableName string
+		stmt      string
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidStart(tableName string) {
+	fake.tableRepairDidStartMutex.Lock()
+	fake.tableRepairDidStartArgsForCall = append(fake.tableRepairDidStartArgsForCall, struct {
+		tableName string
+	}{tableName})
+	fake.recordInvocation("TableRepairDidStart", []interface{}{tableName})
+	fake.tableRepairDidStartMutex.Unlock()
+	if fake.TableRepairDidStartStub != nil {
+		fake.TableRepairDidStartStub(tableName)
+	}
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidStartCallCount() int {
+	fake.tableRepairDidStartMutex.RLock()
+	defer fake.tableRepairDidStartMutex.RUnlock()
+	return len(fake.tableRepairDidStartArgsForCall)
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidStartArgsForCall(i int) string {
+	fake.tableRepairDidStartMutex.RLock()
+	defer fake.tableRepairDidStartMutex.RUnlock()
+	return fake.tableRepairDidStartArgsForCall[i].tableName
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinish(tableName string, rowsInserted int64, rowsUpdated int64, rowsDeleted int64) {
+	fake.tableRepairDidFinishMutex.Lock()
+	fake.tableRepairDidFinishArgsForCall = append(fake.tableRepairDidFinishArgsForCall, struct {
+		tableName    string
+		rowsInserted int64
+		rowsUpdated  int64
+		rowsDeleted  int64
+	}{tableName, rowsInserted, rowsUpdated, rowsDeleted})
+	fake.recordInvocation("TableRepairDidFinish", []interface{}{tableName, rowsInserted, rowsUpdated, rowsDeleted})
+	fake.tableRepairDidFinishMutex.Unlock()
+	if fake.TableRepairDidFinishStub != nil {
+		fake.TableRepairDidFinishStub(tableName, rowsInserted, rowsUpdated, rowsDeleted)
+	}
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinishCallCount() int {
+	fake.tableRepairDidFinishMutex.RLock()
+	defer fake.tableRepairDidFinishMutex.RUnlock()
+	return len(fake.tableRepairDidFinishArgsForCall)
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinishArgsForCall(i int) (string, int64, int64, int64) {
+	fake.tableRepairDidFinishMutex.RLock()
+	defer fake.tableRepairDidFinishMutex.RUnlock()
+	return fake.tableRepairDidFinishArgsForCall[i].tableName, fake.tableRepairDidFinishArgsForCall[i].rowsInserted, fake.tableRepairDidFinishArgsForCall[i].rowsUpdated, fake.tableRepairDidFinishArgsForCall[i].rowsDeleted
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinishWithError(tableName string, err error) {
+	fake.tableRepairDidFinishWithErrorMutex.Lock()
+	fake.tableRepairDidFinishWithErrorArgsForCall = append(fake.tableRepairDidFinishWithErrorArgsForCall, struct {
+		tableName string
+		err       error
+	}{tableName, err})
+	fake.recordInvocation("TableRepairDidFinishWithError", []interface{}{tableName, err})
+	fake.tableRepairDidFinishWithErrorMutex.Unlock()
+	if fake.TableRepairDidFinishWithErrorStub != nil {
+		fake.TableRepairDidFinishWithErrorStub(tableName, err)
+	}
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinishWithErrorCallCount() int {
+	fake.tableRepairDidFinishWithErrorMutex.RLock()
+	defer fake.tableRepairDidFinishWithErrorMutex.RUnlock()
+	return len(fake.tableRepairDidFinishWithErrorArgsForCall)
+}
+
+func (fake *FakeRepairerWatcher) TableRepairDidFinishWithErrorArgsForCall(i int) (string, error) {
+	fake.tableRepairDidFinishWithErrorMutex.RLock()
+	defer fake.tableRepairDidFinishWithErrorMutex.RUnlock()
+	return fake.tableRepairDidFinishWithErrorArgsForCall[i].tableName, fake.tableRepairDidFinishWithErrorArgsForCall[i].err
+}
+
+func (fake *FakeRepairerWatcher) WouldExecuteStatement(tableName string, stmt string) {
+	fake.wouldExecuteStatementMutex.Lock()
+	fake.wouldExecuteStatementArgsForCall = append(fake.wouldExecuteStatementArgsForCall, struct {
+		tableName string
+		stmt      string
+	}{tableName, stmt})
+	fake.recordInvocation("WouldExecuteStatement", []interface{}{tableName, stmt})
+	fake.wouldExecuteStatementMutex.Unlock()
+	if fake.WouldExecuteStatementStub != nil {
+		fake.WouldExecuteStatementStub(tableName, stmt)
+	}
+}
+
+func (fake *FakeRepairerWatcher) WouldExecuteStatementCallCount() int {
+	fake.wouldExecuteStatementMutex.RLock()
+	defer fake.wouldExecuteStatementMutex.RUnlock()
+	return len(fake.wouldExecuteStatementArgsForCall)
+}
+
+func (fake *FakeRepairerWatcher) WouldExecuteStatementArgsForCall(i int) (string, string) {
+	fake.wouldExecuteStatementMutex.RLock()
+	defer fake.wouldExecuteStatementMutex.RUnlock()
+	return fake.wouldExecuteStatementArgsForCall[i].tableName, fake.wouldExecuteStatementArgsForCall[i].stmt
+}
+
+func (fake *FakeRepairerWatcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.tableRepairDidStartMutex.RLock()
+	defer fake.tableRepairDidStartMutex.RUnlock()
+	fake.tableRepairDidFinishMutex.RLock()
+	defer fake.tableRepairDidFinishMutex.RUnlock()
+	fake.tableRepairDidFinishWithErrorMutex.RLock()
+	defer fake.tableRepairDidFinishWithErrorMutex.RUnlock()
+	fake.wouldExecuteStatementMutex.RLock()
+	defer fake.wouldExecuteStatementMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeRepairerWatcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ pg2mysql.RepairerWatcher = new(FakeRepairerWatcher)
@@ -0,0 +1,132 @@
+// This file was generated by counterfeiter
+package pg2mysqlfakes
+
+import (
+	"sync"
+
+	"pg2mysql"
+)
+
+type FakeReplayerWatcher struct {
+	DeadLetterReplayDidStartStub        func(tableName string)
+	deadLetterReplayDidStartMutex       sync.RWMutex
+	deadLetterReplayDidStartArgsForCall []struct {
+		tableName string
+	}
+	DeadLetterReplayDidFinishStub        func(tableName string, rowsReplayed int64, rowsRemaining int64)
+	deadLetterReplayDidFinishMutex       sync.RWMutex
+	deadLetterReplayDidFinishArgsForCall []struct {
+		tableName     string
+		rowsReplayed  int64
+		rowsRemaining int64
+	}
+	DeadLetterReplayDidFinishWithErrorStub        func(tableName string, err error)
+	deadLetterReplayDidFinishWithErrorMutex       sync.RWMutex
+	deadLetterReplayDidFinishWithErrorArgsForCall []struct {
+		tableName string
+		err       error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidStart(tableName string) {
+	fake.deadLetterReplayDidStartMutex.Lock()
+	fake.deadLetterReplayDidStartArgsForCall = append(fake.deadLetterReplayDidStartArgsForCall, struct {
+		tableName string
+	}{tableName})
+	fake.recordInvocation("DeadLetterReplayDidStart", []interface{}{tableName})
+	fake.deadLetterReplayDidStartMutex.Unlock()
+	if fake.DeadLetterReplayDidStartStub != nil {
+		fake.DeadLetterReplayDidStartStub(tableName)
+	}
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidStartCallCount() int {
+	fake.deadLetterReplayDidStartMutex.RLock()
+	defer fake.deadLetterReplayDidStartMutex.RUnlock()
+	return len(fake.deadLetterReplayDidStartArgsForCall)
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidStartArgsForCall(i int) string {
+	fake.deadLetterReplayDidStartMutex.RLock()
+	defer fake.deadLetterReplayDidStartMutex.RUnlock()
+	return fake.deadLetterReplayDidStartArgsForCall[i].tableName
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinish(tableName string, rowsReplayed int64, rowsRemaining int64) {
+	fake.deadLetterReplayDidFinishMutex.Lock()
+	fake.deadLetterReplayDidFinishArgsForCall = append(fake.deadLetterReplayDidFinishArgsForCall, struct {
+		tableName     string
+		rowsReplayed  int64
+		rowsRemaining int64
+	}{tableName, rowsReplayed, rowsRemaining})
+	fake.recordInvocation("DeadLetterReplayDidFinish", []interface{}{tableName, rowsReplayed, rowsRemaining})
+	fake.deadLetterReplayDidFinishMutex.Unlock()
+	if fake.DeadLetterReplayDidFinishStub != nil {
+		fake.DeadLetterReplayDidFinishStub(tableName, rowsReplayed, rowsRemaining)
+	}
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinishCallCount() int {
+	fake.deadLetterReplayDidFinishMutex.RLock()
+	defer fake.deadLetterReplayDidFinishMutex.RUnlock()
+	return len(fake.deadLetterReplayDidFinishArgsForCall)
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinishArgsForCall(i int) (string, int64, int64) {
+	fake.deadLetterReplayDidFinishMutex.RLock()
+	defer fake.deadLetterReplayDidFinishMutex.RUnlock()
+	return fake.deadLetterReplayDidFinishArgsForCall[i].tableName, fake.deadLetterReplayDidFinishArgsForCall[i].rowsReplayed, fake.deadLetterReplayDidFinishArgsForCall[i].rowsRemaining
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinishWithError(tableName string, err error) {
+	fake.deadLetterReplayDidFinishWithErrorMutex.Lock()
+	fake.deadLetterReplayDidFinishWithErrorArgsForCall = append(fake.deadLetterReplayDidFinishWithErrorArgsForCall, struct {
+		tableName string
+		err       error
+	}{tableName, err})
+	fake.recordInvocation("DeadLetterReplayDidFinishWithError", []interface{}{tableName, err})
+	fake.deadLetterReplayDidFinishWithErrorMutex.Unlock()
+	if fake.DeadLetterReplayDidFinishWithErrorStub != nil {
+		fake.DeadLetterReplayDidFinishWithErrorStub(tableName, err)
+	}
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinishWithErrorCallCount() int {
+	fake.deadLetterReplayDidFinishWithErrorMutex.RLock()
+	defer fake.deadLetterReplayDidFinishWithErrorMutex.RUnlock()
+	return len(fake.deadLetterReplayDidFinishWithErrorArgsForCall)
+}
+
+func (fake *FakeReplayerWatcher) DeadLetterReplayDidFinishWithErrorArgsForCall(i int) (string, error) {
+	fake.deadLetterReplayDidFinishWithErrorMutex.RLock()
+	defer fake.deadLetterReplayDidFinishWithErrorMutex.RUnlock()
+	return fake.deadLetterReplayDidFinishWithErrorArgsForCall[i].tableName, fake.deadLetterReplayDidFinishWithErrorArgsForCall[i].err
+}
+
+func (fake *FakeReplayerWatcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.deadLetterReplayDidStartMutex.RLock()
+	defer fake.deadLetterReplayDidStartMutex.RUnlock()
+	fake.deadLetterReplayDidFinishMutex.RLock()
+	defer fake.deadLetterReplayDidFinishMutex.RUnlock()
+	fake.deadLetterReplayDidFinishWithErrorMutex.RLock()
+	defer fake.deadLetterReplayDidFinishWithErrorMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeReplayerWatcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ pg2mysql.ReplayerWatcher = new(FakeReplayerWatcher)
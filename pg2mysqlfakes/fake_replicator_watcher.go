@@ -0,0 +1,128 @@
+// This file was generated by counterfeiter
+package pg2mysqlfakes
+
+import (
+	"sync"
+
+	"pg2mysql"
+)
+
+type FakeReplicatorWatcher struct {
+	ReplicationSlotDidCreateStub        func(slotName string, lsn string)
+	replicationSlotDidCreateMutex       sync.RWMutex
+	replicationSlotDidCreateArgsForCall []struct {
+		slotName string
+		lsn      string
+	}
+	ReplicationDidProgressStub        func(progress pg2mysql.ReplicationProgress)
+	replicationDidProgressMutex       sync.RWMutex
+	replicationDidProgressArgsForCall []struct {
+		progress pg2mysql.ReplicationProgress
+	}
+	ReplicationDidStopStub        func(confirmedLSN string)
+	replicationDidStopMutex       sync.RWMutex
+	replicationDidStopArgsForCall []struct {
+		confirmedLSN string
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationSlotDidCreate(slotName string, lsn string) {
+	fake.replicationSlotDidCreateMutex.Lock()
+	fake.replicationSlotDidCreateArgsForCall = append(fake.replicationSlotDidCreateArgsForCall, struct {
+		slotName string
+		lsn      string
+	}{slotName, lsn})
+	fake.recordInvocation("ReplicationSlotDidCreate", []interface{}{slotName, lsn})
+	fake.replicationSlotDidCreateMutex.Unlock()
+	if fake.ReplicationSlotDidCreateStub != nil {
+		fake.ReplicationSlotDidCreateStub(slotName, lsn)
+	}
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationSlotDidCreateCallCount() int {
+	fake.replicationSlotDidCreateMutex.RLock()
+	defer fake.replicationSlotDidCreateMutex.RUnlock()
+	return len(fake.replicationSlotDidCreateArgsForCall)
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationSlotDidCreateArgsForCall(i int) (string, string) {
+	fake.replicationSlotDidCreateMutex.RLock()
+	defer fake.replicationSlotDidCreateMutex.RUnlock()
+	return fake.replicationSlotDidCreateArgsForCall[i].slotName, fake.replicationSlotDidCreateArgsForCall[i].lsn
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidProgress(progress pg2mysql.ReplicationProgress) {
+	fake.replicationDidProgressMutex.Lock()
+	fake.replicationDidProgressArgsForCall = append(fake.replicationDidProgressArgsForCall, struct {
+		progress pg2mysql.ReplicationProgress
+	}{progress})
+	fake.recordInvocation("ReplicationDidProgress", []interface{}{progress})
+	fake.replicationDidProgressMutex.Unlock()
+	if fake.ReplicationDidProgressStub != nil {
+		fake.ReplicationDidProgressStub(progress)
+	}
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidProgressCallCount() int {
+	fake.replicationDidProgressMutex.RLock()
+	defer fake.replicationDidProgressMutex.RUnlock()
+	return len(fake.replicationDidProgressArgsForCall)
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidProgressArgsForCall(i int) pg2mysql.ReplicationProgress {
+	fake.replicationDidProgressMutex.RLock()
+	defer fake.replicationDidProgressMutex.RUnlock()
+	return fake.replicationDidProgressArgsForCall[i].progress
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidStop(confirmedLSN string) {
+	fake.replicationDidStopMutex.Lock()
+	fake.replicationDidStopArgsForCall = append(fake.replicationDidStopArgsForCall, struct {
+		confirmedLSN string
+	}{confirmedLSN})
+	fake.recordInvocation("ReplicationDidStop", []interface{}{confirmedLSN})
+	fake.replicationDidStopMutex.Unlock()
+	if fake.ReplicationDidStopStub != nil {
+		fake.ReplicationDidStopStub(confirmedLSN)
+	}
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidStopCallCount() int {
+	fake.replicationDidStopMutex.RLock()
+	defer fake.replicationDidStopMutex.RUnlock()
+	return len(fake.replicationDidStopArgsForCall)
+}
+
+func (fake *FakeReplicatorWatcher) ReplicationDidStopArgsForCall(i int) string {
+	fake.replicationDidStopMutex.RLock()
+	defer fake.replicationDidStopMutex.RUnlock()
+	return fake.replicationDidStopArgsForCall[i].confirmedLSN
+}
+
+func (fake *FakeReplicatorWatcher) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.replicationSlotDidCreateMutex.RLock()
+	defer fake.replicationSlotDidCreateMutex.RUnlock()
+	fake.replicationDidProgressMutex.RLock()
+	defer fake.replicationDidProgressMutex.RUnlock()
+	fake.replicationDidStopMutex.RLock()
+	defer fake.replicationDidStopMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeReplicatorWatcher) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ pg2mysql.ReplicatorWatcher = new(FakeReplicatorWatcher)
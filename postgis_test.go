@@ -0,0 +1,147 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("PostGIS geometries", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	)
+
+	BeforeEach(func() {
+		if _, err := pgRunner.DB().Exec("CREATE EXTENSION IF NOT EXISTS postgis"); err != nil {
+			Skip("PostGIS is not available: " + err.Error())
+		}
+
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE places (
+				id int PRIMARY KEY,
+				location geometry(Point, 4326),
+				shape geometry
+			);
+			INSERT INTO places VALUES
+				(1, ST_SetSRID(ST_MakePoint(-122.4, 37.8), 4326), ST_GeomFromText('LINESTRING(0 0, 1 1)')),
+				(2, NULL, NULL);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec(`
+			CREATE TABLE places (
+				id int PRIMARY KEY,
+				location POINT,
+				shape GEOMETRY
+			);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		if mysql == nil {
+			return
+		}
+
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+		mysql, pg = nil, nil
+
+		_, err := pgRunner.DB().Exec("DROP TABLE places")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE places")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("migrates geometries in the SRID of their column and verifies them by WKB", func() {
+		migrator := pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{})
+		Expect(migrator.Migrate(context.Background(), config)).To(Succeed())
+
+		var srid, shapeSRID int
+		err := mysqlRunner.DB().QueryRow("SELECT ST_SRID(location), ST_SRID(shape) FROM places WHERE id = 1").Scan(&srid, &shapeSRID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srid).To(Equal(4326))
+		Expect(shapeSRID).To(BeZero())
+
+		watcher := &pg2mysqlfakes.FakeVerifierWatcher{}
+		verifier := pg2mysql.NewVerifier(pg, mysql, watcher)
+		Expect(verifier.Verify(context.Background(), config)).To(Succeed())
+
+		Expect(watcher.TableVerificationDidFinishCallCount()).To(Equal(1))
+		tableName, missingRows, _ := watcher.TableVerificationDidFinishArgsForCall(0)
+		Expect(tableName).To(Equal("places"))
+		Expect(missingRows).To(BeZero())
+	})
+
+	It("replays dead-lettered geometries in the SRID of their column", func() {
+		dir, err := ioutil.TempDir("", "dead-letters")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(dir)
+
+		var location, shape []byte
+		err = pgRunner.DB().QueryRow("SELECT ST_AsBinary(location), ST_AsBinary(shape) FROM places WHERE id = 1").Scan(&location, &shape)
+		Expect(err).NotTo(HaveOccurred())
+
+		table := &pg2mysql.Table{
+			Name: "places",
+			Columns: []*pg2mysql.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "location", Type: "geometry", SRID: 4326},
+				{Name: "shape", Type: "geometry"},
+			},
+		}
+		writer := pg2mysql.NewDeadLetterWriter(dir)
+		Expect(writer.Write(table, []interface{}{int64(1), location, shape}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Write(table, []interface{}{int64(2), nil, nil}, errors.New("some-error"))).To(Succeed())
+		Expect(writer.Close()).To(Succeed())
+
+		watcher := &pg2mysqlfakes.FakeReplayerWatcher{}
+		Expect(pg2mysql.NewReplayer(mysql, dir, watcher).Replay(context.Background())).To(Succeed())
+		Expect(watcher.DeadLetterReplayDidFinishCallCount()).To(Equal(1))
+		_, replayed, remaining := watcher.DeadLetterReplayDidFinishArgsForCall(0)
+		Expect([]int64{replayed, remaining}).To(Equal([]int64{2, 0}))
+
+		var srid, shapeSRID int
+		err = mysqlRunner.DB().QueryRow("SELECT ST_SRID(location), ST_SRID(shape) FROM places WHERE id = 1").Scan(&srid, &shapeSRID)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(srid).To(Equal(4326))
+		Expect(shapeSRID).To(BeZero())
+
+		verifierWatcher := &pg2mysqlfakes.FakeVerifierWatcher{}
+		Expect(pg2mysql.NewVerifier(pg, mysql, verifierWatcher).Verify(context.Background(), config)).To(Succeed())
+		_, missingRows, _ := verifierWatcher.TableVerificationDidFinishArgsForCall(0)
+		Expect(missingRows).To(BeZero())
+	})
+
+	It("validates the geometries MySQL can't hold", func() {
+		_, err := pgRunner.DB().Exec(`
+			INSERT INTO places (id, shape) VALUES
+				(3, ST_GeomFromText('POINT Z (1 2 3)')),
+				(4, ST_SetSRID(ST_MakePoint(1, 2), 3857));`)
+		Expect(err).NotTo(HaveOccurred())
+
+		results, err := pg2mysql.NewValidator(pg, mysql).Validate(context.Background(), config)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].InvalidGeometries).To(Equal([]pg2mysql.InvalidGeometryMetadata{
+			{ColumnName: "shape", Problem: "of types or with coordinates MySQL doesn't support", RowCount: 1, RowIDs: []string{"3"}},
+			{ColumnName: "shape", Problem: "in another SRID than 0", RowCount: 1, RowIDs: []string{"4"}},
+		}))
+	})
+})
@@ -1,6 +1,7 @@
 package pg2mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -14,9 +15,13 @@ func NewPostgreSQLDB(
 	host string,
 	port int,
 	sslMode string,
+	timezone string,
 ) DB {
 	dsn := fmt.Sprintf("dbname=%s host=%s port=%d sslmode=%s", database, host, port, sslMode)
 
+	if timezone != "" {
+		dsn = fmt.Sprintf("%s timezone=%s", dsn, timezone)
+	}
 	if username != "" {
 		dsn = fmt.Sprintf("%s user=%s", dsn, username)
 	}
@@ -51,16 +56,16 @@ func (p *postgreSQLDB) Close() error {
 	return p.db.Close()
 }
 
-func (p *postgreSQLDB) HasPrimaryKey(tableName string) (bool, error) {
-	primaryKey, err := p.GetPrimaryKey(tableName)
+func (p *postgreSQLDB) HasPrimaryKey(ctx context.Context, tableName string) (bool, error) {
+	primaryKey, err := p.GetPrimaryKey(ctx, tableName)
 	if err != nil {
 		return false, err
 	}
 
-	return primaryKey != "", nil
+	return len(primaryKey) > 0, nil
 }
 
-func (p *postgreSQLDB) GetPrimaryKey(tableName string) (string, error) {
+func (p *postgreSQLDB) GetPrimaryKey(ctx context.Context, tableName string) ([]string, error) {
 	stmt := `
 		SELECT kcu.column_name
 		FROM information_schema.table_constraints tc
@@ -70,37 +75,141 @@ func (p *postgreSQLDB) GetPrimaryKey(tableName string) (string, error) {
 			AND tc.table_name = kcu.table_name
 		WHERE tc.constraint_type = 'PRIMARY KEY'
 		AND tc.table_schema = 'public'
-		AND tc.table_name = $1`
+		AND tc.table_name = $1
+		ORDER BY kcu.ordinal_position`
 
-	var primaryKey string
-	err := p.db.QueryRow(stmt, tableName).Scan(&primaryKey)
-	if err == sql.ErrNoRows {
-		// No primary key found
-		return "", nil
-	} else if err != nil {
-		// Other error occurred
-		return "", err
+	rows, err := p.db.QueryContext(ctx, stmt, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// No rows means the table has no primary key
+	var primaryKey []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		primaryKey = append(primaryKey, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := rows.Close(); err != nil {
+		return nil, err
 	}
 
-	// Primary key found
 	return primaryKey, nil
 }
 
-func (p *postgreSQLDB) GetSchemaRows() (*sql.Rows, error) {
+// EstimateRowCount returns the number of rows in the table according to the
+// planner's statistics, or 0 if the table hasn't been analyzed.
+func (p *postgreSQLDB) EstimateRowCount(ctx context.Context, tableName string) (int64, error) {
+	stmt := `
+		SELECT c.reltuples::bigint
+		FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public'
+		AND c.relname = $1`
+
+	var count int64
+	err := p.db.QueryRowContext(ctx, stmt, tableName).Scan(&count)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// Tables that have never been analyzed report -1
+	if count < 0 {
+		return 0, nil
+	}
+
+	return count, nil
+}
+
+// GetSchemaRows describes the columns of the tables in the public schema.
+// Columns of a domain are described by the base type of the domain, and
+// enums as enum, with their labels in MySQL's enum('a','b') form. PostGIS
+// columns are described as geometry or geography, with their full type, e.g.
+// geometry(Point,4326).
+func (p *postgreSQLDB) GetSchemaRows(ctx context.Context) (*sql.Rows, error) {
 	stmt := `
 	SELECT t1.table_name,
 	       t1.column_name,
-	       t1.data_type,
-	       t1.character_maximum_length
+	       CASE WHEN t1.data_type = 'ARRAY'
+	            THEN t1.udt_name::text::regtype::text
+	            WHEN enums.labels IS NOT NULL
+	            THEN 'enum'
+	            WHEN t1.udt_name IN ('geometry', 'geography')
+	            THEN t1.udt_name::text
+	            ELSE t1.data_type
+	       END,
+	       t1.character_maximum_length,
+	       t1.datetime_precision,
+	       CASE WHEN t1.udt_name IN ('geometry', 'geography')
+	            THEN (SELECT format_type(a.atttypid, a.atttypmod)
+	                  FROM   pg_attribute a
+	                  WHERE  a.attrelid = format('%I.%I', t1.table_schema, t1.table_name)::regclass
+	                         AND a.attname = t1.column_name)
+	            ELSE enums.labels
+	       END
 	FROM   information_schema.columns t1
 	       JOIN information_schema.tables t2
 	         ON t2.table_name = t1.table_name
 	            AND t2.table_type = 'BASE TABLE'
+	       LEFT JOIN LATERAL (
+	         SELECT 'enum(' || string_agg(quote_literal(e.enumlabel), ',' ORDER BY e.enumsortorder) || ')' AS labels
+	         FROM   pg_enum e
+	                JOIN pg_type t
+	                  ON t.oid = e.enumtypid
+	                JOIN pg_namespace n
+	                  ON n.oid = t.typnamespace
+	         WHERE  t.typname = t1.udt_name
+	                AND n.nspname = t1.udt_schema
+	       ) enums ON true
 	WHERE  t1.table_schema = 'public'
-	       AND t1.table_name NOT IN ('schema_migrations')
+	       AND t1.table_name NOT IN ('schema_migrations', $2)
 	       AND t1.table_catalog = $1`
 
-	rows, err := p.db.Query(stmt, p.dbName)
+	// The changelog of capture is pg2mysql's own, not a table to migrate
+	rows, err := p.db.QueryContext(ctx, stmt, p.dbName, ChangelogTable)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (p *postgreSQLDB) GetForeignKeyRows(ctx context.Context) (*sql.Rows, error) {
+	stmt := `
+	SELECT con.conname,
+	       src.relname,
+	       a.attname,
+	       dst.relname,
+	       af.attname
+	FROM   pg_constraint con
+	       JOIN pg_namespace n
+	         ON n.oid = con.connamespace
+	       JOIN pg_class src
+	         ON src.oid = con.conrelid
+	       JOIN pg_class dst
+	         ON dst.oid = con.confrelid
+	       CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, refattnum, position)
+	       JOIN pg_attribute a
+	         ON a.attrelid = con.conrelid
+	            AND a.attnum = k.attnum
+	       JOIN pg_attribute af
+	         ON af.attrelid = con.confrelid
+	            AND af.attnum = k.refattnum
+	WHERE  con.contype = 'f'
+	       AND n.nspname = 'public'
+	ORDER  BY src.relname, con.conname, k.position`
+
+	rows, err := p.db.QueryContext(ctx, stmt)
 	if err != nil {
 		return nil, err
 	}
@@ -116,10 +225,10 @@ func (p *postgreSQLDB) ColumnNameForSelect(name string) string {
 	return name
 }
 
-func (p *postgreSQLDB) EnableConstraints() error {
+func (p *postgreSQLDB) EnableConstraints(ctx context.Context) error {
 	panic("not implemented")
 }
 
-func (p *postgreSQLDB) DisableConstraints() error {
+func (p *postgreSQLDB) DisableConstraints(ctx context.Context) error {
 	panic("not implemented")
 }
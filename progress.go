@@ -0,0 +1,140 @@
+package pg2mysql
+
+import "time"
+
+const defaultProgressInterval = 10 * time.Second
+
+// MigrationProgress is how far a migration has got, in the table being
+// migrated and across all tables. Totals are estimated from the statistics
+// of the source and are 0 when it has none. Rows that were already in the
+// destination are skipped without being counted, so a migration that picks
+// up where another stopped finishes short of its totals.
+type MigrationProgress struct {
+	TableName    string
+	TableRows    int64
+	TableTotal   int64
+	TableElapsed time.Duration
+
+	Rows    int64
+	Total   int64
+	Elapsed time.Duration
+}
+
+// TableRate is the number of rows per second migrated in the current table.
+func (p MigrationProgress) TableRate() float64 {
+	return rate(p.TableRows, p.TableElapsed)
+}
+
+// TableETA is the estimated time left to migrate the current table. It is
+// false when there is no estimate.
+func (p MigrationProgress) TableETA() (time.Duration, bool) {
+	return eta(p.TableRows, p.TableTotal, p.TableElapsed)
+}
+
+// Rate is the number of rows per second migrated across all tables.
+func (p MigrationProgress) Rate() float64 {
+	return rate(p.Rows, p.Elapsed)
+}
+
+// ETA is the estimated time left to migrate all tables. It is false when
+// there is no estimate.
+func (p MigrationProgress) ETA() (time.Duration, bool) {
+	return eta(p.Rows, p.Total, p.Elapsed)
+}
+
+func rate(rows int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	return float64(rows) / elapsed.Seconds()
+}
+
+func eta(rows, total int64, elapsed time.Duration) (time.Duration, bool) {
+	if rows <= 0 || total <= 0 || elapsed <= 0 {
+		return 0, false
+	}
+
+	// The statistics may be out of date
+	if rows >= total {
+		return 0, true
+	}
+
+	return time.Duration(float64(elapsed) * float64(total-rows) / float64(rows)), true
+}
+
+// progressTracker counts the rows of a migration and passes its progress to
+// the watcher at most once per interval.
+type progressTracker struct {
+	watcher  MigratorWatcher
+	interval time.Duration
+	now      func() time.Time
+
+	started      time.Time
+	tableStarted time.Time
+	lastReported time.Time
+	progress     MigrationProgress
+
+	// reportedRows is how many rows of the table had been counted when its
+	// progress was last reported, -1 if it hasn't been
+	reportedRows int64
+}
+
+func newProgressTracker(watcher MigratorWatcher, interval time.Duration, total int64) *progressTracker {
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+
+	t := &progressTracker{
+		watcher:  watcher,
+		interval: interval,
+		now:      time.Now,
+	}
+	t.started = t.now()
+	t.lastReported = t.started
+	t.progress.Total = total
+
+	return t
+}
+
+func (t *progressTracker) startTable(tableName string, total int64) {
+	t.tableStarted = t.now()
+	t.lastReported = t.tableStarted
+	t.progress.TableName = tableName
+	t.progress.TableRows = 0
+	t.progress.TableTotal = total
+	t.reportedRows = -1
+}
+
+// rowDone counts a row that was inserted or failed to insert.
+func (t *progressTracker) rowDone() {
+	t.progress.TableRows++
+	t.progress.Rows++
+
+	now := t.now()
+	if now.Sub(t.lastReported) < t.interval {
+		return
+	}
+
+	t.report(now)
+}
+
+// finishTable reports the progress of a table that has been reported before,
+// so that the last report has all of its rows. Tables that finished within
+// the interval aren't reported at all.
+func (t *progressTracker) finishTable() {
+	if t.reportedRows < 0 || t.reportedRows == t.progress.TableRows {
+		return
+	}
+
+	t.report(t.now())
+}
+
+func (t *progressTracker) report(now time.Time) {
+	t.lastReported = now
+	t.reportedRows = t.progress.TableRows
+
+	t.progress.TableElapsed = now.Sub(t.tableStarted)
+	t.progress.Elapsed = now.Sub(t.started)
+	t.watcher.TableMigrationDidProgress(t.progress)
+}
@@ -0,0 +1,165 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("MigrationProgress", func() {
+	var progress pg2mysql.MigrationProgress
+
+	BeforeEach(func() {
+		progress = pg2mysql.MigrationProgress{
+			TableName:    "some_table",
+			TableRows:    250,
+			TableTotal:   1000,
+			TableElapsed: 10 * time.Second,
+			Rows:         750,
+			Total:        3000,
+			Elapsed:      30 * time.Second,
+		}
+	})
+
+	It("computes the rates", func() {
+		Expect(progress.TableRate()).To(Equal(25.0))
+		Expect(progress.Rate()).To(Equal(25.0))
+	})
+
+	It("estimates the time left from the rate so far", func() {
+		tableETA, ok := progress.TableETA()
+		Expect(ok).To(BeTrue())
+		Expect(tableETA).To(Equal(30 * time.Second))
+
+		eta, ok := progress.ETA()
+		Expect(ok).To(BeTrue())
+		Expect(eta).To(Equal(90 * time.Second))
+	})
+
+	It("has no estimate without a total", func() {
+		progress.TableTotal = 0
+
+		_, ok := progress.TableETA()
+		Expect(ok).To(BeFalse())
+	})
+
+	It("has nothing left once the total is passed", func() {
+		progress.TableRows = 1200
+
+		tableETA, ok := progress.TableETA()
+		Expect(ok).To(BeTrue())
+		Expect(tableETA).To(BeZero())
+	})
+})
+
+var _ = Describe("ProgressTracker", func() {
+	It("reports at most once per interval and reports all rows of a reported table when it finishes", func() {
+		now := time.Date(2017, 3, 24, 12, 0, 0, 0, time.UTC)
+		watcher := &pg2mysqlfakes.FakeMigratorWatcher{}
+		tracker := pg2mysql.NewProgressTracker(watcher, time.Second, 12, func() time.Time { return now })
+
+		tracker.StartTable("some_table", 10)
+		for i := 0; i < 10; i++ {
+			now = now.Add(300 * time.Millisecond)
+			tracker.RowDone()
+		}
+		tracker.FinishTable()
+
+		Expect(watcher.TableMigrationDidProgressCallCount()).To(Equal(3))
+		var tableRows []int64
+		for i := 0; i < 3; i++ {
+			tableRows = append(tableRows, watcher.TableMigrationDidProgressArgsForCall(i).TableRows)
+		}
+		Expect(tableRows).To(Equal([]int64{4, 8, 10}))
+
+		last := watcher.TableMigrationDidProgressArgsForCall(2)
+		Expect(last.TableName).To(Equal("some_table"))
+		Expect(last.TableElapsed).To(Equal(3 * time.Second))
+		Expect(last.Rows).To(BeEquivalentTo(10))
+		Expect(last.Total).To(BeEquivalentTo(12))
+
+		// Tables that finish within the interval aren't reported
+		tracker.StartTable("other_table", 2)
+		for i := 0; i < 2; i++ {
+			now = now.Add(100 * time.Millisecond)
+			tracker.RowDone()
+		}
+		tracker.FinishTable()
+		Expect(watcher.TableMigrationDidProgressCallCount()).To(Equal(3))
+	})
+})
+
+var _ = Describe("Migrating with progress", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+
+		config pg2mysql.MigrationConfig
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE counters (id int PRIMARY KEY);
+			INSERT INTO counters SELECT generate_series(1, 50);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec("CREATE TABLE counters (id int PRIMARY KEY)")
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE counters")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE counters")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reports the progress of the table, ending with all of its rows", func() {
+		config.ProgressInterval = time.Nanosecond
+		watcher := &pg2mysqlfakes.FakeMigratorWatcher{}
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, watcher).Migrate(context.Background(), config)).To(Succeed())
+
+		count := watcher.TableMigrationDidProgressCallCount()
+		Expect(count).To(BeNumerically(">", 0))
+		Expect(count).To(BeNumerically("<=", 50))
+
+		var previous int64
+		for i := 0; i < count; i++ {
+			progress := watcher.TableMigrationDidProgressArgsForCall(i)
+			Expect(progress.TableName).To(Equal("counters"))
+			Expect(progress.TableRows).To(BeNumerically(">", previous))
+			previous = progress.TableRows
+		}
+
+		last := watcher.TableMigrationDidProgressArgsForCall(count - 1)
+		Expect(last.TableRows).To(BeEquivalentTo(50))
+		Expect(last.Rows).To(BeEquivalentTo(50))
+	})
+
+	It("doesn't report tables that finish within the interval", func() {
+		config.ProgressInterval = time.Hour
+		watcher := &pg2mysqlfakes.FakeMigratorWatcher{}
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, watcher).Migrate(context.Background(), config)).To(Succeed())
+
+		Expect(watcher.TableMigrationDidProgressCallCount()).To(BeZero())
+		Expect(watcher.TableMigrationDidFinishCallCount()).To(Equal(1))
+	})
+})
@@ -0,0 +1,480 @@
+package pg2mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Repairer interface {
+	Repair(ctx context.Context, repairConfig RepairConfig) error
+}
+
+type RepairConfig struct {
+	MigrationConfig
+
+	DeleteExtraRows bool
+	DryRun          bool
+	BatchSize       int
+}
+
+func NewRepairer(src, dst DB, watcher RepairerWatcher) Repairer {
+	return &repairer{
+		src:     src,
+		dst:     dst,
+		watcher: watcher,
+	}
+}
+
+type repairer struct {
+	src, dst DB
+	watcher  RepairerWatcher
+}
+
+type repairCounts struct {
+	inserted, updated, deleted int64
+}
+
+func (r *repairer) Repair(ctx context.Context, repairConfig RepairConfig) error {
+	srcSchema, err := BuildSchema(ctx, r.src)
+	if err != nil {
+		return fmt.Errorf("failed to build source schema: %s", err)
+	}
+
+	dstSchema, err := BuildSchema(ctx, r.dst)
+	if err != nil {
+		return fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	options, err := conversionOptions(ctx, r.dst, repairConfig.MigrationConfig)
+	if err != nil {
+		return err
+	}
+
+	// Child tables of array columns are rebuilt by migrating, not repaired
+	splitArrayColumns(srcSchema, options.Arrays)
+
+	var failedTables []string
+	for _, table := range srcSchema.Tables {
+		if ignoreTable(table.Name, repairConfig.IgnoreTables) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r.watcher.TableRepairDidStart(table.Name)
+
+		dstTable, _ := dstSchema.GetTable(table.Name)
+		converter := NewRowConverter(table, dstTable, options)
+
+		counts, err := r.repairTable(ctx, table, converter, repairConfig)
+		if err != nil {
+			r.watcher.TableRepairDidFinishWithError(table.Name, err)
+			failedTables = append(failedTables, table.Name)
+			continue
+		}
+
+		r.watcher.TableRepairDidFinish(table.Name, counts.inserted, counts.updated, counts.deleted)
+	}
+
+	if len(failedTables) > 0 {
+		return fmt.Errorf("failed to repair tables: %s", strings.Join(failedTables, ", "))
+	}
+
+	return nil
+}
+
+func (r *repairer) repairTable(ctx context.Context, table *Table, converter *RowConverter, repairConfig RepairConfig) (repairCounts, error) {
+	var counts repairCounts
+
+	primaryKey, err := r.src.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return counts, fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+
+	if len(primaryKey) == 0 {
+		if repairConfig.DeleteExtraRows {
+			return counts, fmt.Errorf("cannot delete extra rows from table '%s' without a primary key", table.Name)
+		}
+
+		if err := r.checkRowsWithoutKey(ctx, table, converter); err != nil {
+			return counts, err
+		}
+	}
+
+	keyIndexes := make([]int, len(primaryKey))
+	isKey := make([]bool, len(table.Columns))
+	for i, column := range primaryKey {
+		keyIndexes[i], _, err = table.GetColumn(column)
+		if err != nil {
+			return counts, err
+		}
+		isKey[keyIndexes[i]] = true
+	}
+
+	batch := &repairBatch{
+		db:        r.dst.DB(),
+		tableName: table.Name,
+		dryRun:    repairConfig.DryRun,
+		size:      repairConfig.BatchSize,
+		watcher:   r.watcher,
+		stmts:     map[string]*sql.Stmt{},
+	}
+	defer batch.close()
+
+	insertQuery := insertStatement(table, converter)
+
+	// A table made up only of key columns has nothing to update; any
+	// mismatching row is simply missing
+	var existsStmt *sql.Stmt
+	var updateQuery string
+	if len(primaryKey) > 0 && len(primaryKey) < len(table.Columns) {
+		existsStmt, err = r.dst.DB().PrepareContext(ctx, fmt.Sprintf(
+			"SELECT EXISTS (SELECT 1 FROM `%s` WHERE %s)",
+			table.Name,
+			keyCondition(r.dst, primaryKey, "<=>", "?"),
+		))
+		if err != nil {
+			return counts, fmt.Errorf("failed to prepare statement: %s", err)
+		}
+		defer existsStmt.Close()
+
+		updateQuery = updateStatement(table, primaryKey, isKey, converter)
+	}
+
+	err = EachMissingRow(ctx, r.src, r.dst, table, converter, func(values []interface{}) error {
+		if err := converter.Check(values); err != nil {
+			return err
+		}
+
+		if existsStmt != nil {
+			keyValues := make([]interface{}, len(keyIndexes))
+			for i, index := range keyIndexes {
+				keyValues[i] = values[index]
+			}
+
+			var exists bool
+			if err := existsStmt.QueryRowContext(ctx, keyValues...).Scan(&exists); err != nil {
+				return fmt.Errorf("failed to check if row exists: %s", err)
+			}
+
+			if exists {
+				var args []interface{}
+				for i := range values {
+					if !isKey[i] {
+						args = append(args, values[i])
+					}
+				}
+				args = append(args, keyValues...)
+
+				counts.updated++
+				return batch.add(updateQuery, args, false)
+			}
+		}
+
+		counts.inserted++
+		return batch.add(insertQuery, values, true)
+	})
+	if err != nil {
+		// Keep the repairs found before an interruption
+		if ctx.Err() != nil {
+			if flushErr := batch.flush(); flushErr != nil {
+				return counts, flushErr
+			}
+		}
+		return counts, err
+	}
+
+	if repairConfig.DeleteExtraRows {
+		counts.deleted, err = r.deleteExtraRows(ctx, table, primaryKey, keyIndexes, converter, batch)
+		if err != nil {
+			return counts, err
+		}
+	}
+
+	if err = batch.flush(); err != nil {
+		return counts, err
+	}
+
+	return counts, nil
+}
+
+// checkRowsWithoutKey fails for a table without a primary key whose rows
+// missing from MySQL may have changed rather than be missing. A changed row
+// is missing from MySQL while its old version is still there, and inserting
+// it would leave both, so rows are only inserted when the row counts show
+// MySQL has none that PostgreSQL doesn't.
+func (r *repairer) checkRowsWithoutKey(ctx context.Context, table *Table, converter *RowConverter) error {
+	var missing int64
+	err := EachMissingRow(ctx, r.src, r.dst, table, converter, func([]interface{}) error {
+		missing++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if missing == 0 {
+		return nil
+	}
+
+	var srcRows, dstRows int64
+	if err := r.src.DB().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(1) FROM \"%s\"", table.Name)).Scan(&srcRows); err != nil {
+		return fmt.Errorf("failed to count rows: %s", err)
+	}
+	if err := r.dst.DB().QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(1) FROM `%s`", table.Name)).Scan(&dstRows); err != nil {
+		return fmt.Errorf("failed to count rows: %s", err)
+	}
+
+	if dstRows+missing != srcRows {
+		return fmt.Errorf("table '%s' has %d rows that differ in MySQL and no primary key to repair them by", table.Name, missing)
+	}
+
+	return nil
+}
+
+// deleteExtraRows queues a delete for every row in MySQL whose key no longer
+// exists in PostgreSQL. Keys are looked up in PostgreSQL as converter
+// reverses them, e.g. UUIDs packed into BINARY(16) as text.
+func (r *repairer) deleteExtraRows(ctx context.Context, table *Table, primaryKey []string, keyIndexes []int, converter *RowConverter, batch *repairBatch) (int64, error) {
+	srcKeyConditions := make([]string, len(primaryKey))
+	dstKeyColumns := make([]string, len(primaryKey))
+	for i, column := range primaryKey {
+		srcKeyConditions[i] = fmt.Sprintf("\"%s\" = $%d", column, i+1)
+		dstKeyColumns[i] = r.dst.ColumnNameForSelect(column)
+	}
+
+	existsStmt, err := r.src.DB().PrepareContext(ctx, fmt.Sprintf(
+		"SELECT EXISTS (SELECT 1 FROM \"%s\" WHERE %s)",
+		table.Name,
+		strings.Join(srcKeyConditions, " AND "),
+	))
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %s", err)
+	}
+	defer existsStmt.Close()
+
+	rows, err := r.dst.DB().QueryContext(ctx, fmt.Sprintf("SELECT %s FROM `%s`", strings.Join(dstKeyColumns, ","), table.Name))
+	if err != nil {
+		return 0, fmt.Errorf("failed to select primary key from rows: %s", err)
+	}
+	defer rows.Close()
+
+	deleteQuery := fmt.Sprintf("DELETE FROM `%s` WHERE %s", table.Name, keyCondition(r.dst, primaryKey, "=", "?"))
+
+	var deleted int64
+	for rows.Next() {
+		keyValues := make([]interface{}, len(primaryKey))
+		scanArgs := make([]interface{}, len(primaryKey))
+		for i := range keyValues {
+			scanArgs[i] = &keyValues[i]
+		}
+
+		if err = rows.Scan(scanArgs...); err != nil {
+			return deleted, fmt.Errorf("failed to scan id from row: %s", err)
+		}
+
+		srcKeyValues := make([]interface{}, len(keyValues))
+		for i, value := range keyValues {
+			srcKeyValues[i] = converter.Reverse(keyIndexes[i], value)
+		}
+
+		var exists bool
+		if err = existsStmt.QueryRowContext(ctx, srcKeyValues...).Scan(&exists); err != nil {
+			return deleted, fmt.Errorf("failed to check if row exists: %s", err)
+		}
+
+		if !exists {
+			if err = batch.add(deleteQuery, keyValues, false); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return deleted, fmt.Errorf("failed iterating through rows: %s", err)
+	}
+
+	return deleted, nil
+}
+
+func keyCondition(db DB, primaryKey []string, operator, placeholder string) string {
+	conditions := make([]string, len(primaryKey))
+	for i, column := range primaryKey {
+		conditions[i] = fmt.Sprintf("%s %s %s", db.ColumnNameForSelect(column), operator, placeholder)
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// updateStatement builds an UPDATE of every non-key column of table. Its
+// arguments are the non-key values in column order followed by the key.
+func updateStatement(table *Table, primaryKey []string, isKey []bool, converter *RowConverter) string {
+	var assignments []string
+	for i, column := range table.Columns {
+		if !isKey[i] {
+			assignments = append(assignments, fmt.Sprintf("`%s` = %s", column.Name, converter.WritePlaceholder(i)))
+		}
+	}
+
+	conditions := make([]string, len(primaryKey))
+	for i, column := range primaryKey {
+		conditions[i] = fmt.Sprintf("`%s` = ?", column)
+	}
+
+	return fmt.Sprintf(
+		"UPDATE `%s` SET %s WHERE %s",
+		table.Name,
+		strings.Join(assignments, ","),
+		strings.Join(conditions, " AND "),
+	)
+}
+
+type repairOperation struct {
+	query  string
+	args   []interface{}
+	insert bool
+}
+
+// repairBatch collects the statements needed to repair a table and runs
+// them in transactions of at most size statements. Transactions aren't
+// given the context of the run, so one that has started is committed even
+// when the run is interrupted.
+type repairBatch struct {
+	db         *sql.DB
+	tableName  string
+	dryRun     bool
+	size       int
+	watcher    RepairerWatcher
+	stmts      map[string]*sql.Stmt
+	operations []repairOperation
+}
+
+func (b *repairBatch) add(query string, args []interface{}, isInsert bool) error {
+	b.operations = append(b.operations, repairOperation{
+		query:  query,
+		args:   args,
+		insert: isInsert,
+	})
+
+	if len(b.operations) >= b.size {
+		return b.flush()
+	}
+
+	return nil
+}
+
+func (b *repairBatch) flush() error {
+	if len(b.operations) == 0 {
+		return nil
+	}
+
+	operations := b.operations
+	b.operations = nil
+
+	if b.dryRun {
+		b.watcher.WouldExecuteStatement(b.tableName, "START TRANSACTION")
+		for _, operation := range operations {
+			b.watcher.WouldExecuteStatement(b.tableName, renderStatement(operation.query, operation.args))
+		}
+		b.watcher.WouldExecuteStatement(b.tableName, "COMMIT")
+		return nil
+	}
+
+	tx, err := b.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %s", err)
+	}
+
+	for _, operation := range operations {
+		stmt, err := b.prepare(operation.query)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		txStmt := tx.Stmt(stmt)
+		if operation.insert {
+			err = insert(txStmt, operation.args)
+		} else {
+			_, err = txStmt.Exec(operation.args...)
+		}
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed repairing %s: %s", b.tableName, err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %s", err)
+	}
+
+	return nil
+}
+
+func (b *repairBatch) prepare(query string) (*sql.Stmt, error) {
+	if stmt, ok := b.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := b.db.Prepare(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating prepared statement: %s", err)
+	}
+	b.stmts[query] = stmt
+
+	return stmt, nil
+}
+
+func (b *repairBatch) close() {
+	for _, stmt := range b.stmts {
+		stmt.Close()
+	}
+}
+
+// renderStatement inlines args into the placeholders of query so it can be
+// shown to the user. The result is for display only and is never executed.
+func renderStatement(query string, args []interface{}) string {
+	var buf strings.Builder
+	argIndex := 0
+	for _, r := range query {
+		if r == '?' && argIndex < len(args) {
+			buf.WriteString(sqlLiteral(args[argIndex]))
+			argIndex++
+			continue
+		}
+		buf.WriteRune(r)
+	}
+
+	return buf.String()
+}
+
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64, int32, int, float64, float32:
+		return fmt.Sprintf("%v", v)
+	case time.Time:
+		return quoteString(v.Format("2006-01-02 15:04:05.999999"))
+	case []byte:
+		return quoteString(string(v))
+	default:
+		return quoteString(fmt.Sprintf("%v", v))
+	}
+}
+
+func quoteString(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`, "\n", `\n`, "\r", `\r`, "\x1a", `\Z`)
+	return "'" + replacer.Replace(s) + "'"
+}
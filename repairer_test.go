@@ -0,0 +1,266 @@
+package pg2mysql_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("Repairer", func() {
+	var (
+		mysql   pg2mysql.DB
+		pg      pg2mysql.DB
+		watcher *pg2mysqlfakes.FakeRepairerWatcher
+
+		config = pg2mysql.RepairConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			DeleteExtraRows: true,
+			BatchSize:       10,
+		}
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE accounts (
+				id int PRIMARY KEY,
+				name varchar(50)
+			);
+			INSERT INTO accounts VALUES (1, 'a'), (2, 'b'), (3, 'c');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE accounts (id int PRIMARY KEY, name varchar(50))",
+			"INSERT INTO accounts VALUES (1, 'a'), (2, 'stale'), (4, 'extra')",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		watcher = &pg2mysqlfakes.FakeRepairerWatcher{}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE accounts")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE accounts")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	accounts := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(id, ':', name) FROM accounts ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var accounts []string
+		for rows.Next() {
+			var account string
+			Expect(rows.Scan(&account)).To(Succeed())
+			accounts = append(accounts, account)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return accounts
+	}
+
+	It("inserts missing rows, updates drifted rows and deletes extra rows", func() {
+		Expect(pg2mysql.NewRepairer(pg, mysql, watcher).Repair(context.Background(), config)).To(Succeed())
+
+		Expect(accounts()).To(Equal([]string{"1:a", "2:b", "3:c"}))
+
+		Expect(watcher.TableRepairDidFinishCallCount()).To(Equal(1))
+		tableName, inserted, updated, deleted := watcher.TableRepairDidFinishArgsForCall(0)
+		Expect(tableName).To(Equal("accounts"))
+		Expect([]int64{inserted, updated, deleted}).To(Equal([]int64{1, 1, 1}))
+	})
+
+	It("only reports the statements it would run in a dry run", func() {
+		dryRun := config
+		dryRun.DryRun = true
+		Expect(pg2mysql.NewRepairer(pg, mysql, watcher).Repair(context.Background(), dryRun)).To(Succeed())
+
+		Expect(accounts()).To(Equal([]string{"1:a", "2:stale", "4:extra"}))
+
+		var statements []string
+		for i := 0; i < watcher.WouldExecuteStatementCallCount(); i++ {
+			_, stmt := watcher.WouldExecuteStatementArgsForCall(i)
+			statements = append(statements, stmt)
+		}
+		Expect(statements).To(Equal([]string{
+			"START TRANSACTION",
+			"UPDATE `accounts` SET `name` = 'b' WHERE `id` = 2",
+			"INSERT INTO `accounts` (`id`,`name`) VALUES (3,'c')",
+			"DELETE FROM `accounts` WHERE `id` = 4",
+			"COMMIT",
+		}))
+	})
+})
+
+var _ = Describe("Repairing packed UUID keys", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE sessions (
+				id uuid PRIMARY KEY,
+				name text
+			);
+			INSERT INTO sessions VALUES
+				('6ccd780c-baba-1026-9564-5b8c656024db', 'a'),
+				('7ccd780c-baba-1026-9564-5b8c656024db', 'b');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE sessions (id binary(16) PRIMARY KEY, name text)",
+			`INSERT INTO sessions VALUES
+				(UNHEX('6ccd780cbaba102695645b8c656024db'), 'a'),
+				(UNHEX('7ccd780cbaba102695645b8c656024db'), 'stale'),
+				(UNHEX('8ccd780cbaba102695645b8c656024db'), 'extra')`,
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE sessions")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE sessions")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("looks up the keys of MySQL rows in PostgreSQL as UUIDs", func() {
+		watcher := &pg2mysqlfakes.FakeRepairerWatcher{}
+		config := pg2mysql.RepairConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			DeleteExtraRows: true,
+			BatchSize:       10,
+		}
+		Expect(pg2mysql.NewRepairer(pg, mysql, watcher).Repair(context.Background(), config)).To(Succeed())
+
+		Expect(watcher.TableRepairDidFinishCallCount()).To(Equal(1))
+		_, inserted, updated, deleted := watcher.TableRepairDidFinishArgsForCall(0)
+		Expect([]int64{inserted, updated, deleted}).To(Equal([]int64{0, 1, 1}))
+
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(HEX(id), ':', name) FROM sessions ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var sessions []string
+		for rows.Next() {
+			var session string
+			Expect(rows.Scan(&session)).To(Succeed())
+			sessions = append(sessions, session)
+		}
+		Expect(sessions).To(Equal([]string{
+			"6CCD780CBABA102695645B8C656024DB:a",
+			"7CCD780CBABA102695645B8C656024DB:b",
+		}))
+	})
+})
+
+var _ = Describe("Repairing tables without a primary key", func() {
+	var (
+		mysql   pg2mysql.DB
+		pg      pg2mysql.DB
+		watcher *pg2mysqlfakes.FakeRepairerWatcher
+
+		config = pg2mysql.RepairConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			BatchSize: 10,
+		}
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE events (name text);
+			INSERT INTO events VALUES ('a'), ('b'), ('c');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec("CREATE TABLE events (name text)")
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		watcher = &pg2mysqlfakes.FakeRepairerWatcher{}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE events")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE events")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	events := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT name FROM events ORDER BY name")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var events []string
+		for rows.Next() {
+			var event string
+			Expect(rows.Scan(&event)).To(Succeed())
+			events = append(events, event)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return events
+	}
+
+	It("inserts rows that are only missing", func() {
+		_, err := mysqlRunner.DB().Exec("INSERT INTO events VALUES ('a'), ('b')")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pg2mysql.NewRepairer(pg, mysql, watcher).Repair(context.Background(), config)).To(Succeed())
+		Expect(events()).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("reports rows that changed without repairing them", func() {
+		_, err := mysqlRunner.DB().Exec("INSERT INTO events VALUES ('a'), ('b'), ('stale')")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = pg2mysql.NewRepairer(pg, mysql, watcher).Repair(context.Background(), config)
+		Expect(err).To(MatchError("failed to repair tables: events"))
+
+		Expect(watcher.TableRepairDidFinishWithErrorCallCount()).To(Equal(1))
+		_, repairErr := watcher.TableRepairDidFinishWithErrorArgsForCall(0)
+		Expect(repairErr).To(MatchError("table 'events' has 1 rows that differ in MySQL and no primary key to repair them by"))
+		Expect(events()).To(Equal([]string{"a", "b", "stale"}))
+	})
+})
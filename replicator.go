@@ -0,0 +1,405 @@
+package pg2mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Replicator interface {
+	Replicate(ctx context.Context, replicationConfig ReplicationConfig) error
+}
+
+type ReplicationConfig struct {
+	MigrationConfig
+
+	// SlotName is the logical replication slot changes are read from. It
+	// is created, using the wal2json plugin, if it doesn't exist. Slots of
+	// other plugins, such as pgoutput, aren't supported.
+	SlotName string
+
+	// StateFile records whether the snapshot was taken and the last LSN
+	// applied, so that replication resumes where it stopped.
+	StateFile string
+
+	// PollInterval is how long to wait for changes when there are none.
+	PollInterval time.Duration
+
+	// BatchSize is roughly how many changes are read and applied to MySQL
+	// in one transaction. Transactions in PostgreSQL are never split.
+	BatchSize int
+}
+
+// ReplicationProgress is reported after each batch of changes, and every
+// ProgressInterval while there are none.
+type ReplicationProgress struct {
+	ChangesApplied int64
+	ConfirmedLSN   string
+	LagBytes       int64
+}
+
+// ReplicationState is what the StateFile of a replication records.
+type ReplicationState struct {
+	SlotName     string `json:"slot_name"`
+	SnapshotDone bool   `json:"snapshot_done"`
+	ConfirmedLSN string `json:"confirmed_lsn,omitempty"`
+}
+
+// ReadReplicationState reads the replication state at path. A missing file is
+// a replication that hasn't started.
+func ReadReplicationState(path string) (*ReplicationState, error) {
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &ReplicationState{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var state ReplicationState
+	if err := json.Unmarshal(bs, &state); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func WriteReplicationState(path string, state *ReplicationState) error {
+	bs, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, append(bs, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// NewReplicator returns a Replicator that takes its initial snapshot with
+// snapshot, then applies the changes decoded from a logical replication
+// slot to MySQL until its context is cancelled.
+func NewReplicator(src, dst DB, snapshot Migrator, watcher ReplicatorWatcher) Replicator {
+	return &replicator{
+		src:      src,
+		dst:      dst,
+		snapshot: snapshot,
+		watcher:  watcher,
+	}
+}
+
+type replicator struct {
+	src, dst DB
+	snapshot Migrator
+	watcher  ReplicatorWatcher
+}
+
+// replicationPlugin is the only output plugin changes are decoded from.
+const replicationPlugin = "wal2json"
+
+// wal2jsonOptions select the wal2json output with one change per row, each
+// with the types of its columns.
+var wal2jsonOptions = `'format-version', '2', 'include-types', 'true'`
+
+func (r *replicator) Replicate(ctx context.Context, replicationConfig ReplicationConfig) error {
+	state, err := ReadReplicationState(replicationConfig.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read replication state: %s", err)
+	}
+
+	exists, err := r.slotExists(ctx, replicationConfig.SlotName)
+	if err != nil {
+		return err
+	}
+
+	// Changes made while the snapshot is taken are applied after it, so the
+	// slot has to exist before the snapshot starts
+	if !exists {
+		var slotName, lsn string
+		err = r.src.DB().QueryRowContext(ctx,
+			"SELECT slot_name, lsn::text FROM pg_create_logical_replication_slot($1, $2)",
+			replicationConfig.SlotName,
+			replicationPlugin,
+		).Scan(&slotName, &lsn)
+		if isUndefinedFile(err) {
+			return fmt.Errorf("failed to create replication slot: the %s plugin isn't installed on the PostgreSQL server; use capture where it can't be installed", replicationPlugin)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create replication slot: %s", err)
+		}
+
+		state = &ReplicationState{SlotName: slotName}
+		if err := WriteReplicationState(replicationConfig.StateFile, state); err != nil {
+			return fmt.Errorf("failed to write replication state: %s", err)
+		}
+
+		r.watcher.ReplicationSlotDidCreate(slotName, lsn)
+	}
+
+	if !state.SnapshotDone {
+		err = r.snapshot.Migrate(ctx, replicationConfig.MigrationConfig)
+		if err != nil {
+			return fmt.Errorf("failed taking snapshot: %s", err)
+		}
+
+		state.SlotName = replicationConfig.SlotName
+		state.SnapshotDone = true
+		if err := WriteReplicationState(replicationConfig.StateFile, state); err != nil {
+			return fmt.Errorf("failed to write replication state: %s", err)
+		}
+	}
+
+	applier, err := newChangeApplier(ctx, r.src, r.dst, replicationConfig.MigrationConfig)
+	if err != nil {
+		return err
+	}
+
+	pollInterval := replicationConfig.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	progressInterval := replicationConfig.ProgressInterval
+	if progressInterval <= 0 {
+		progressInterval = defaultProgressInterval
+	}
+
+	var lastReported time.Time
+	for {
+		applied, err := r.applyBatch(ctx, applier, replicationConfig, state)
+		if err != nil {
+			// Stopping is how replication ends once it has caught up
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+
+		if applied > 0 || time.Since(lastReported) >= progressInterval {
+			lag, err := r.lag(ctx, replicationConfig.SlotName)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+				return err
+			}
+
+			r.watcher.ReplicationDidProgress(ReplicationProgress{
+				ChangesApplied: applied,
+				ConfirmedLSN:   state.ConfirmedLSN,
+				LagBytes:       lag,
+			})
+			lastReported = time.Now()
+		}
+
+		if applied > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(pollInterval):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	r.watcher.ReplicationDidStop(state.ConfirmedLSN)
+
+	return nil
+}
+
+// slotExists is whether the slot exists. A slot of another plugin than
+// wal2json is an error, as its changes can't be decoded.
+func (r *replicator) slotExists(ctx context.Context, slotName string) (bool, error) {
+	var plugin sql.NullString
+	err := r.src.DB().QueryRowContext(ctx,
+		"SELECT plugin FROM pg_replication_slots WHERE slot_name = $1",
+		slotName,
+	).Scan(&plugin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up replication slot: %s", err)
+	}
+
+	if plugin.String != replicationPlugin {
+		return false, fmt.Errorf("replication slot '%s' uses the '%s' plugin; only %s is supported", slotName, plugin.String, replicationPlugin)
+	}
+
+	return true, nil
+}
+
+// isUndefinedFile is whether err is PostgreSQL failing to find a file, as it
+// does for an output plugin that isn't installed.
+func isUndefinedFile(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "58P01"
+}
+
+// lag is how many bytes of WAL the slot has yet to confirm.
+func (r *replicator) lag(ctx context.Context, slotName string) (int64, error) {
+	var lag sql.NullInt64
+	err := r.src.DB().QueryRowContext(ctx,
+		"SELECT pg_wal_lsn_diff(pg_current_wal_lsn(), confirmed_flush_lsn)::bigint FROM pg_replication_slots WHERE slot_name = $1",
+		slotName,
+	).Scan(&lag)
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure replication lag: %s", err)
+	}
+
+	return lag.Int64, nil
+}
+
+// wal2jsonChange is a change in the wal2json format version 2.
+type wal2jsonChange struct {
+	Action   string           `json:"action"`
+	Schema   string           `json:"schema"`
+	Table    string           `json:"table"`
+	Columns  []wal2jsonColumn `json:"columns"`
+	Identity []wal2jsonColumn `json:"identity"`
+}
+
+type wal2jsonColumn struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+func wal2jsonValues(columns []wal2jsonColumn) map[string]interface{} {
+	if columns == nil {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		values[column.Name] = column.Value
+	}
+
+	return values
+}
+
+// applyBatch applies the next changes in the slot to MySQL in a transaction
+// and only then consumes them from the slot, so changes are applied again
+// rather than lost when replication stops in between.
+func (r *replicator) applyBatch(ctx context.Context, applier *changeApplier, replicationConfig ReplicationConfig, state *ReplicationState) (int64, error) {
+	batchSize := replicationConfig.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	rows, err := r.src.DB().QueryContext(ctx,
+		fmt.Sprintf("SELECT lsn::text, data FROM pg_logical_slot_peek_changes($1, NULL, $2, %s)", wal2jsonOptions),
+		replicationConfig.SlotName,
+		batchSize,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read changes: %s", err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	var lastCommit string
+	for rows.Next() {
+		var lsn string
+		var data []byte
+		if err := rows.Scan(&lsn, &data); err != nil {
+			return 0, fmt.Errorf("failed to scan change: %s", err)
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		var change wal2jsonChange
+		if err := decoder.Decode(&change); err != nil {
+			return 0, fmt.Errorf("failed to decode change at %s: %s", lsn, err)
+		}
+
+		// Only the tables of the public schema are migrated, so changes to
+		// tables of the same name in other schemas are skipped
+		if change.Schema != "" && change.Schema != "public" {
+			continue
+		}
+
+		switch change.Action {
+		case "C":
+			lastCommit = lsn
+		case "I":
+			changes = append(changes, Change{Kind: ChangeInsert, Table: change.Table, Columns: wal2jsonValues(change.Columns)})
+		case "U":
+			changes = append(changes, Change{Kind: ChangeUpdate, Table: change.Table, Columns: wal2jsonValues(change.Columns), Key: wal2jsonValues(change.Identity)})
+		case "D":
+			changes = append(changes, Change{Kind: ChangeDelete, Table: change.Table, Key: wal2jsonValues(change.Identity)})
+		case "T":
+			changes = append(changes, Change{Kind: ChangeTruncate, Table: change.Table})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed iterating through changes: %s", err)
+	}
+
+	if lastCommit == "" {
+		return 0, nil
+	}
+
+	// Like repairs, a batch that has started is committed even when
+	// replication is stopped
+	tx, err := r.dst.DB().Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %s", err)
+	}
+
+	for _, change := range changes {
+		if err := applier.apply(ctx, tx, change); err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %s", err)
+	}
+
+	_, err = r.src.DB().ExecContext(ctx,
+		fmt.Sprintf("SELECT count(*) FROM pg_logical_slot_get_changes($1, $2::pg_lsn, NULL, %s)", wal2jsonOptions),
+		replicationConfig.SlotName,
+		lastCommit,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to confirm changes: %s", err)
+	}
+
+	state.ConfirmedLSN = lastCommit
+	if err := WriteReplicationState(replicationConfig.StateFile, state); err != nil {
+		return 0, fmt.Errorf("failed to write replication state: %s", err)
+	}
+
+	return int64(len(changes)), nil
+}
+
+// DropReplicationSlot drops the replication slot once replication is no
+// longer needed, along with its state file. Until it is dropped, the slot
+// keeps PostgreSQL from removing WAL it hasn't consumed.
+func DropReplicationSlot(ctx context.Context, src DB, slotName, stateFile string) error {
+	_, err := src.DB().ExecContext(ctx, "SELECT pg_drop_replication_slot($1)", slotName)
+	if err != nil {
+		return fmt.Errorf("failed to drop replication slot: %s", err)
+	}
+
+	if err := os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("ReplicationState", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pg2mysql-replication")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(dir, "replication.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("has not taken the snapshot when there is no file", func() {
+		state, err := pg2mysql.ReadReplicationState(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.SnapshotDone).To(BeFalse())
+		Expect(state.ConfirmedLSN).To(BeEmpty())
+	})
+
+	It("reads back the state that was written", func() {
+		written := &pg2mysql.ReplicationState{
+			SlotName:     "pg2mysql",
+			SnapshotDone: true,
+			ConfirmedLSN: "0/16B3748",
+		}
+		err := pg2mysql.WriteReplicationState(path, written)
+		Expect(err).NotTo(HaveOccurred())
+
+		state, err := pg2mysql.ReadReplicationState(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state).To(Equal(written))
+	})
+})
+
+var _ = Describe("Replicator", func() {
+	var (
+		mysql   pg2mysql.DB
+		pg      pg2mysql.DB
+		dir     string
+		watcher *pg2mysqlfakes.FakeReplicatorWatcher
+		config  pg2mysql.ReplicationConfig
+
+		cancel context.CancelFunc
+		done   chan error
+	)
+
+	BeforeEach(func() {
+		var walLevel string
+		Expect(pgRunner.DB().QueryRow("SHOW wal_level").Scan(&walLevel)).To(Succeed())
+		if walLevel != "logical" {
+			Skip("PostgreSQL isn't running with wal_level = logical")
+		}
+
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE items (
+				id int PRIMARY KEY,
+				name text,
+				body text
+			);
+			ALTER TABLE items ALTER COLUMN body SET STORAGE EXTERNAL;
+			INSERT INTO items VALUES (1, 'a', repeat('x', 10000)), (2, 'b', NULL);`)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = mysqlRunner.DB().Exec("CREATE TABLE items (id int PRIMARY KEY, name text, body mediumtext)")
+		Expect(err).NotTo(HaveOccurred())
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		dir, err = ioutil.TempDir("", "pg2mysql-replication")
+		Expect(err).NotTo(HaveOccurred())
+
+		watcher = &pg2mysqlfakes.FakeReplicatorWatcher{}
+		config = pg2mysql.ReplicationConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			SlotName:     "pg2mysql_test",
+			StateFile:    filepath.Join(dir, "replication.json"),
+			PollInterval: 10 * time.Millisecond,
+		}
+	})
+
+	AfterEach(func() {
+		if mysql == nil {
+			return
+		}
+
+		if cancel != nil {
+			cancel()
+			<-done
+			cancel = nil
+		}
+
+		_, err := pgRunner.DB().Exec("SELECT pg_drop_replication_slot(slot_name) FROM pg_replication_slots WHERE slot_name = $1", config.SlotName)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+		mysql, pg = nil, nil
+		os.RemoveAll(dir)
+
+		_, err = pgRunner.DB().Exec("DROP TABLE items")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE items")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	replicate := func() {
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		done = make(chan error, 1)
+
+		snapshot := pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{})
+		replicator := pg2mysql.NewReplicator(pg, mysql, snapshot, watcher)
+		go func() {
+			defer GinkgoRecover()
+			done <- replicator.Replicate(ctx, config)
+		}()
+	}
+
+	stop := func() error {
+		cancel()
+		cancel = nil
+		return <-done
+	}
+
+	exec := func(stmts ...string) {
+		for _, stmt := range stmts {
+			_, err := pgRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	items := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(id, ':', name, ':', COALESCE(LENGTH(body), 0)) FROM items ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var items []string
+		for rows.Next() {
+			var item string
+			Expect(rows.Scan(&item)).To(Succeed())
+			items = append(items, item)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return items
+	}
+
+	It("applies inserts, updates and deletes, including updates of the key", func() {
+		replicate()
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0"}))
+
+		exec(
+			"INSERT INTO items VALUES (3, 'c', 'some-body')",
+			"UPDATE items SET name = 'd' WHERE id = 2",
+			"UPDATE items SET id = 4 WHERE id = 3",
+			"DELETE FROM items WHERE id = 1",
+		)
+		Eventually(items, 10*time.Second).Should(Equal([]string{"2:d:0", "4:c:9"}))
+
+		Expect(stop()).To(Succeed())
+		Expect(watcher.ReplicationDidStopCallCount()).To(Equal(1))
+	})
+
+	It("creates the slot once and resumes from the last change applied", func() {
+		replicate()
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0"}))
+		exec("INSERT INTO items VALUES (3, 'c', NULL)")
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0", "3:c:0"}))
+		Expect(stop()).To(Succeed())
+
+		state, err := pg2mysql.ReadReplicationState(config.StateFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.SnapshotDone).To(BeTrue())
+		Expect(state.ConfirmedLSN).NotTo(BeEmpty())
+		Expect(watcher.ReplicationDidStopArgsForCall(0)).To(Equal(state.ConfirmedLSN))
+
+		// Rows only in MySQL show that the snapshot isn't taken again
+		_, err = mysqlRunner.DB().Exec("INSERT INTO items VALUES (9, 'z', NULL)")
+		Expect(err).NotTo(HaveOccurred())
+		exec("UPDATE items SET name = 'd' WHERE id = 3")
+
+		replicate()
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0", "3:d:0", "9:z:0"}))
+		Expect(stop()).To(Succeed())
+
+		Expect(watcher.ReplicationSlotDidCreateCallCount()).To(Equal(1))
+		slotName, _ := watcher.ReplicationSlotDidCreateArgsForCall(0)
+		Expect(slotName).To(Equal("pg2mysql_test"))
+	})
+
+	It("skips changes to tables of the same name in other schemas", func() {
+		exec(
+			"CREATE SCHEMA other",
+			"CREATE TABLE other.items (id int PRIMARY KEY, name text, body text)",
+		)
+		defer exec("DROP SCHEMA other CASCADE")
+
+		replicate()
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0"}))
+
+		exec(
+			"INSERT INTO other.items VALUES (7, 'other', NULL)",
+			"DELETE FROM other.items",
+			"INSERT INTO other.items VALUES (8, 'other', NULL)",
+			"INSERT INTO items VALUES (3, 'c', NULL)",
+		)
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0", "3:c:0"}))
+		Consistently(items, 100*time.Millisecond).Should(Equal([]string{"1:a:10000", "2:b:0", "3:c:0"}))
+
+		Expect(stop()).To(Succeed())
+	})
+
+	It("fails for a slot of another output plugin", func() {
+		exec("SELECT pg_create_logical_replication_slot('pg2mysql_test', 'test_decoding')")
+
+		snapshot := pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{})
+		err := pg2mysql.NewReplicator(pg, mysql, snapshot, watcher).Replicate(context.Background(), config)
+		Expect(err).To(MatchError("replication slot 'pg2mysql_test' uses the 'test_decoding' plugin; only wal2json is supported"))
+		Expect(items()).To(BeEmpty())
+	})
+
+	It("keeps TOASTed values an update didn't change", func() {
+		replicate()
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:a:10000", "2:b:0"}))
+
+		exec("UPDATE items SET name = 'c' WHERE id = 1")
+		Eventually(items, 10*time.Second).Should(Equal([]string{"1:c:10000", "2:b:0"}))
+
+		exec("UPDATE items SET id = 3, name = 'd' WHERE id = 1")
+		Eventually(items, 10*time.Second).Should(Equal([]string{"2:b:0", "3:d:10000"}))
+
+		Expect(stop()).To(Succeed())
+	})
+})
@@ -0,0 +1,150 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("Row IDs", func() {
+	Describe("FormatRowID", func() {
+		It("prints single column keys as-is and composite keys as a tuple", func() {
+			Expect(pg2mysql.FormatRowID([]interface{}{int64(3)})).To(Equal("3"))
+			Expect(pg2mysql.FormatRowID([]interface{}{[]byte("abc")})).To(Equal("abc"))
+			Expect(pg2mysql.FormatRowID([]interface{}{int64(1), "a", nil})).To(Equal("(1,a,NULL)"))
+			Expect(pg2mysql.FormatRowID([]interface{}{time.Date(2017, 3, 24, 12, 30, 15, 0, time.UTC)})).To(Equal("2017-03-24T12:30:15Z"))
+		})
+	})
+
+	Describe("FormatRowDigest", func() {
+		It("tells rows apart by all of their values", func() {
+			digest := pg2mysql.FormatRowDigest([]interface{}{"a", nil})
+			Expect(digest).To(HavePrefix("sha1:"))
+			Expect(pg2mysql.FormatRowDigest([]interface{}{[]byte("a"), nil})).To(Equal(digest))
+			Expect(pg2mysql.FormatRowDigest([]interface{}{"a", "NULL"})).NotTo(Equal(digest))
+			Expect(pg2mysql.FormatRowDigest([]interface{}{nil, "a"})).NotTo(Equal(digest))
+		})
+	})
+})
+
+var _ = Describe("Verifying rows by primary key", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE memberships (
+				org_id int,
+				user_id int,
+				PRIMARY KEY (org_id, user_id)
+			);
+			INSERT INTO memberships VALUES (1, 1), (1, 2);
+			CREATE TABLE events (name text);
+			INSERT INTO events VALUES ('a'), ('b');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE memberships (org_id int, user_id int, PRIMARY KEY (org_id, user_id))",
+			"INSERT INTO memberships VALUES (1, 1)",
+			"CREATE TABLE events (name text)",
+			"INSERT INTO events VALUES ('a')",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE memberships, events")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE memberships, events")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reports missing rows by their composite key, or by a digest without one", func() {
+		watcher := &pg2mysqlfakes.FakeVerifierWatcher{}
+		Expect(pg2mysql.NewVerifier(pg, mysql, watcher).Verify(context.Background(), config)).To(Succeed())
+
+		missing := map[string][]string{}
+		for i := 0; i < watcher.TableVerificationDidFinishCallCount(); i++ {
+			tableName, missingRows, missingIDs := watcher.TableVerificationDidFinishArgsForCall(i)
+			Expect(missingRows).To(BeEquivalentTo(len(missingIDs)))
+			missing[tableName] = missingIDs
+		}
+
+		Expect(missing).To(Equal(map[string][]string{
+			"memberships": {"(1,2)"},
+			"events":      {pg2mysql.FormatRowDigest([]interface{}{"b"})},
+		}))
+	})
+})
+
+var _ = Describe("GetIncompatibleRowIDsAndColumns", func() {
+	var pg pg2mysql.DB
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE notes (id int PRIMARY KEY, body text);
+			INSERT INTO notes VALUES (1, 'short'), (2, 'much too long');
+			CREATE TABLE keyless_notes (body text);
+			INSERT INTO keyless_notes VALUES ('short'), ('much too long');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE notes, keyless_notes")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	table := func(name string, maxChars int64) *pg2mysql.Table {
+		return &pg2mysql.Table{
+			Name: name,
+			Columns: []*pg2mysql.Column{
+				{Name: "id", Type: "integer"},
+				{Name: "body", Type: "text", MaxChars: maxChars},
+			},
+		}
+	}
+
+	It("looks up the primary key of the source table", func() {
+		rowIDs, columns, err := pg2mysql.GetIncompatibleRowIDsAndColumns(context.Background(), pg, table("notes", 0), table("mysql_notes", 5))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rowIDs).To(Equal([]string{"2"}))
+		Expect(columns).To(Equal([]pg2mysql.IncompatibleColumnMetadata{{ColumnName: "body", MaxChars: 13}}))
+	})
+
+	It("only returns the columns of tables without a primary key", func() {
+		src := &pg2mysql.Table{Name: "keyless_notes", Columns: []*pg2mysql.Column{{Name: "body", Type: "text"}}}
+		dst := &pg2mysql.Table{Name: "keyless_notes", Columns: []*pg2mysql.Column{{Name: "body", Type: "text", MaxChars: 5}}}
+
+		rowIDs, columns, err := pg2mysql.GetIncompatibleRowIDsAndColumns(context.Background(), pg, src, dst)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rowIDs).To(BeEmpty())
+		Expect(columns).To(Equal([]pg2mysql.IncompatibleColumnMetadata{{ColumnName: "body", MaxChars: 13}}))
+	})
+})
@@ -0,0 +1,228 @@
+package pg2mysql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// SpecialValueAction is what is done with a value MySQL rejects in strict
+// mode: NaN or Infinity in a real, double precision or numeric column, or
+// text with characters outside the Basic Multilingual Plane, which the utf8
+// connections pg2mysql writes with can't carry, or with NUL characters.
+type SpecialValueAction string
+
+const (
+	// SpecialValueFail fails the row, as if it had failed to insert,
+	// without writing it.
+	SpecialValueFail SpecialValueAction = "fail"
+	// SpecialValueNull writes NULL instead.
+	SpecialValueNull SpecialValueAction = "null"
+	// SpecialValueSentinel writes the sentinel of the policy instead.
+	SpecialValueSentinel SpecialValueAction = "sentinel"
+)
+
+// SpecialValuePolicy is what is done with the special values of a single
+// column. Columns without one are written as they are, and MySQL decides.
+type SpecialValuePolicy struct {
+	Action SpecialValueAction `yaml:"action"`
+
+	// Sentinel is written in place of special values in sentinel mode,
+	// e.g. -1. MySQL converts it to the type of the column.
+	Sentinel string `yaml:"sentinel"`
+}
+
+// SpecialValuePolicies are the special value policies of each table, by
+// table and column name.
+type SpecialValuePolicies map[string]map[string]SpecialValuePolicy
+
+func (p SpecialValuePolicies) validate() error {
+	for table, columns := range p {
+		for column, policy := range columns {
+			switch policy.Action {
+			case SpecialValueFail, SpecialValueNull, SpecialValueSentinel:
+			default:
+				return fmt.Errorf("unknown special value action '%s' for %s.%s", policy.Action, table, column)
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookup returns the policy of column of table, if it is a column that can
+// hold special values and has one.
+func (p SpecialValuePolicies) lookup(table string, column *Column) (SpecialValuePolicy, bool) {
+	policy, ok := p[table][column.Name]
+	return policy, ok && specialValueCheck(column) != nil
+}
+
+// specialValueCheck returns whether a value of column, as the driver scans
+// it or as it is decoded from a change, is special, or nil if the column
+// can't hold special values.
+func specialValueCheck(column *Column) func(interface{}) bool {
+	switch {
+	case isNumberColumn(column):
+		return isNaNOrInfinity
+	case column.Type == "text", column.Type == "character varying", column.Type == "character":
+		return isSpecialText
+	default:
+		return nil
+	}
+}
+
+// isNumberColumn is whether column can hold NaN and Infinity.
+func isNumberColumn(column *Column) bool {
+	return column.Type == "real" || column.Type == "double precision" || column.Type == "numeric"
+}
+
+func isNaNOrInfinity(value interface{}) bool {
+	switch v := value.(type) {
+	case float64:
+		return math.IsNaN(v) || math.IsInf(v, 0)
+	case []byte, string:
+		s, _ := textValue(v)
+		return s == "NaN" || s == "Infinity" || s == "-Infinity"
+	default:
+		return false
+	}
+}
+
+// isSpecialText is whether value has characters outside the Basic
+// Multilingual Plane or NUL characters. PostgreSQL text can't hold NUL, so
+// GetSpecialValues doesn't look for it, but rows are still checked for it.
+func isSpecialText(value interface{}) bool {
+	s, err := textValue(value)
+	if err != nil {
+		return false
+	}
+
+	for _, r := range s {
+		if r > 0xFFFF || r == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// specialValueConversion replaces the special values of a column with NULL
+// or the sentinel of policy, and converts other values with conversion.
+func specialValueConversion(special func(interface{}) bool, policy SpecialValuePolicy, conversion func(interface{}) interface{}) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		if value != nil && special(value) {
+			switch policy.Action {
+			case SpecialValueNull:
+				return nil
+			case SpecialValueSentinel:
+				return policy.Sentinel
+			}
+		}
+
+		return conversion(value)
+	}
+}
+
+type SpecialValueMetadata struct {
+	ColumnName string
+	RowCount   int64
+	RowIDs     []string
+
+	// Problem is what kind of special values the column has, and Action
+	// what its policy does with them, empty if it has none.
+	Problem string
+	Action  SpecialValueAction
+}
+
+// GetSpecialValues finds the values of the columns of table that MySQL
+// rejects in strict mode, and the policies configured for them.
+func GetSpecialValues(ctx context.Context, db DB, table *Table, policies SpecialValuePolicies) ([]SpecialValueMetadata, error) {
+	primaryKey, err := db.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	var special []SpecialValueMetadata
+	for _, column := range table.Columns {
+		if specialValueCheck(column) == nil {
+			continue
+		}
+
+		metadata := SpecialValueMetadata{
+			ColumnName: column.Name,
+		}
+
+		var condition string
+		var args []interface{}
+		if isNumberColumn(column) {
+			condition = fmt.Sprintf("\"%s\"::text IN ('NaN', 'Infinity', '-Infinity')", column.Name)
+			metadata.Problem = "NaN or Infinity"
+		} else {
+			// Only supplementary characters are looked for, since text
+			// can't hold NUL in PostgreSQL
+			condition = fmt.Sprintf("\"%s\" ~ $1", column.Name)
+			args = append(args, `[\U00010000-\U0010FFFF]`)
+			metadata.Problem = "characters outside the Basic Multilingual Plane"
+		}
+
+		if policy, ok := policies.lookup(table.Name, column); ok {
+			metadata.Action = policy.Action
+		}
+
+		metadata.RowCount, metadata.RowIDs, err = rowsMatching(ctx, db, table, primaryKey, condition, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting rows with special values: %s", err)
+		}
+
+		if metadata.RowCount > 0 {
+			special = append(special, metadata)
+		}
+	}
+
+	return special, nil
+}
+
+// rowsMatching counts the rows of table matching condition, and returns
+// their IDs if it has a primary key.
+func rowsMatching(ctx context.Context, db DB, table *Table, primaryKey []string, condition string, args ...interface{}) (int64, []string, error) {
+	if len(primaryKey) == 0 {
+		var count int64
+		stmt := fmt.Sprintf("SELECT count(1) FROM \"%s\" WHERE %s", table.Name, condition)
+		if err := db.DB().QueryRowContext(ctx, stmt, args...).Scan(&count); err != nil {
+			return 0, nil, err
+		}
+		return count, nil, nil
+	}
+
+	keyColumnsForSelect := make([]string, len(primaryKey))
+	for i := range primaryKey {
+		keyColumnsForSelect[i] = fmt.Sprintf("\"%s\"", primaryKey[i])
+	}
+
+	stmt := fmt.Sprintf("SELECT %s FROM \"%s\" WHERE %s", strings.Join(keyColumnsForSelect, ","), table.Name, condition)
+	rows, err := db.DB().QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	keyValues := make([]interface{}, len(primaryKey))
+	keyScanArgs := make([]interface{}, len(primaryKey))
+	for i := range keyValues {
+		keyScanArgs[i] = &keyValues[i]
+	}
+
+	var rowIDs []string
+	for rows.Next() {
+		if err := rows.Scan(keyScanArgs...); err != nil {
+			return 0, nil, err
+		}
+		rowIDs = append(rowIDs, FormatRowID(keyValues))
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	return int64(len(rowIDs)), rowIDs, nil
+}
@@ -0,0 +1,83 @@
+package pg2mysql_test
+
+import (
+	"math"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("Special value policies", func() {
+	var src, dst *pg2mysql.Table
+
+	BeforeEach(func() {
+		src = &pg2mysql.Table{
+			Name: "readings",
+			Columns: []*pg2mysql.Column{
+				{Name: "value", Type: "double precision"},
+				{Name: "total", Type: "numeric"},
+				{Name: "note", Type: "text"},
+			},
+		}
+		dst = &pg2mysql.Table{
+			Name: "readings",
+			Columns: []*pg2mysql.Column{
+				{Name: "value", Type: "double"},
+				{Name: "total", Type: "decimal"},
+				{Name: "note", Type: "text"},
+			},
+		}
+	})
+
+	converter := func(policies map[string]pg2mysql.SpecialValuePolicy) *pg2mysql.RowConverter {
+		return pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{
+			SpecialValues: pg2mysql.SpecialValuePolicies{"readings": policies},
+		})
+	}
+
+	It("writes special values as they are without a policy", func() {
+		row := []interface{}{math.NaN(), []byte("Infinity"), "🎉"}
+		converted := converter(nil).Convert(row)
+		Expect(math.IsNaN(converted[0].(float64))).To(BeTrue())
+		Expect(converted[1:]).To(Equal([]interface{}{[]byte("Infinity"), "🎉"}))
+	})
+
+	It("replaces special values with NULL or a sentinel", func() {
+		c := converter(map[string]pg2mysql.SpecialValuePolicy{
+			"value": {Action: pg2mysql.SpecialValueNull},
+			"total": {Action: pg2mysql.SpecialValueSentinel, Sentinel: "-1"},
+			"note":  {Action: pg2mysql.SpecialValueSentinel, Sentinel: "?"},
+		})
+
+		Expect(c.Convert([]interface{}{math.Inf(-1), []byte("NaN"), "party 🎉"})).To(Equal([]interface{}{nil, "-1", "?"}))
+		Expect(c.Convert([]interface{}{1.5, []byte("2.50"), "café"})).To(Equal([]interface{}{1.5, []byte("2.50"), "café"}))
+		Expect(c.Check([]interface{}{nil, "-1", "?"})).To(Succeed())
+	})
+
+	It("treats text with supplementary or NUL characters as special", func() {
+		c := converter(map[string]pg2mysql.SpecialValuePolicy{
+			"note": {Action: pg2mysql.SpecialValueNull},
+		})
+
+		Expect(c.Convert([]interface{}{nil, nil, []byte("party 🎉")})).To(Equal([]interface{}{nil, nil, nil}))
+		Expect(c.Convert([]interface{}{nil, nil, "a\x00b"})).To(Equal([]interface{}{nil, nil, nil}))
+		Expect(c.Convert([]interface{}{nil, nil, "日本語"})).To(Equal([]interface{}{nil, nil, "日本語"}))
+
+		c = converter(map[string]pg2mysql.SpecialValuePolicy{
+			"note": {Action: pg2mysql.SpecialValueFail},
+		})
+		Expect(c.Check(c.Convert([]interface{}{nil, nil, "a\x00b"}))).To(MatchError(ContainSubstring("note")))
+		Expect(c.Check(c.Convert([]interface{}{nil, nil, "ab"}))).To(Succeed())
+	})
+
+	It("fails rows with special values", func() {
+		c := converter(map[string]pg2mysql.SpecialValuePolicy{
+			"value": {Action: pg2mysql.SpecialValueFail},
+		})
+
+		Expect(c.Check(c.Convert([]interface{}{1.5, nil, nil}))).To(Succeed())
+		Expect(c.Check(c.Convert([]interface{}{math.NaN(), nil, nil}))).To(MatchError(ContainSubstring("value")))
+	})
+})
@@ -0,0 +1,288 @@
+package pg2mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+type Syncer interface {
+	Sync(ctx context.Context, syncConfig SyncConfig) error
+}
+
+type SyncConfig struct {
+	MigrationConfig
+
+	// SyncColumns maps each table to sync to the column that tracks when
+	// its rows changed, such as updated_at. Other tables are left alone.
+	SyncColumns map[string]string
+
+	// StateFile is where the watermark of each table is kept between runs.
+	StateFile string
+}
+
+// NewSyncer returns a Syncer that upserts the rows of each table changed
+// since the last sync, reporting its progress like a migration.
+func NewSyncer(src, dst DB, watcher MigratorWatcher) Syncer {
+	return &migrator{
+		src:     src,
+		dst:     dst,
+		watcher: watcher,
+	}
+}
+
+// SyncState records the watermark of each table: the greatest value of its
+// sync column as of the last sync. Watermarks are kept in the text form
+// PostgreSQL gives them.
+type SyncState struct {
+	Watermarks map[string]string `json:"watermarks"`
+}
+
+// ReadSyncState reads the sync state at path. A missing file is a state
+// without watermarks, so every row is synced.
+func ReadSyncState(path string) (*SyncState, error) {
+	state := &SyncState{Watermarks: map[string]string{}}
+
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(bs, state); err != nil {
+		return nil, err
+	}
+	if state.Watermarks == nil {
+		state.Watermarks = map[string]string{}
+	}
+
+	return state, nil
+}
+
+func WriteSyncState(path string, state *SyncState) error {
+	bs, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, append(bs, '\n'), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func (m *migrator) Sync(ctx context.Context, syncConfig SyncConfig) error {
+	m.watcher.WillBuildSchema()
+
+	srcSchema, err := BuildSchema(ctx, m.src)
+	if err != nil {
+		return fmt.Errorf("failed to build source schema: %s", err)
+	}
+
+	dstSchema, err := BuildSchema(ctx, m.dst)
+	if err != nil {
+		return fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	m.watcher.DidBuildSchema()
+
+	options, err := conversionOptions(ctx, m.dst, syncConfig.MigrationConfig)
+	if err != nil {
+		return err
+	}
+
+	// Child tables of array columns are only filled in by migrations
+	splitArrayColumns(srcSchema, options.Arrays)
+
+	state, err := ReadSyncState(syncConfig.StateFile)
+	if err != nil {
+		return fmt.Errorf("failed to read sync state: %s", err)
+	}
+
+	var names []string
+	for name := range syncConfig.SyncColumns {
+		if ignoreTable(name, syncConfig.IgnoreTables) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var tables []*Table
+	for _, name := range names {
+		table, err := srcSchema.GetTable(name)
+		if err != nil {
+			return err
+		}
+		if !table.HasColumn(syncConfig.SyncColumns[name]) {
+			return fmt.Errorf("table '%s' has no sync column '%s'", name, syncConfig.SyncColumns[name])
+		}
+		tables = append(tables, table)
+	}
+
+	foreignKeys, err := GetForeignKeys(ctx, m.src)
+	if err != nil {
+		return fmt.Errorf("failed to get foreign keys from source: %s", err)
+	}
+
+	// Rows of tables referencing each other can't all be written in an
+	// order that satisfies their foreign keys, so constraints are off
+	tables, _ = OrderTables(tables, foreignKeys)
+
+	syncConfig.WriteMode = WriteModeUpsert
+	defer m.startRun(syncConfig.MigrationConfig)()
+	m.progress = newProgressTracker(m.watcher, syncConfig.ProgressInterval, 0)
+
+	enableConstraints, err := m.disableConstraints(ctx)
+	if err != nil {
+		return err
+	}
+	defer enableConstraints()
+
+	defer func() {
+		if m.failures.Total > 0 {
+			m.watcher.MigrationDidFinishWithFailures(m.failures)
+		}
+	}()
+
+	for _, table := range tables {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		since, ok := state.Watermarks[table.Name]
+		watermark, err := m.syncTable(ctx, table, dstSchema, options, syncConfig.SyncColumns[table.Name], since, ok)
+		if err != nil {
+			return err
+		}
+
+		// Saved as each table finishes, so an interrupted sync only
+		// repeats the table it was in
+		if watermark != "" {
+			state.Watermarks[table.Name] = watermark
+			if err := WriteSyncState(syncConfig.StateFile, state); err != nil {
+				return fmt.Errorf("failed to write sync state: %s", err)
+			}
+		}
+	}
+
+	if m.failures.Total > 0 {
+		return &RowFailuresError{Failures: m.failures}
+	}
+
+	return nil
+}
+
+// syncTable upserts every row of table whose sync column is at or after the
+// watermark, or every row if there is no watermark yet, and returns the new
+// watermark. Rows at the watermark are synced again because rows changed in
+// the same instant may have been committed after the last sync read them.
+// The watermark stays where it was when rows fail to write, so that they are
+// synced again, and not only once their dead letters are replayed.
+func (m *migrator) syncTable(
+	ctx context.Context,
+	table *Table,
+	dstSchema *Schema,
+	options ConversionOptions,
+	syncColumn string,
+	watermark string,
+	hasWatermark bool,
+) (string, error) {
+	primaryKey, err := m.src.GetPrimaryKey(ctx, table.Name)
+	if err != nil {
+		return "", fmt.Errorf("failed to get primary key from source table: %s", err)
+	}
+	if len(primaryKey) == 0 {
+		return "", fmt.Errorf("cannot sync table '%s' without a primary key", table.Name)
+	}
+
+	// The new watermark is read before the rows, so that rows changing
+	// while they are copied are synced again next time
+	var newWatermark sql.NullString
+	err = m.src.DB().QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT MAX(%s)::text FROM \"%s\"",
+		m.src.ColumnNameForSelect(syncColumn),
+		table.Name,
+	)).Scan(&newWatermark)
+	if err != nil {
+		return "", fmt.Errorf("failed to read watermark: %s", err)
+	}
+
+	dstTable, _ := dstSchema.GetTable(table.Name)
+	converter := NewRowConverter(table, dstTable, options)
+
+	preparedStmt, err := m.dst.DB().PrepareContext(ctx, upsertStatement(table, primaryKey, converter))
+	if err != nil {
+		return "", fmt.Errorf("failed creating prepared statement: %s", err)
+	}
+	defer preparedStmt.Close()
+
+	m.watcher.TableMigrationDidStart(table.Name)
+	m.progress.startTable(table.Name, 0)
+
+	columnNamesForSelect := make([]string, len(table.Columns))
+	values := make([]interface{}, len(table.Columns))
+	scanArgs := make([]interface{}, len(table.Columns))
+	for i := range table.Columns {
+		columnNamesForSelect[i] = selectExpression(m.src.ColumnNameForSelect(table.Columns[i].Name), table.Columns[i])
+		scanArgs[i] = &values[i]
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM \"%s\"", strings.Join(columnNamesForSelect, ","), table.Name)
+	var args []interface{}
+	if hasWatermark {
+		query += fmt.Sprintf(" WHERE %s >= $1", m.src.ColumnNameForSelect(syncColumn))
+		args = append(args, watermark)
+	}
+
+	rows, err := m.src.DB().QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to select rows: %s", err)
+	}
+	defer rows.Close()
+
+	var recordsSynced, recordsFailed int64
+	for rows.Next() {
+		if err = rows.Scan(scanArgs...); err != nil {
+			return "", fmt.Errorf("failed to scan row: %s", err)
+		}
+
+		args := converter.Convert(values)
+		err = converter.Check(args)
+		if err == nil {
+			err = m.write(preparedStmt, args)
+		}
+		if err != nil {
+			if err = m.rowFailed(table, args, err); err != nil {
+				return "", err
+			}
+			recordsFailed++
+			continue
+		}
+
+		m.rowMigrated(table, args)
+		recordsSynced++
+	}
+
+	if err = rows.Err(); err != nil {
+		return "", fmt.Errorf("failed iterating through rows: %s", err)
+	}
+
+	m.progress.finishTable()
+	m.watcher.TableMigrationDidFinish(table.Name, recordsSynced)
+
+	if !newWatermark.Valid || recordsFailed > 0 {
+		return watermark, nil
+	}
+
+	return newWatermark.String, nil
+}
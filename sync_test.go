@@ -0,0 +1,162 @@
+package pg2mysql_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("SyncState", func() {
+	var dir, path string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "pg2mysql-sync")
+		Expect(err).NotTo(HaveOccurred())
+
+		path = filepath.Join(dir, "sync.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("has no watermarks when there is no file", func() {
+		state, err := pg2mysql.ReadSyncState(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Watermarks).To(BeEmpty())
+	})
+
+	It("reads back the watermarks that were written", func() {
+		err := pg2mysql.WriteSyncState(path, &pg2mysql.SyncState{
+			Watermarks: map[string]string{"droplets": "2017-06-01 12:00:00.123456+00"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		state, err := pg2mysql.ReadSyncState(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(state.Watermarks).To(Equal(map[string]string{"droplets": "2017-06-01 12:00:00.123456+00"}))
+	})
+})
+
+var _ = Describe("Syncing", func() {
+	var (
+		mysql  pg2mysql.DB
+		pg     pg2mysql.DB
+		dir    string
+		config pg2mysql.SyncConfig
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE events (
+				id int PRIMARY KEY,
+				name text,
+				updated_at timestamp
+			);
+			INSERT INTO events VALUES
+				(1, 'a', '2017-01-01 00:00:00'),
+				(2, 'b', '2017-01-02 00:00:00'),
+				(3, 'c', '2017-01-03 00:00:00');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE events (id int PRIMARY KEY, name varchar(10) NOT NULL, updated_at datetime)",
+			"INSERT INTO events VALUES (1, 'stale', '2016-01-01 00:00:00'), (2, 'stale', '2016-01-01 00:00:00')",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		dir, err = ioutil.TempDir("", "pg2mysql-sync")
+		Expect(err).NotTo(HaveOccurred())
+
+		config = pg2mysql.SyncConfig{
+			MigrationConfig: pg2mysql.MigrationConfig{
+				IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+			},
+			SyncColumns: map[string]string{"events": "updated_at"},
+			StateFile:   filepath.Join(dir, "sync.json"),
+		}
+
+		err = pg2mysql.WriteSyncState(config.StateFile, &pg2mysql.SyncState{
+			Watermarks: map[string]string{"events": "2017-01-02 00:00:00"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+		os.RemoveAll(dir)
+
+		_, err := pgRunner.DB().Exec("DROP TABLE events")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE events")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	events := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(id, ':', name) FROM events ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var events []string
+		for rows.Next() {
+			var event string
+			Expect(rows.Scan(&event)).To(Succeed())
+			events = append(events, event)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return events
+	}
+
+	watermark := func() string {
+		state, err := pg2mysql.ReadSyncState(config.StateFile)
+		Expect(err).NotTo(HaveOccurred())
+		return state.Watermarks["events"]
+	}
+
+	It("upserts the rows at or after the watermark, skips older ones and saves the new watermark", func() {
+		watcher := &pg2mysqlfakes.FakeMigratorWatcher{}
+		Expect(pg2mysql.NewSyncer(pg, mysql, watcher).Sync(context.Background(), config)).To(Succeed())
+
+		Expect(events()).To(Equal([]string{"1:stale", "2:b", "3:c"}))
+		Expect(watermark()).To(Equal("2017-01-03 00:00:00"))
+
+		Expect(watcher.TableMigrationDidFinishCallCount()).To(Equal(1))
+		tableName, synced := watcher.TableMigrationDidFinishArgsForCall(0)
+		Expect(tableName).To(Equal("events"))
+		Expect(synced).To(BeEquivalentTo(2))
+	})
+
+	It("leaves the watermark alone when rows fail, so they are synced again", func() {
+		_, err := pgRunner.DB().Exec("UPDATE events SET name = NULL WHERE id = 3")
+		Expect(err).NotTo(HaveOccurred())
+
+		err = pg2mysql.NewSyncer(pg, mysql, &pg2mysqlfakes.FakeMigratorWatcher{}).Sync(context.Background(), config)
+		Expect(err).To(MatchError("1 row failed to insert"))
+		Expect(events()).To(Equal([]string{"1:stale", "2:b"}))
+		Expect(watermark()).To(Equal("2017-01-02 00:00:00"))
+
+		_, err = pgRunner.DB().Exec("UPDATE events SET name = 'c' WHERE id = 3")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(pg2mysql.NewSyncer(pg, mysql, &pg2mysqlfakes.FakeMigratorWatcher{}).Sync(context.Background(), config)).To(Succeed())
+		Expect(events()).To(Equal([]string{"1:stale", "2:b", "3:c"}))
+		Expect(watermark()).To(Equal("2017-01-03 00:00:00"))
+	})
+})
@@ -0,0 +1,124 @@
+package pg2mysql
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PackedUUID is a UUID as it is stored in a BINARY(16) column. With
+// SwapTimeFields, its time fields are stored the way MySQL's
+// UUID_TO_BIN(uuid, 1) does, high to low, so that time-based UUIDs are
+// inserted in index order.
+type PackedUUID struct {
+	UUID           [16]byte
+	SwapTimeFields bool
+}
+
+// ParseUUID parses the text form of a UUID, as PostgreSQL outputs it.
+func ParseUUID(s string) ([16]byte, error) {
+	var uuid [16]byte
+
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil {
+		return uuid, fmt.Errorf("invalid UUID '%s': %s", s, err)
+	}
+	if len(b) != len(uuid) {
+		return uuid, fmt.Errorf("invalid UUID '%s'", s)
+	}
+
+	copy(uuid[:], b)
+	return uuid, nil
+}
+
+// Value is the 16 bytes stored in MySQL.
+func (u PackedUUID) Value() (driver.Value, error) {
+	return u.Bytes(), nil
+}
+
+func (u PackedUUID) Bytes() []byte {
+	if !u.SwapTimeFields {
+		return append([]byte{}, u.UUID[:]...)
+	}
+
+	// time_low, time_mid and time_hi_and_version become
+	// time_hi_and_version, time_mid and time_low
+	b := make([]byte, 0, len(u.UUID))
+	b = append(b, u.UUID[6:8]...)
+	b = append(b, u.UUID[4:6]...)
+	b = append(b, u.UUID[0:4]...)
+	return append(b, u.UUID[8:]...)
+}
+
+// UnpackUUID returns the UUID stored as b.
+func UnpackUUID(b []byte, swapTimeFields bool) ([16]byte, error) {
+	var uuid [16]byte
+	if len(b) != len(uuid) {
+		return uuid, fmt.Errorf("packed UUID is %d bytes long", len(b))
+	}
+
+	if !swapTimeFields {
+		copy(uuid[:], b)
+		return uuid, nil
+	}
+
+	copy(uuid[0:4], b[4:8])
+	copy(uuid[4:6], b[2:4])
+	copy(uuid[6:8], b[0:2])
+	copy(uuid[8:], b[8:])
+	return uuid, nil
+}
+
+// String is the text form of the UUID, whichever way it is stored, so that
+// rows are reported by the key they have in PostgreSQL.
+func (u PackedUUID) String() string {
+	s := hex.EncodeToString(u.UUID[:])
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// isPackedUUIDColumn is whether src is a uuid column stored in dst as
+// BINARY(16).
+func isPackedUUIDColumn(src, dst *Column) bool {
+	return src.Type == "uuid" && dst != nil && dst.Type == "binary" && dst.MaxChars == 16
+}
+
+// uuidConversion packs the uuid values the PostgreSQL driver scans as text.
+// Values that aren't UUIDs are left as they are, to fail when inserted.
+func uuidConversion(swapTimeFields bool) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		var s string
+		switch v := value.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return value
+		}
+
+		uuid, err := ParseUUID(s)
+		if err != nil {
+			return value
+		}
+
+		return PackedUUID{UUID: uuid, SwapTimeFields: swapTimeFields}
+	}
+}
+
+// uuidReversal turns packed UUIDs read from MySQL back into their text form.
+func uuidReversal(swapTimeFields bool) func(interface{}) interface{} {
+	return func(value interface{}) interface{} {
+		b, ok := value.([]byte)
+		if !ok {
+			return value
+		}
+
+		uuid, err := UnpackUUID(b, swapTimeFields)
+		if err != nil {
+			return value
+		}
+
+		return PackedUUID{UUID: uuid, SwapTimeFields: swapTimeFields}.String()
+	}
+}
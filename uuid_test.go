@@ -0,0 +1,61 @@
+package pg2mysql_test
+
+import (
+	"encoding/hex"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+)
+
+var _ = Describe("PackedUUID", func() {
+	const text = "6ccd780c-baba-1026-9564-5b8c656024db"
+
+	It("packs UUIDs in order", func() {
+		uuid, err := pg2mysql.ParseUUID(text)
+		Expect(err).NotTo(HaveOccurred())
+
+		packed := pg2mysql.PackedUUID{UUID: uuid}
+		Expect(hex.EncodeToString(packed.Bytes())).To(Equal("6ccd780cbaba102695645b8c656024db"))
+		Expect(packed.String()).To(Equal(text))
+	})
+
+	It("swaps the time fields like UUID_TO_BIN(uuid, 1)", func() {
+		uuid, err := pg2mysql.ParseUUID(text)
+		Expect(err).NotTo(HaveOccurred())
+
+		packed := pg2mysql.PackedUUID{UUID: uuid, SwapTimeFields: true}
+		Expect(hex.EncodeToString(packed.Bytes())).To(Equal("1026baba6ccd780c95645b8c656024db"))
+		Expect(packed.String()).To(Equal(text))
+
+		unpacked, err := pg2mysql.UnpackUUID(packed.Bytes(), true)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(unpacked).To(Equal(uuid))
+	})
+
+	It("is converted for BINARY(16) columns and reported as text", func() {
+		src := &pg2mysql.Table{
+			Name:    "some_table",
+			Columns: []*pg2mysql.Column{{Name: "id", Type: "uuid"}, {Name: "other_id", Type: "uuid"}},
+		}
+		dst := &pg2mysql.Table{
+			Name:    "some_table",
+			Columns: []*pg2mysql.Column{{Name: "id", Type: "binary", MaxChars: 16}, {Name: "other_id", Type: "char", MaxChars: 36}},
+		}
+
+		converter := pg2mysql.NewRowConverter(src, dst, pg2mysql.ConversionOptions{UUIDSwapTimeFields: true})
+		converted := converter.Convert([]interface{}{[]byte(text), []byte(text)})
+		Expect(converted[0]).To(BeAssignableToTypeOf(pg2mysql.PackedUUID{}))
+		Expect(converted[1]).To(Equal([]byte(text)))
+		Expect(pg2mysql.FormatRowID(converted[:1])).To(Equal(text))
+
+		packed := converted[0].(pg2mysql.PackedUUID).Bytes()
+		Expect(converter.Reverse(0, packed)).To(Equal(text))
+	})
+
+	It("rejects values that aren't UUIDs", func() {
+		_, err := pg2mysql.ParseUUID("not-a-uuid")
+		Expect(err).To(HaveOccurred())
+	})
+})
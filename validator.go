@@ -1,11 +1,13 @@
 package pg2mysql
 
 import (
+	"context"
 	"fmt"
+	"time"
 )
 
 type Validator interface {
-	Validate(validationConfig MigrationConfig) ([]ValidationResult, error)
+	Validate(ctx context.Context, validationConfig MigrationConfig) ([]ValidationResult, error)
 }
 
 func NewValidator(src, dst DB) Validator {
@@ -20,7 +22,63 @@ type validator struct {
 }
 
 type MigrationConfig struct {
-	IgnoreTables []string
+	IgnoreTables        []string
+	TimestampRounding   TimestampRounding
+	SourceTimezone      string
+	DestinationTimezone string
+
+	// DeadLetterDir is where rows that fail to insert are recorded, one
+	// file per table. Failed rows are only reported when it is empty.
+	DeadLetterDir string
+
+	// MaxErrors and MaxErrorsPerTable stop a migration once more rows than
+	// they allow fail to insert, overall or in a single table. Zero means
+	// no limit. FailFast stops it at the first failed row.
+	MaxErrors         int64
+	MaxErrorsPerTable int64
+	FailFast          bool
+
+	// ProgressInterval is how often the progress of a migration is
+	// reported. Zero means every 10 seconds.
+	ProgressInterval time.Duration
+
+	// CheckpointFile is where an interrupted migration records the tables
+	// it finished. Resume has a migration skip the tables of the checkpoint
+	// there; otherwise an earlier checkpoint is ignored and replaced.
+	CheckpointFile string
+	Resume         bool
+
+	// EnforceForeignKeys migrates with foreign key checks on, inserting the
+	// columns of foreign keys that reference rows not migrated yet as NULL
+	// and filling them in at the end. Those columns must be nullable.
+	EnforceForeignKeys bool
+
+	// WriteMode is how rows are written to MySQL, WriteModeInsert if empty.
+	WriteMode WriteMode
+
+	// Arrays are how array columns are translated. Those without a
+	// conversion are migrated in their literal form.
+	Arrays ArrayConversions
+
+	// UUIDSwapTimeFields stores uuid columns migrated to BINARY(16) with
+	// their time fields swapped, like UUID_TO_BIN(uuid, 1).
+	UUIDSwapTimeFields bool
+
+	// Converters convert values between the types they are registered
+	// for. DefaultConverters are used if it is nil.
+	Converters *ConverterRegistry
+
+	// SpecialValues are what is done with NaN, Infinity and text MySQL
+	// rejects, by table and column. Columns without a policy are written
+	// as they are.
+	SpecialValues SpecialValuePolicies
+}
+
+func (c MigrationConfig) converters() *ConverterRegistry {
+	if c.Converters == nil {
+		return DefaultConverters()
+	}
+	return c.Converters
 }
 
 func ignoreTable(table string, tables []string) bool {
@@ -32,35 +90,106 @@ func ignoreTable(table string, tables []string) bool {
 	return false
 }
 
-func (v *validator) Validate(validationConfig MigrationConfig) ([]ValidationResult, error) {
-	srcSchema, err := BuildSchema(v.src)
+func (v *validator) Validate(ctx context.Context, validationConfig MigrationConfig) ([]ValidationResult, error) {
+	srcSchema, err := BuildSchema(ctx, v.src)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build source schema: %s", err)
 	}
 
-	dstSchema, err := BuildSchema(v.dst)
+	dstSchema, err := BuildSchema(ctx, v.dst)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build destination schema: %s", err)
 	}
 
+	if err := validationConfig.Arrays.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := validationConfig.SpecialValues.validate(); err != nil {
+		return nil, err
+	}
+
+	converters := validationConfig.converters()
+
+	// Timestamps without time zone only need to be checked when they are
+	// wall clock times of a zone that may observe daylight saving time
+	checkTimestamps := validationConfig.SourceTimezone != "" && validationConfig.SourceTimezone != "UTC"
+
 	var results []ValidationResult
 	for _, srcTable := range srcSchema.Tables {
 		if ignoreTable(srcTable.Name, validationConfig.IgnoreTables) {
 			continue
 		}
 
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var ambiguousTimestamps []AmbiguousTimestampMetadata
+		if checkTimestamps {
+			ambiguousTimestamps, err = GetAmbiguousTimestamps(ctx, v.src, srcTable, validationConfig.SourceTimezone)
+			if err != nil {
+				return nil, fmt.Errorf("failed getting ambiguous timestamps: %s", err)
+			}
+		}
+
 		dstTable, err := dstSchema.GetTable(srcTable.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get table from destination schema: %s", err)
 		}
 
-		hasSrcPrimaryKey, err := v.src.HasPrimaryKey(srcTable.Name)
+		invalidJSON, err := GetInvalidJSON(ctx, v.src, srcTable, dstTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting invalid JSON: %s", err)
+		}
+
+		invalidArrays, err := GetInvalidArrays(ctx, v.src, srcTable, dstTable, dstSchema, validationConfig.Arrays)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting invalid arrays: %s", err)
+		}
+
+		unconvertible, err := GetUnconvertibleValues(ctx, v.src, srcTable, dstTable, converters)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting unconvertible values: %s", err)
+		}
+
+		missingEnumLabels, err := GetMissingEnumLabels(ctx, v.src, srcTable, dstTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting missing enum labels: %s", err)
+		}
+
+		specialValues, err := GetSpecialValues(ctx, v.src, srcTable, validationConfig.SpecialValues)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting special values: %s", err)
+		}
+
+		invalidGeometries, err := GetInvalidGeometries(ctx, v.src, srcTable, dstTable)
+		if err != nil {
+			return nil, fmt.Errorf("failed getting invalid geometries: %s", err)
+		}
+
+		// Translated array columns are checked by GetInvalidArrays,
+		// converted columns by GetUnconvertibleValues and enums by
+		// GetMissingEnumLabels only
+		srcTable, dstTable = withoutColumns(srcTable, dstTable, func(column *Column) bool {
+			if _, ok := validationConfig.Arrays.lookup(srcTable.Name, column); ok {
+				return true
+			}
+			_, dstColumn, _ := dstTable.GetColumn(column.Name)
+			if dstColumn != nil && dstColumn.Type == "enum" {
+				return true
+			}
+			_, ok := converters.lookupColumns(column, dstColumn)
+			return ok
+		})
+
+		hasSrcPrimaryKey, err := v.src.HasPrimaryKey(ctx, srcTable.Name)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get primary key from source table: %s", err)
 		}
 
 		if hasSrcPrimaryKey {
-			rowIDs, incompatibleColumnMetadata, err := GetIncompatibleRowIDsAndColumns(v.src, srcTable, dstTable)
+			rowIDs, incompatibleColumnMetadata, err := GetIncompatibleRowIDsAndColumns(ctx, v.src, srcTable, dstTable)
 			if err != nil {
 				return nil, fmt.Errorf("failed getting incompatible row ids: %s", err)
 			}
@@ -70,9 +199,16 @@ func (v *validator) Validate(validationConfig MigrationConfig) ([]ValidationResu
 				IncompatibleRowIDs:         rowIDs,
 				IncompatibleRowCount:       int64(len(rowIDs)),
 				IncompatibleColumnMetadata: incompatibleColumnMetadata,
+				AmbiguousTimestamps:        ambiguousTimestamps,
+				InvalidJSON:                invalidJSON,
+				InvalidArrays:              invalidArrays,
+				UnconvertibleValues:        unconvertible,
+				MissingEnumLabels:          missingEnumLabels,
+				SpecialValues:              specialValues,
+				InvalidGeometries:          invalidGeometries,
 			})
 		} else {
-			rowCount, incomptibleColumnMetadata, err := GetIncompatibleRowCount(v.src, srcTable, dstTable)
+			rowCount, incomptibleColumnMetadata, err := GetIncompatibleRowCount(ctx, v.src, srcTable, dstTable)
 			if err != nil {
 				return nil, fmt.Errorf("failed getting incompatible row count: %s", err)
 			}
@@ -81,6 +217,13 @@ func (v *validator) Validate(validationConfig MigrationConfig) ([]ValidationResu
 				TableName:                  srcTable.Name,
 				IncompatibleRowCount:       rowCount,
 				IncompatibleColumnMetadata: incomptibleColumnMetadata,
+				AmbiguousTimestamps:        ambiguousTimestamps,
+				InvalidJSON:                invalidJSON,
+				InvalidArrays:              invalidArrays,
+				UnconvertibleValues:        unconvertible,
+				MissingEnumLabels:          missingEnumLabels,
+				SpecialValues:              specialValues,
+				InvalidGeometries:          invalidGeometries,
 			})
 		}
 	}
@@ -93,4 +236,11 @@ type ValidationResult struct {
 	IncompatibleRowIDs         []string
 	IncompatibleColumnMetadata []IncompatibleColumnMetadata
 	IncompatibleRowCount       int64
+	AmbiguousTimestamps        []AmbiguousTimestampMetadata
+	InvalidJSON                []InvalidJSONMetadata
+	InvalidArrays              []InvalidArrayMetadata
+	UnconvertibleValues        []UnconvertibleValueMetadata
+	MissingEnumLabels          []MissingEnumLabelMetadata
+	SpecialValues              []SpecialValueMetadata
+	InvalidGeometries          []InvalidGeometryMetadata
 }
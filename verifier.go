@@ -1,9 +1,12 @@
 package pg2mysql
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type Verifier interface {
-	Verify() error
+	Verify(ctx context.Context, verificationConfig MigrationConfig) error
 }
 
 type verifier struct {
@@ -19,24 +22,70 @@ func NewVerifier(src, dst DB, watcher VerifierWatcher) Verifier {
 	}
 }
 
-func (v *verifier) Verify() error {
-	srcSchema, err := BuildSchema(v.src)
+func (v *verifier) Verify(ctx context.Context, verificationConfig MigrationConfig) error {
+	srcSchema, err := BuildSchema(ctx, v.src)
 	if err != nil {
 		return fmt.Errorf("failed to build source schema: %s", err)
 	}
 
+	dstSchema, err := BuildSchema(ctx, v.dst)
+	if err != nil {
+		return fmt.Errorf("failed to build destination schema: %s", err)
+	}
+
+	options, err := conversionOptions(ctx, v.dst, verificationConfig)
+	if err != nil {
+		return err
+	}
+
+	childTables := splitArrayColumns(srcSchema, options.Arrays)
+
 	for _, table := range srcSchema.Tables {
+		if ignoreTable(table.Name, verificationConfig.IgnoreTables) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		v.watcher.TableVerificationDidStart(table.Name)
 
+		primaryKey, err := v.src.GetPrimaryKey(ctx, table.Name)
+		if err != nil {
+			v.watcher.TableVerificationDidFinishWithError(table.Name, err)
+			continue
+		}
+
+		keyIndexes := make([]int, len(primaryKey))
+		for i, column := range primaryKey {
+			keyIndexes[i], _, err = table.GetColumn(column)
+			if err != nil {
+				break
+			}
+		}
+		if err != nil {
+			v.watcher.TableVerificationDidFinishWithError(table.Name, err)
+			continue
+		}
+
+		dstTable, _ := dstSchema.GetTable(table.Name)
+		converter := NewRowConverter(table, dstTable, options)
+
 		var missingRows int64
 		var missingIDs []string
-		err = EachMissingRow(v.src, v.dst, table, func(scanArgs []interface{}) {
-			if colIndex, _, getColErr := table.GetColumn("id"); getColErr == nil {
-				if colID, ok := scanArgs[colIndex].(*interface{}); ok {
-					missingIDs = append(missingIDs, fmt.Sprintf("%v", *colID))
+		err = EachMissingRow(ctx, v.src, v.dst, table, converter, func(values []interface{}) error {
+			if len(keyIndexes) > 0 {
+				keyValues := make([]interface{}, len(keyIndexes))
+				for i, index := range keyIndexes {
+					keyValues[i] = values[index]
 				}
+				missingIDs = append(missingIDs, FormatRowID(keyValues))
+			} else {
+				missingIDs = append(missingIDs, FormatRowDigest(values))
 			}
 			missingRows++
+			return nil
 		})
 		if err != nil {
 			v.watcher.TableVerificationDidFinishWithError(table.Name, err)
@@ -46,5 +95,44 @@ func (v *verifier) Verify() error {
 		v.watcher.TableVerificationDidFinish(table.Name, missingRows, missingIDs)
 	}
 
+	for _, child := range childTables {
+		if ignoreTable(child.parent.Name, verificationConfig.IgnoreTables) {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		v.verifyArrayChildTable(ctx, child)
+	}
+
 	return nil
 }
+
+// verifyArrayChildTable looks for the elements of the arrays of an array
+// column in its child table. Missing elements are identified by the primary
+// key of their row and their ordinal.
+func (v *verifier) verifyArrayChildTable(ctx context.Context, child *arrayChildTable) {
+	v.watcher.TableVerificationDidStart(child.name())
+
+	primaryKey, err := v.src.GetPrimaryKey(ctx, child.parent.Name)
+	if err != nil {
+		v.watcher.TableVerificationDidFinishWithError(child.name(), err)
+		return
+	}
+
+	var missingRows int64
+	var missingIDs []string
+	err = child.eachMissingRow(ctx, v.src, v.dst, primaryKey, func(row []interface{}) error {
+		missingIDs = append(missingIDs, FormatRowID(row[:len(primaryKey)+1]))
+		missingRows++
+		return nil
+	})
+	if err != nil {
+		v.watcher.TableVerificationDidFinishWithError(child.name(), err)
+		return
+	}
+
+	v.watcher.TableVerificationDidFinish(child.name(), missingRows, missingIDs)
+}
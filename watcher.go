@@ -2,7 +2,9 @@ package pg2mysql
 
 import (
 	"fmt"
+	"os"
 	"strings"
+	"time"
 )
 
 //go:generate counterfeiter . VerifierWatcher
@@ -13,6 +15,50 @@ type VerifierWatcher interface {
 	TableVerificationDidFinishWithError(tableName string, err error)
 }
 
+//go:generate counterfeiter . RepairerWatcher
+
+type RepairerWatcher interface {
+	TableRepairDidStart(tableName string)
+	TableRepairDidFinish(tableName string, rowsInserted, rowsUpdated, rowsDeleted int64)
+	TableRepairDidFinishWithError(tableName string, err error)
+
+	WouldExecuteStatement(tableName string, stmt string)
+}
+
+//go:generate counterfeiter . ReplayerWatcher
+
+type ReplayerWatcher interface {
+	DeadLetterReplayDidStart(tableName string)
+	DeadLetterReplayDidFinish(tableName string, rowsReplayed, rowsRemaining int64)
+	DeadLetterReplayDidFinishWithError(tableName string, err error)
+}
+
+//go:generate counterfeiter . ReplicatorWatcher
+
+type ReplicatorWatcher interface {
+	ReplicationSlotDidCreate(slotName, lsn string)
+	ReplicationDidProgress(progress ReplicationProgress)
+	ReplicationDidStop(confirmedLSN string)
+}
+
+//go:generate counterfeiter . CapturerWatcher
+
+type CapturerWatcher interface {
+	CaptureDidInstall(tableNames []string)
+	CaptureDidApplyChanges(changesApplied int64)
+	CaptureDidUninstall(tableNames []string)
+}
+
+// Watcher is notified of the progress of every command.
+type Watcher interface {
+	MigratorWatcher
+	VerifierWatcher
+	RepairerWatcher
+	ReplayerWatcher
+	ReplicatorWatcher
+	CapturerWatcher
+}
+
 //go:generate counterfeiter . MigratorWatcher
 
 type MigratorWatcher interface {
@@ -30,17 +76,29 @@ type MigratorWatcher interface {
 	TruncateTableDidFinish(tableName string)
 
 	TableMigrationDidStart(tableName string)
+	TableMigrationDidSkip(tableName string)
 	TableMigrationDidFinish(tableName string, recordsInserted int64)
+	TableMigrationDidProgress(progress MigrationProgress)
+
+	WillUpdateDeferredColumns(tableName string, columns []string)
+	DeferredColumnsDidUpdate(tableName string, rowsUpdated int64)
 
 	DidMigrateRow(tableName string)
+	DidInsertBytes(tableName string, bytes int64)
 	DidFailToMigrateRowWithError(tableName string, err error)
+
+	MigrationDidFinishWithFailures(failures *RowFailures)
 }
 
 func NewStdoutPrinter() *StdoutPrinter {
 	return &StdoutPrinter{}
 }
 
-type StdoutPrinter struct{}
+type StdoutPrinter struct {
+	// tableProgressed is whether progress was printed for the table being
+	// migrated, so that its result starts on a new line
+	tableProgressed bool
+}
 
 func (s *StdoutPrinter) TableVerificationDidStart(tableName string) {
 	fmt.Printf("Verifying table %s...", tableName)
@@ -69,6 +127,68 @@ func (s *StdoutPrinter) TableVerificationDidFinishWithError(tableName string, er
 	fmt.Printf("failed: %s", err)
 }
 
+func (s *StdoutPrinter) TableRepairDidStart(tableName string) {
+	fmt.Printf("Repairing table %s...", tableName)
+}
+
+func (s *StdoutPrinter) TableRepairDidFinish(tableName string, rowsInserted, rowsUpdated, rowsDeleted int64) {
+	if rowsInserted == 0 && rowsUpdated == 0 && rowsDeleted == 0 {
+		s.done()
+		return
+	}
+
+	fmt.Printf("\n\tREPAIRED: %d inserted, %d updated, %d deleted\n", rowsInserted, rowsUpdated, rowsDeleted)
+}
+
+func (s *StdoutPrinter) TableRepairDidFinishWithError(tableName string, err error) {
+	fmt.Printf("failed: %s\n", err)
+}
+
+func (s *StdoutPrinter) WouldExecuteStatement(tableName string, stmt string) {
+	fmt.Printf("\n\t%s;", stmt)
+}
+
+func (s *StdoutPrinter) DeadLetterReplayDidStart(tableName string) {
+	fmt.Printf("Replaying dead letters for %s...", tableName)
+}
+
+func (s *StdoutPrinter) DeadLetterReplayDidFinish(tableName string, rowsReplayed, rowsRemaining int64) {
+	if rowsRemaining == 0 {
+		fmt.Printf("OK\n  inserted %d rows\n", rowsReplayed)
+		return
+	}
+
+	fmt.Printf("\n\tFAILED: %d rows inserted, %d rows still failing\n", rowsReplayed, rowsRemaining)
+}
+
+func (s *StdoutPrinter) DeadLetterReplayDidFinishWithError(tableName string, err error) {
+	fmt.Printf("failed: %s\n", err)
+}
+
+func (s *StdoutPrinter) ReplicationSlotDidCreate(slotName, lsn string) {
+	fmt.Printf("Created replication slot %s at %s\n", slotName, lsn)
+}
+
+func (s *StdoutPrinter) ReplicationDidProgress(progress ReplicationProgress) {
+	fmt.Printf("Applied %d changes up to %s, %d bytes behind\n", progress.ChangesApplied, progress.ConfirmedLSN, progress.LagBytes)
+}
+
+func (s *StdoutPrinter) ReplicationDidStop(confirmedLSN string) {
+	fmt.Printf("Stopped replicating at %s; replicate again to resume\n", confirmedLSN)
+}
+
+func (s *StdoutPrinter) CaptureDidInstall(tableNames []string) {
+	fmt.Printf("Capturing changes to %d tables in %s\n", len(tableNames), ChangelogTable)
+}
+
+func (s *StdoutPrinter) CaptureDidApplyChanges(changesApplied int64) {
+	fmt.Printf("Applied %d changes\n", changesApplied)
+}
+
+func (s *StdoutPrinter) CaptureDidUninstall(tableNames []string) {
+	fmt.Printf("Stopped capturing changes to %d tables\n", len(tableNames))
+}
+
 func (s *StdoutPrinter) WillBuildSchema() {
 	fmt.Print("Building schema...")
 }
@@ -111,6 +231,51 @@ func (s *StdoutPrinter) TruncateTableDidFinish(tableName string) {
 
 func (s *StdoutPrinter) TableMigrationDidStart(tableName string) {
 	fmt.Printf("Migrating %s...", tableName)
+	s.tableProgressed = false
+}
+
+func (s *StdoutPrinter) TableMigrationDidSkip(tableName string) {
+	fmt.Printf("Skipping %s...already migrated before the checkpoint\n", tableName)
+}
+
+func (s *StdoutPrinter) WillUpdateDeferredColumns(tableName string, columns []string) {
+	fmt.Printf("Updating deferred columns %s of %s...", strings.Join(columns, ", "), tableName)
+}
+
+func (s *StdoutPrinter) DeferredColumnsDidUpdate(tableName string, rowsUpdated int64) {
+	s.done()
+	fmt.Printf("  updated %d rows\n", rowsUpdated)
+}
+
+func (s *StdoutPrinter) TableMigrationDidProgress(progress MigrationProgress) {
+	if !s.tableProgressed {
+		fmt.Println()
+		s.tableProgressed = true
+	}
+
+	tableETA, hasTableETA := progress.TableETA()
+	fmt.Printf("  %s: %s\n", progress.TableName, formatProgress(progress.TableRows, progress.TableTotal, progress.TableRate(), tableETA, hasTableETA))
+
+	eta, hasETA := progress.ETA()
+	fmt.Printf("  all tables: %s\n", formatProgress(progress.Rows, progress.Total, progress.Rate(), eta, hasETA))
+}
+
+func formatProgress(rows, total int64, rate float64, eta time.Duration, hasETA bool) string {
+	progress := fmt.Sprintf("%d rows", rows)
+	if total > 0 {
+		percent := float64(rows) * 100 / float64(total)
+		if percent > 100 {
+			percent = 100
+		}
+		progress = fmt.Sprintf("%d of ~%d rows (%.0f%%)", rows, total, percent)
+	}
+
+	progress += fmt.Sprintf(", %.0f rows/s", rate)
+	if hasETA {
+		progress += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+
+	return progress
 }
 
 func (s *StdoutPrinter) TableMigrationDidFinish(tableName string, recordsInserted int64) {
@@ -124,10 +289,33 @@ func (s *StdoutPrinter) TableMigrationDidFinish(tableName string, recordsInserte
 	}
 }
 
-func (s *StdoutPrinter) DidMigrateRow(tableName string) {
-	fmt.Printf(".")
+func (s *StdoutPrinter) MigrationDidFinishWithFailures(failures *RowFailures) {
+	if failures.Total == 1 {
+		fmt.Println("FAILED: 1 row failed to insert")
+	} else {
+		fmt.Printf("FAILED: %d rows failed to insert\n", failures.Total)
+	}
+
+	for _, errorFailures := range failures.Errors() {
+		if errorFailures.Number == 0 {
+			fmt.Printf("  %d rows: %s\n", errorFailures.Rows, errorFailures.Message)
+		} else {
+			fmt.Printf("  %d rows: Error %d: %s\n", errorFailures.Rows, errorFailures.Number, errorFailures.Message)
+		}
+
+		for _, tableName := range errorFailures.TableNames() {
+			fmt.Printf("    %s: %d\n", tableName, errorFailures.Tables[tableName])
+		}
+	}
 }
 
+// Rows are reported in aggregate by TableMigrationDidProgress.
+func (s *StdoutPrinter) DidMigrateRow(tableName string) {}
+
+func (s *StdoutPrinter) DidInsertBytes(tableName string, bytes int64) {}
+
+// DidFailToMigrateRowWithError prints failures to stderr as they happen, to
+// keep them apart from the progress on stdout.
 func (s *StdoutPrinter) DidFailToMigrateRowWithError(tableName string, err error) {
-	fmt.Printf("x")
+	fmt.Fprintf(os.Stderr, "failed to insert into %s: %s\n", tableName, err)
 }
@@ -0,0 +1,76 @@
+package pg2mysql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WriteMode is how the migrator writes rows to MySQL.
+type WriteMode string
+
+const (
+	// WriteModeInsert inserts the rows missing from MySQL. Rows whose key
+	// is already there with other values fail to insert.
+	WriteModeInsert WriteMode = "insert"
+
+	// WriteModeInsertIgnore inserts the rows missing from MySQL and
+	// skips those that conflict with a row already there.
+	WriteModeInsertIgnore WriteMode = "insert-ignore"
+
+	// WriteModeUpsert inserts the rows that differ in MySQL, updating the
+	// non-key columns of a row whose key is already there.
+	WriteModeUpsert WriteMode = "upsert"
+
+	// WriteModeReplace inserts the rows that differ in MySQL, deleting a
+	// row whose key is already there first.
+	WriteModeReplace WriteMode = "replace"
+)
+
+// rewritesRows is whether rows already in MySQL with other values are written
+// again, so that every row that differs has to be found rather than only
+// those whose key is missing.
+func (w WriteMode) rewritesRows() bool {
+	return w == WriteModeUpsert || w == WriteModeReplace
+}
+
+// statement builds the statement used to write a full row of table to MySQL,
+// with one placeholder per column, those of converter.
+func (w WriteMode) statement(table *Table, primaryKey []string, converter *RowConverter) (string, error) {
+	switch w {
+	case "", WriteModeInsert:
+		return insertStatement(table, converter), nil
+	case WriteModeInsertIgnore:
+		return strings.Replace(insertStatement(table, converter), "INSERT INTO", "INSERT IGNORE INTO", 1), nil
+	case WriteModeReplace:
+		return strings.Replace(insertStatement(table, converter), "INSERT INTO", "REPLACE INTO", 1), nil
+	case WriteModeUpsert:
+		return upsertStatement(table, primaryKey, converter), nil
+	default:
+		return "", fmt.Errorf("unknown write mode '%s'", w)
+	}
+}
+
+// upsertStatement builds an INSERT that updates every non-key column of the
+// row it conflicts with. Tables without a primary key, or made up only of
+// key columns, update every column instead; without a primary key, rows
+// only conflict through a unique key MySQL may have.
+func upsertStatement(table *Table, primaryKey []string, converter *RowConverter) string {
+	isKey := map[string]bool{}
+	for _, column := range primaryKey {
+		isKey[column] = true
+	}
+
+	var assignments []string
+	for _, column := range table.Columns {
+		if !isKey[column.Name] {
+			assignments = append(assignments, fmt.Sprintf("`%s` = VALUES(`%s`)", column.Name, column.Name))
+		}
+	}
+	if len(assignments) == 0 {
+		for _, column := range table.Columns {
+			assignments = append(assignments, fmt.Sprintf("`%s` = VALUES(`%s`)", column.Name, column.Name))
+		}
+	}
+
+	return fmt.Sprintf("%s ON DUPLICATE KEY UPDATE %s", insertStatement(table, converter), strings.Join(assignments, ","))
+}
@@ -0,0 +1,125 @@
+package pg2mysql_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"pg2mysql"
+	"pg2mysql/pg2mysqlfakes"
+)
+
+var _ = Describe("WriteMode", func() {
+	var table *pg2mysql.Table
+
+	BeforeEach(func() {
+		table = &pg2mysql.Table{
+			Name: "accounts",
+			Columns: []*pg2mysql.Column{
+				{Name: "id"},
+				{Name: "name"},
+			},
+		}
+	})
+
+	statement := func(mode pg2mysql.WriteMode, primaryKey ...string) string {
+		stmt, err := mode.Statement(table, primaryKey, nil)
+		Expect(err).NotTo(HaveOccurred())
+		return stmt
+	}
+
+	It("builds the statement of each write mode", func() {
+		Expect(statement("", "id")).To(Equal("INSERT INTO `accounts` (`id`,`name`) VALUES (?,?)"))
+		Expect(statement(pg2mysql.WriteModeInsert, "id")).To(Equal("INSERT INTO `accounts` (`id`,`name`) VALUES (?,?)"))
+		Expect(statement(pg2mysql.WriteModeInsertIgnore, "id")).To(Equal("INSERT IGNORE INTO `accounts` (`id`,`name`) VALUES (?,?)"))
+		Expect(statement(pg2mysql.WriteModeReplace, "id")).To(Equal("REPLACE INTO `accounts` (`id`,`name`) VALUES (?,?)"))
+		Expect(statement(pg2mysql.WriteModeUpsert, "id")).To(Equal("INSERT INTO `accounts` (`id`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)"))
+	})
+
+	It("updates every column of tables made up only of key columns, or without a key", func() {
+		Expect(statement(pg2mysql.WriteModeUpsert, "id", "name")).To(Equal("INSERT INTO `accounts` (`id`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `id` = VALUES(`id`),`name` = VALUES(`name`)"))
+		Expect(statement(pg2mysql.WriteModeUpsert)).To(Equal("INSERT INTO `accounts` (`id`,`name`) VALUES (?,?) ON DUPLICATE KEY UPDATE `id` = VALUES(`id`),`name` = VALUES(`name`)"))
+	})
+
+	It("rejects unknown write modes", func() {
+		_, err := pg2mysql.WriteMode("merge").Statement(table, []string{"id"}, nil)
+		Expect(err).To(MatchError("unknown write mode 'merge'"))
+	})
+})
+
+var _ = Describe("Migrating with a write mode", func() {
+	var (
+		mysql pg2mysql.DB
+		pg    pg2mysql.DB
+
+		config pg2mysql.MigrationConfig
+	)
+
+	BeforeEach(func() {
+		_, err := pgRunner.DB().Exec(`
+			CREATE TABLE accounts (
+				id int PRIMARY KEY,
+				name varchar(50)
+			);
+			INSERT INTO accounts VALUES (1, 'a'), (2, 'b'), (3, 'c');`)
+		Expect(err).NotTo(HaveOccurred())
+
+		for _, stmt := range []string{
+			"CREATE TABLE accounts (id int PRIMARY KEY, name varchar(50))",
+			"INSERT INTO accounts VALUES (1, 'a'), (2, 'stale')",
+		} {
+			_, err = mysqlRunner.DB().Exec(stmt)
+			Expect(err).NotTo(HaveOccurred())
+		}
+
+		mysql = pg2mysql.NewMySQLDB(mysqlRunner.DBName, "root", "", "127.0.0.1", 3306, nil, "")
+		Expect(mysql.Open()).To(Succeed())
+
+		pg = pg2mysql.NewPostgreSQLDB(pgRunner.DBName, "", "", "127.0.0.1", 5432, "disable", "")
+		Expect(pg.Open()).To(Succeed())
+
+		config = pg2mysql.MigrationConfig{
+			IgnoreTables: []string{"table_with_id", "table_with_string_id", "table_without_id"},
+		}
+	})
+
+	AfterEach(func() {
+		Expect(mysql.Close()).To(Succeed())
+		Expect(pg.Close()).To(Succeed())
+
+		_, err := pgRunner.DB().Exec("DROP TABLE accounts")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = mysqlRunner.DB().Exec("DROP TABLE accounts")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	accounts := func() []string {
+		rows, err := mysqlRunner.DB().Query("SELECT CONCAT(id, ':', name) FROM accounts ORDER BY id")
+		Expect(err).NotTo(HaveOccurred())
+		defer rows.Close()
+
+		var accounts []string
+		for rows.Next() {
+			var account string
+			Expect(rows.Scan(&account)).To(Succeed())
+			accounts = append(accounts, account)
+		}
+		Expect(rows.Err()).NotTo(HaveOccurred())
+		return accounts
+	}
+
+	It("updates rows that differ when upserting", func() {
+		config.WriteMode = pg2mysql.WriteModeUpsert
+
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{}).Migrate(context.Background(), config)).To(Succeed())
+		Expect(accounts()).To(Equal([]string{"1:a", "2:b", "3:c"}))
+	})
+
+	It("only inserts the missing rows when inserting", func() {
+		config.WriteMode = pg2mysql.WriteModeInsert
+
+		Expect(pg2mysql.NewMigrator(pg, mysql, false, &pg2mysqlfakes.FakeMigratorWatcher{}).Migrate(context.Background(), config)).To(Succeed())
+		Expect(accounts()).To(Equal([]string{"1:a", "2:stale", "3:c"}))
+	})
+})